@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"verkeyoss/internal/config"
+	"verkeyoss/internal/database"
+	"verkeyoss/internal/migration"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/store"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// configFile 配置文件路径
+const configFile = "config.yaml"
+
+// command 命令行子命令
+type command struct {
+	name  string
+	usage string // 在帮助信息中显示的用法
+	brief string // 简要说明
+	run   func(args []string) error
+}
+
+// commands 全部子命令，未指定子命令时执行 serve
+var commands = []command{
+	{"serve", "serve", "启动服务（未指定命令时的默认行为）", runServeCommand},
+	{"migrate", "migrate [up|down|status]", "执行或回滚数据库迁移", runMigrate},
+	{"app", "app create|list|delete", "管理应用", runApp},
+	{"version", "version publish|revoke", "发布或撤回版本", runVersion},
+	{"backup", "backup export|import", "导出或导入应用、版本和公告", runBackup},
+	{"admin", "admin reset-password", "重置用户密码并重新启用账号", runAdmin},
+	{"config", "config check", "检查配置文件和数据库连接", runConfig},
+}
+
+// cliActor 命令行操作在审计日志中记录的操作者
+var cliActor = &service.Actor{Username: "cli", IP: "local"}
+
+// errUsage 参数错误，帮助信息已输出，无需再输出错误信息
+var errUsage = errors.New("usage")
+
+// runCommand 解析并执行子命令
+func runCommand(args []string) error {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	switch name {
+	case "help", "-h", "--help":
+		printUsage()
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n", name)
+	printUsage()
+	return errUsage
+}
+
+// printUsage 输出命令行帮助信息
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法: verkeyoss <命令> [操作] [参数]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-28s %s\n", cmd.usage, cmd.brief)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "使用 \"verkeyoss <命令> <操作> -h\" 查看参数说明")
+}
+
+// runServeCommand 执行 serve 子命令
+func runServeCommand(args []string) error {
+	flags := newFlagSet("serve")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	runServe()
+	return nil
+}
+
+// runActions 执行命令下的操作，args 的第一个参数为操作名
+func runActions(name string, args []string, actions map[string]func(args []string) error) error {
+	names := make([]string, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "用法: verkeyoss %s <%s> [参数]\n", name, strings.Join(names, "|"))
+		return errUsage
+	}
+	action, ok := actions[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的操作: %s %s（可选值: %s）\n", name, args[0], strings.Join(names, "、"))
+		return errUsage
+	}
+	return action(args[1:])
+}
+
+// newFlagSet 创建子命令的参数解析器，解析失败时由调用方返回错误
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("verkeyoss "+name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+// parseFlags 解析参数，不允许出现多余的位置参数
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return flag.ErrHelp
+		}
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "多余的参数: %s\n", strings.Join(flags.Args(), " "))
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// requireFlag 检查必填参数
+func requireFlag(flags *flag.FlagSet, name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		fmt.Fprintf(os.Stderr, "缺少参数: -%s\n", name)
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// openDatabase 加载配置文件并连接数据库
+// 配置文件不存在时返回错误，不会像启动服务时那样自动创建默认配置
+func openDatabase() (*config.Config, *gorm.DB, error) {
+	if _, err := os.Stat(configFile); err != nil {
+		return nil, nil, fmt.Errorf("配置文件 %s 不存在: %w", configFile, err)
+	}
+	appConfig, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("配置文件加载失败: %w", err)
+	}
+
+	db, err := database.Open(appConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 命令行输出可能被脚本解析，不输出查询不到记录等SQL日志
+	db.Logger = gormlogger.Default.LogMode(gormlogger.Silent)
+	return appConfig, db, nil
+}
+
+// cliEnv 子命令使用的配置、数据库连接和服务层
+type cliEnv struct {
+	config   *config.Config
+	db       *gorm.DB
+	services *service.Services
+}
+
+// openCLIEnv 连接配置文件中的数据库，创建与服务端相同的服务层
+// 子命令不会自动修改表结构，数据库存在未执行的迁移时返回错误
+func openCLIEnv() (*cliEnv, error) {
+	appConfig, db, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("数据库有 %d 个迁移尚未执行，请先执行 verkeyoss migrate up", pending)
+	}
+
+	// 命令行进程很快退出，不在这里投递Webhook，事件写入投递队列后由运行中的服务端投递
+	services := service.NewCLIServices(store.NewStore(db), appConfig)
+
+	return &cliEnv{config: appConfig, db: db, services: services}, nil
+}
+
+// Close 停止服务层的后台任务
+func (e *cliEnv) Close() {
+	e.services.TelemetryService.Close()
+	if sqlDB, err := e.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// pendingMigrations 返回尚未执行的迁移数量
+func pendingMigrations(db *gorm.DB) (int, error) {
+	statuses, err := migration.NewRunner(db, false, nil).Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// printJSON 以缩进格式向标准输出写入JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
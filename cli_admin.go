@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+
+	"verkeyoss/internal/blob"
+	"verkeyoss/internal/config"
+	"verkeyoss/internal/database"
+	"verkeyoss/internal/validator"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 生成随机密码使用的字符集和长度
+const (
+	passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+	passwordLength   = 16
+)
+
+// runAdmin 执行 admin 子命令
+func runAdmin(args []string) error {
+	return runActions("admin", args, map[string]func(args []string) error{
+		"reset-password": runAdminResetPassword,
+	})
+}
+
+// runConfig 执行 config 子命令
+func runConfig(args []string) error {
+	return runActions("config", args, map[string]func(args []string) error{
+		"check": runConfigCheck,
+	})
+}
+
+// runAdminResetPassword 重置用户密码并重新启用账号，角色保持不变
+// 用法: verkeyoss admin reset-password [-username 用户名] [-password 新密码 | -password-stdin]
+// 未指定新密码时生成随机密码并输出到标准输出
+func runAdminResetPassword(args []string) error {
+	flags := newFlagSet("admin reset-password")
+	username := flags.String("username", "", "用户名，默认为配置文件中的管理员账号")
+	password := flags.String("password", "", "新密码，为空时生成随机密码")
+	passwordStdin := flags.Bool("password-stdin", false, "从标准输入读取新密码")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *passwordStdin {
+		if *password != "" {
+			return errors.New("-password 和 -password-stdin 不能同时使用")
+		}
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("读取新密码失败: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	generated := *password == ""
+	if generated {
+		var err error
+		if *password, err = generatePassword(); err != nil {
+			return fmt.Errorf("生成随机密码失败: %w", err)
+		}
+	} else if err := validator.ValidatePassword(*password); err != nil {
+		return err
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	if *username == "" {
+		*username = env.config.Admin.Username
+	}
+	user, err := env.services.UserService.ResetPassword(cliActor, *username, *password)
+	if err != nil {
+		return fmt.Errorf("重置密码失败: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "用户 %s 的密码已重置，账号已启用\n", user.Username)
+	if generated {
+		fmt.Println(*password)
+	}
+	return nil
+}
+
+// generatePassword 生成满足密码强度要求的随机密码
+func generatePassword() (string, error) {
+	for {
+		buf := make([]byte, passwordLength)
+		for i := range buf {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			buf[i] = passwordAlphabet[n.Int64()]
+		}
+		// 随机结果可能不含数字或字母，重新生成直到满足强度要求
+		if validator.ValidatePassword(string(buf)) == nil {
+			return string(buf), nil
+		}
+	}
+}
+
+// configCheck 配置检查结果
+type configCheck struct {
+	failures int
+}
+
+// pass 输出通过的检查项
+func (c *configCheck) pass(item, format string, args ...interface{}) {
+	fmt.Printf("[通过] %s: %s\n", item, fmt.Sprintf(format, args...))
+}
+
+// warn 输出存在风险但不影响启动的检查项
+func (c *configCheck) warn(item, format string, args ...interface{}) {
+	fmt.Printf("[警告] %s: %s\n", item, fmt.Sprintf(format, args...))
+}
+
+// fail 输出未通过的检查项
+func (c *configCheck) fail(item, format string, args ...interface{}) {
+	c.failures++
+	fmt.Printf("[失败] %s: %s\n", item, fmt.Sprintf(format, args...))
+}
+
+// runConfigCheck 检查配置文件、数据库连接和迁移状态，有检查项未通过时返回错误
+// 用法: verkeyoss config check
+func runConfigCheck(args []string) error {
+	flags := newFlagSet("config check")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	check := &configCheck{}
+	if _, err := os.Stat(configFile); err != nil {
+		check.fail("配置文件", "%s 不存在", configFile)
+		return check.result()
+	}
+	appConfig, err := config.LoadConfig(configFile)
+	if err != nil {
+		check.fail("配置文件", "%v", err)
+		return check.result()
+	}
+	check.pass("配置文件", "%s", configFile)
+
+	checkAdmin(check, appConfig)
+	checkSecurity(check, appConfig)
+	checkDatabase(check, appConfig)
+	checkStorage(check, appConfig)
+	return check.result()
+}
+
+// result 返回检查结果
+func (c *configCheck) result() error {
+	if c.failures > 0 {
+		return fmt.Errorf("配置检查未通过，共 %d 项失败", c.failures)
+	}
+	return nil
+}
+
+// checkAdmin 检查管理员账号配置
+func checkAdmin(check *configCheck, appConfig *config.Config) {
+	if err := validator.ValidateUsername(appConfig.Admin.Username); err != nil {
+		check.fail("管理员账号", "用户名 %q 无效: %v", appConfig.Admin.Username, err)
+		return
+	}
+	if _, err := bcrypt.Cost([]byte(appConfig.Admin.Password)); err != nil {
+		check.fail("管理员账号", "admin.password 不是有效的bcrypt哈希，可执行 verkeyoss admin reset-password 重新设置")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(appConfig.Admin.Password), []byte("verkeyoss")) == nil {
+		check.warn("管理员账号", "%s 仍在使用默认密码，请尽快修改", appConfig.Admin.Username)
+		return
+	}
+	check.pass("管理员账号", "%s", appConfig.Admin.Username)
+}
+
+// checkSecurity 检查JWT密钥、响应签名密钥和反向代理配置
+func checkSecurity(check *configCheck, appConfig *config.Config) {
+	if len(appConfig.JWT.Secret) < 32 {
+		check.warn("JWT密钥", "长度不足32个字符，建议使用更长的随机密钥")
+	} else {
+		check.pass("JWT密钥", "已配置")
+	}
+
+	for _, proxy := range appConfig.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				check.fail("反向代理", "%q 不是有效的IP地址或CIDR", proxy)
+				return
+			}
+		}
+	}
+	if config.HasSigningKey() {
+		check.pass("响应签名密钥", "已配置")
+	} else {
+		check.warn("响应签名密钥", "未配置，启动服务时自动生成并写入配置文件")
+	}
+
+	if len(appConfig.Server.TrustedProxies) > 0 {
+		check.pass("反向代理", "%s", strings.Join(appConfig.Server.TrustedProxies, ", "))
+	}
+}
+
+// checkDatabase 检查数据库连接和迁移状态
+func checkDatabase(check *configCheck, appConfig *config.Config) {
+	db, err := database.Open(appConfig)
+	if err != nil {
+		check.fail("数据库连接", "%s: %v", database.Describe(appConfig), err)
+		return
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		defer sqlDB.Close()
+		err = sqlDB.Ping()
+	}
+	if err != nil {
+		check.fail("数据库连接", "%s: %v", database.Describe(appConfig), err)
+		return
+	}
+	check.pass("数据库连接", "%s", database.Describe(appConfig))
+
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		check.fail("数据库迁移", "%v", err)
+		return
+	}
+	if pending > 0 {
+		check.warn("数据库迁移", "有 %d 个迁移尚未执行，启动服务时会自动执行，也可以执行 verkeyoss migrate up", pending)
+		return
+	}
+	check.pass("数据库迁移", "已是最新版本")
+}
+
+// checkStorage 检查制品存储配置
+func checkStorage(check *configCheck, appConfig *config.Config) {
+	if _, err := blob.Open(appConfig); err != nil {
+		check.fail("制品存储", "%s: %v", blob.Describe(appConfig), err)
+		return
+	}
+	if appConfig.Storage.Driver == config.StorageDriverLocal && appConfig.Storage.BaseURL == "" {
+		check.warn("制品存储", "%s，未配置 storage.base_url，下载地址将使用相对路径", blob.Describe(appConfig))
+		return
+	}
+	check.pass("制品存储", "%s", blob.Describe(appConfig))
+}
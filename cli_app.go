@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+)
+
+// runApp 执行 app 子命令
+func runApp(args []string) error {
+	return runActions("app", args, map[string]func(args []string) error{
+		"create": runAppCreate,
+		"list":   runAppList,
+		"delete": runAppDelete,
+	})
+}
+
+// runVersion 执行 version 子命令
+func runVersion(args []string) error {
+	return runActions("version", args, map[string]func(args []string) error{
+		"publish": runVersionPublish,
+		"revoke":  runVersionRevoke,
+	})
+}
+
+// runAppCreate 创建应用
+// 用法: verkeyoss app create -name 名称 [-description 描述] [-paid] [-min-version 版本号] [-json]
+func runAppCreate(args []string) error {
+	flags := newFlagSet("app create")
+	name := flags.String("name", "", "应用名称（必填）")
+	description := flags.String("description", "", "应用描述")
+	isPaid := flags.Bool("paid", false, "是否为收费应用")
+	minVersion := flags.String("min-version", "", "最低支持版本，为空表示不限制")
+	asJSON := flags.Bool("json", false, "以JSON格式输出")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "name", *name); err != nil {
+		return err
+	}
+
+	if err := validator.ValidateAppName(*name); err != nil {
+		return err
+	}
+	if err := validator.ValidateDescription(*description); err != nil {
+		return err
+	}
+	if *minVersion != "" {
+		if err := validator.ValidateVersion(*minVersion); err != nil {
+			return err
+		}
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	app, err := env.services.AppService.CreateApp(cliActor, *name, *description, *isPaid, *minVersion)
+	if err != nil {
+		return fmt.Errorf("创建应用失败: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(appOutput(app))
+	}
+	fmt.Println(app.AKey)
+	return nil
+}
+
+// runAppList 列出应用
+// 用法: verkeyoss app list [-page 页码] [-size 每页数量] [-json]
+func runAppList(args []string) error {
+	flags := newFlagSet("app list")
+	page := flags.Int("page", 1, "页码")
+	size := flags.Int("size", 100, "每页数量（1-100）")
+	asJSON := flags.Bool("json", false, "以JSON格式输出")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	apps, total, err := env.services.AppService.GetAppList(*page, *size)
+	if err != nil {
+		return fmt.Errorf("获取应用列表失败: %w", err)
+	}
+
+	if *asJSON {
+		list := make([]map[string]interface{}, 0, len(apps))
+		for _, app := range apps {
+			list = append(list, appOutput(app))
+		}
+		return printJSON(map[string]interface{}{"list": list, "total": total})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AKEY\t名称\t收费\t版本数\t最低支持版本\t创建时间")
+	for _, app := range apps {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\n", app.AKey, app.Name, app.IsPaid, app.VersionCount,
+			app.MinSupportedVersion, app.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "共 %d 个应用\n", total)
+	return nil
+}
+
+// runAppDelete 删除应用及其全部版本
+// 用法: verkeyoss app delete -akey AKey
+func runAppDelete(args []string) error {
+	flags := newFlagSet("app delete")
+	akey := flags.String("akey", "", "应用的AKey（必填）")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "akey", *akey); err != nil {
+		return err
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	if err := env.services.AppService.DeleteApp(cliActor, *akey); err != nil {
+		return fmt.Errorf("删除应用失败: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "应用已删除: %s\n", *akey)
+	return nil
+}
+
+// runVersionPublish 发布版本
+// 用法: verkeyoss version publish -akey AKey -version 版本号 [-channel 渠道] [-description 说明] [-latest] [-forced] [-rollout 比例] [-json]
+func runVersionPublish(args []string) error {
+	flags := newFlagSet("version publish")
+	akey := flags.String("akey", "", "应用的AKey（必填）")
+	versionNumber := flags.String("version", "", "语义化版本号（必填）")
+	channel := flags.String("channel", model.ChannelStable, "发布渠道：stable、beta 或 nightly")
+	description := flags.String("description", "", "版本说明")
+	isLatest := flags.Bool("latest", false, "固定为所在渠道的最新版本")
+	isForced := flags.Bool("forced", false, "是否强制更新")
+	rollout := flags.Int("rollout", 100, "灰度发布比例（1-100）")
+	asJSON := flags.Bool("json", false, "以JSON格式输出")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "akey", *akey); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "version", *versionNumber); err != nil {
+		return err
+	}
+
+	// 版本号必须符合语义化版本号格式，以便计算最新版本
+	if err := validator.ValidateVersion(*versionNumber); err != nil {
+		return err
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	if _, err := env.services.AppService.GetAppByAKey(*akey); err != nil {
+		return fmt.Errorf("发布版本失败: %w", service.ErrAKeyNotFound)
+	}
+	version, err := env.services.VersionService.CreateVersion(cliActor, *akey, *versionNumber, *channel, *description, *isLatest, *isForced, *rollout)
+	if err != nil {
+		return fmt.Errorf("发布版本失败: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(versionOutput(version))
+	}
+	fmt.Println(version.VKey)
+	return nil
+}
+
+// runVersionRevoke 撤回版本
+// 用法: verkeyoss version revoke -vkey VKey [-reason 原因] [-json]
+func runVersionRevoke(args []string) error {
+	flags := newFlagSet("version revoke")
+	vkey := flags.String("vkey", "", "版本的VKey（必填）")
+	reason := flags.String("reason", "", "撤回原因")
+	asJSON := flags.Bool("json", false, "以JSON格式输出")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "vkey", *vkey); err != nil {
+		return err
+	}
+	if err := validator.ValidateDescription(*reason); err != nil {
+		return err
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	version, err := env.services.VersionService.RevokeVersion(cliActor, *vkey, *reason)
+	if err != nil {
+		return fmt.Errorf("撤回版本失败: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(versionOutput(version))
+	}
+	fmt.Fprintf(os.Stderr, "版本已撤回: %s (%s)\n", version.VKey, version.Version)
+	return nil
+}
+
+// appOutput 命令行输出的应用信息，字段与管理接口一致
+func appOutput(app *model.App) map[string]interface{} {
+	return map[string]interface{}{
+		"akey":                    app.AKey,
+		"user_id":                 app.UserID,
+		"name":                    app.Name,
+		"description":             app.Description,
+		"is_paid":                 app.IsPaid,
+		"version_count":           app.VersionCount,
+		"min_supported_version":   app.MinSupportedVersion,
+		"require_signed_requests": app.RequireSignedRequests,
+		"created_at":              app.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// versionOutput 命令行输出的版本信息，字段与管理接口一致
+func versionOutput(version *model.Version) map[string]interface{} {
+	var revokedAt interface{}
+	if version.RevokedAt != nil {
+		revokedAt = version.RevokedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return map[string]interface{}{
+		"vkey":             version.VKey,
+		"akey":             version.AKey,
+		"version":          version.Version,
+		"channel":          version.Channel,
+		"description":      version.Description,
+		"is_latest":        version.IsLatest,
+		"is_forced_update": version.IsForcedUpdate,
+		"rollout_percent":  version.RolloutPercent,
+		"rollout_status":   version.RolloutStatus,
+		"revoked_at":       revokedAt,
+		"revoke_reason":    version.RevokeReason,
+		"created_at":       version.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+)
+
+// runBackup 执行 backup 子命令
+func runBackup(args []string) error {
+	return runActions("backup", args, map[string]func(args []string) error{
+		"export": runBackupExport,
+		"import": runBackupImport,
+	})
+}
+
+// runBackupExport 导出全部应用、版本和公告
+// 用法: verkeyoss backup export [-output 文件] [-format json|tar.gz]
+// 未指定输出文件时写入标准输出；未指定格式时根据输出文件的扩展名判断
+func runBackupExport(args []string) error {
+	flags := newFlagSet("backup export")
+	output := flags.String("output", "", "输出文件，为空时写入标准输出")
+	format := flags.String("format", "", "备份格式：json 或 tar.gz，默认根据输出文件扩展名判断")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = service.BackupFormatJSON
+		if strings.HasSuffix(*output, ".tar.gz") || strings.HasSuffix(*output, ".tgz") {
+			*format = service.BackupFormatTarGz
+		}
+	}
+	if *format != service.BackupFormatJSON && *format != service.BackupFormatTarGz {
+		return service.ErrInvalidBackupFormat
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	backup, err := env.services.BackupService.Export(cliActor)
+	if err != nil {
+		return fmt.Errorf("导出备份失败: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		// 备份包含签名密钥，只允许当前用户读取
+		file, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("创建备份文件失败: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := service.WriteBackup(w, backup, *format); err != nil {
+		return fmt.Errorf("写入备份文件失败: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "已导出 %d 个应用、%d 个版本、%d 个公告，校验和 %s\n",
+		len(backup.Data.Apps), len(backup.Data.Versions), len(backup.Data.Announcements), backup.Checksum)
+	return nil
+}
+
+// runBackupImport 导入备份文件
+// 用法: verkeyoss backup import -file 文件 [-mode merge|replace] [-dry-run] [-json]
+func runBackupImport(args []string) error {
+	flags := newFlagSet("backup import")
+	path := flags.String("file", "", "备份文件，JSON或tar.gz格式，为 - 时从标准输入读取（必填）")
+	mode := flags.String("mode", model.BackupModeMerge, "导入模式：merge 跳过已存在的记录，replace 替换现有数据")
+	dryRun := flags.Bool("dry-run", false, "只检查冲突并输出导入结果，不修改数据库")
+	asJSON := flags.Bool("json", false, "以JSON格式输出导入结果")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "file", *path); err != nil {
+		return err
+	}
+	if !model.IsValidBackupMode(*mode) {
+		return service.ErrInvalidBackupMode
+	}
+
+	var r io.Reader = os.Stdin
+	if *path != "-" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return fmt.Errorf("打开备份文件失败: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	backup, err := service.ReadBackup(r)
+	if err != nil {
+		return fmt.Errorf("读取备份文件失败: %w", err)
+	}
+
+	env, err := openCLIEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	result, err := env.services.BackupService.Import(cliActor, backup, *mode, *dryRun)
+	if err != nil {
+		return fmt.Errorf("导入备份失败: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(result)
+	}
+	printImportResult(result)
+	return nil
+}
+
+// printImportResult 输出备份导入结果
+func printImportResult(result *model.BackupImportResult) {
+	if result.DryRun {
+		fmt.Println("预演模式，未修改数据库")
+	}
+	fmt.Printf("导入模式: %s\n", result.Mode)
+	for _, item := range []struct {
+		name  string
+		count model.BackupImportCount
+	}{
+		{"应用", result.Apps},
+		{"版本", result.Versions},
+		{"公告", result.Announcements},
+	} {
+		fmt.Printf("%s: 导入 %d，跳过 %d", item.name, item.count.Imported, item.count.Skipped)
+		if result.Mode == model.BackupModeReplace {
+			fmt.Printf("，删除 %d", item.count.Removed)
+		}
+		fmt.Println()
+	}
+	for _, conflict := range result.Conflicts {
+		fmt.Printf("冲突: %s %s: %s\n", conflict.Resource, conflict.Key, conflict.Reason)
+	}
+}
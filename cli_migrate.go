@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"verkeyoss/internal/migration"
+)
+
+// runMigrate 执行 migrate 子命令
+// 用法: verkeyoss migrate [up|down|status] [-dry-run] [-to 版本] [-steps 步数]
+func runMigrate(args []string) error {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action = args[0]
+		args = args[1:]
+	}
+
+	flags := newFlagSet("migrate " + action)
+	dryRun := flags.Bool("dry-run", false, "只输出将要执行的SQL，不修改数据库")
+	to := flags.Uint("to", 0, "up: 迁移到指定版本，默认为最新版本")
+	steps := flags.Int("steps", 1, "down: 回滚的迁移数量")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	_, db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+
+	runner := migration.NewRunner(db, *dryRun, os.Stdout)
+	switch action {
+	case "up":
+		count, err := runner.Up(*to)
+		if err != nil {
+			return err
+		}
+		if !*dryRun {
+			fmt.Printf("已执行 %d 个迁移\n", count)
+		}
+	case "down":
+		count, err := runner.Down(*steps)
+		if err != nil {
+			return err
+		}
+		if !*dryRun {
+			fmt.Printf("已回滚 %d 个迁移\n", count)
+		}
+	case "status":
+		statuses, err := runner.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "未执行"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("未知的迁移操作: %s（可选值: up、down、status）", action)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnnouncementHandler 公告管理处理器
+
+type AnnouncementHandler struct {
+	announcementService *service.AnnouncementService
+}
+
+// NewAnnouncementHandler 创建公告管理处理器
+func NewAnnouncementHandler(announcementService *service.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{announcementService: announcementService}
+}
+
+// announcementRequest 创建或更新公告的请求体
+type announcementRequest struct {
+	Title       string     `json:"title" binding:"required"`
+	Content     string     `json:"content" binding:"required"`
+	URL         string     `json:"url"`
+	IsActive    *bool      `json:"is_active"`    // 为空时默认激活
+	PublishDate *time.Time `json:"publish_date"` // RFC3339格式
+	UnpublishAt *time.Time `json:"unpublish_at"` // RFC3339格式
+	AKeys       []string   `json:"akeys"`
+	Channels    []string   `json:"channels"`
+	MinVersion  string     `json:"min_version"`
+	MaxVersion  string     `json:"max_version"`
+}
+
+// toInput 转换为服务层参数
+func (r *announcementRequest) toInput() *service.AnnouncementInput {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+	return &service.AnnouncementInput{
+		Title:       r.Title,
+		Content:     r.Content,
+		URL:         r.URL,
+		IsActive:    isActive,
+		PublishDate: r.PublishDate,
+		UnpublishAt: r.UnpublishAt,
+		AKeys:       r.AKeys,
+		Channels:    r.Channels,
+		MinVersion:  r.MinVersion,
+		MaxVersion:  r.MaxVersion,
+	}
+}
+
+// CreateAnnouncement 创建公告接口
+func (h *AnnouncementHandler) CreateAnnouncement(c *gin.Context) {
+	var request announcementRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("创建公告请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	announcement, err := h.announcementService.CreateAnnouncement(request.toInput())
+	if err != nil {
+		logger.Errorf("创建公告失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功创建公告 (ID: %d)", announcement.ID)
+
+	respondSuccess(c, formatAnnouncement(announcement, time.Now()))
+}
+
+// GetAnnouncementList 获取公告列表接口
+// 支持按状态和目标应用筛选
+func (h *AnnouncementHandler) GetAnnouncementList(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	validPage, validSize, err := validator.ValidatePagination(page, size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	query := &model.AnnouncementQuery{
+		Status: c.Query("status"),
+		AKey:   c.Query("akey"),
+	}
+
+	announcements, total, err := h.announcementService.GetAnnouncementList(query, validPage, validSize)
+	if err != nil {
+		logger.Errorf("获取公告列表失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	announcementList := make([]map[string]interface{}, 0, len(announcements))
+	for _, announcement := range announcements {
+		announcementList = append(announcementList, formatAnnouncement(announcement, now))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  announcementList,
+		"total": total,
+		"page":  validPage,
+		"size":  validSize,
+	})
+}
+
+// GetAnnouncement 获取公告详情接口
+func (h *AnnouncementHandler) GetAnnouncement(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "公告ID无效")
+	if !ok {
+		return
+	}
+
+	announcement, err := h.announcementService.GetAnnouncement(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, formatAnnouncement(announcement, time.Now()))
+}
+
+// UpdateAnnouncement 更新公告接口
+func (h *AnnouncementHandler) UpdateAnnouncement(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "公告ID无效")
+	if !ok {
+		return
+	}
+
+	var request announcementRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("更新公告请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	announcement, err := h.announcementService.UpdateAnnouncement(id, request.toInput())
+	if err != nil {
+		logger.Errorf("更新公告失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功更新公告 (ID: %d)", id)
+
+	respondSuccess(c, formatAnnouncement(announcement, time.Now()))
+}
+
+// DeleteAnnouncement 删除公告接口
+func (h *AnnouncementHandler) DeleteAnnouncement(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "公告ID无效")
+	if !ok {
+		return
+	}
+
+	if err := h.announcementService.DeleteAnnouncement(id); err != nil {
+		logger.Errorf("删除公告失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功删除公告 (ID: %d)", id)
+
+	respondSuccess(c, map[string]interface{}{
+		"message": "删除成功",
+	})
+}
+
+// formatAnnouncement 格式化公告信息，包含目标范围和当前状态
+func formatAnnouncement(announcement *model.Announcement, now time.Time) map[string]interface{} {
+	akeys := announcement.AKeyList()
+	if akeys == nil {
+		akeys = []string{}
+	}
+	channels := announcement.ChannelList()
+	if channels == nil {
+		channels = []string{}
+	}
+	return map[string]interface{}{
+		"id":           announcement.ID,
+		"title":        announcement.Title,
+		"content":      announcement.Content,
+		"url":          announcement.URL,
+		"is_active":    announcement.IsActive,
+		"status":       announcement.Status(now),
+		"publish_date": announcement.PublishDate.Format("2006-01-02T15:04:05Z"),
+		"unpublish_at": formatOptionalTime(announcement.UnpublishAt),
+		"akeys":        akeys,
+		"channels":     channels,
+		"min_version":  announcement.MinVersion,
+		"max_version":  announcement.MaxVersion,
+		"created_at":   announcement.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"updated_at":   announcement.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"verkeyoss/internal/errors"
 	"verkeyoss/internal/logger"
@@ -36,8 +38,8 @@ func AuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 		}
 
 		// 验证令牌
-		valid, err := authService.VerifyToken(token)
-		if err != nil || !valid {
+		principal, err := authService.VerifyToken(token)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "令牌无效或已过期",
@@ -46,15 +48,110 @@ func AuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
+		// 保存调用者身份，供后续中间件和处理器使用
+		c.Set(principalContextKey, principal)
+
 		// 继续处理请求
 		c.Next()
 	}
 }
 
-// AdminMiddleware 管理员权限验证中间件 (在单管理员系统中，等同于AuthMiddleware)
-func AdminMiddleware(authService *service.AuthService) gin.HandlerFunc {
-	// 直接使用AuthMiddleware，因为系统只有一个管理员
-	return AuthMiddleware(authService)
+// AdminMiddleware 角色权限验证中间件
+// 需在 AuthMiddleware 之后使用，要求调用者的角色不低于 role
+func AdminMiddleware(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := currentPrincipal(c)
+		if principal == nil {
+			c.JSON(http.StatusUnauthorized, ErrorResponse(401, "未授权访问"))
+			c.Abort()
+			return
+		}
+
+		if !principal.HasRole(role) {
+			c.JSON(http.StatusForbidden, ErrorResponse(403, "权限不足"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ScopeMiddleware 应用级权限验证中间件
+// 需在 AuthMiddleware 之后使用，应用标识取自路径参数 akey。
+// 登录用户要求角色不低于 role，API令牌要求拥有 scope 权限范围并被授权访问该应用
+func ScopeMiddleware(role, scope string) gin.HandlerFunc {
+	return scopeMiddleware(role, scope, func(c *gin.Context) (string, error) {
+		return c.Param("akey"), nil
+	})
+}
+
+// VersionScopeMiddleware 版本级权限验证中间件
+// 与 ScopeMiddleware 相同，但应用标识通过路径参数 vkey 对应的版本查询得到
+func VersionScopeMiddleware(versionService *service.VersionService, role, scope string) gin.HandlerFunc {
+	return scopeMiddleware(role, scope, func(c *gin.Context) (string, error) {
+		version, err := versionService.GetVersionInfo(c.Param("vkey"))
+		if err != nil {
+			return "", err
+		}
+		return version.AKey, nil
+	})
+}
+
+// scopeMiddleware 权限范围验证的通用实现
+func scopeMiddleware(role, scope string, resolveAKey func(c *gin.Context) (string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := currentPrincipal(c)
+		if principal == nil {
+			c.JSON(http.StatusUnauthorized, ErrorResponse(401, "未授权访问"))
+			c.Abort()
+			return
+		}
+
+		// 登录用户只需校验角色，无需解析应用标识
+		if principal.Token == nil {
+			if !principal.HasRole(role) {
+				c.JSON(http.StatusForbidden, ErrorResponse(403, "权限不足"))
+				c.Abort()
+			}
+			return
+		}
+
+		akey, err := resolveAKey(c)
+		if err != nil || !principal.CanAccess(role, scope, akey) {
+			c.JSON(http.StatusForbidden, ErrorResponse(403, "权限不足"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// principalContextKey 调用者身份在请求上下文中的键名
+const principalContextKey = "principal"
+
+// currentPrincipal 获取当前请求的调用者身份，未认证时返回nil
+func currentPrincipal(c *gin.Context) *service.Principal {
+	value, exists := c.Get(principalContextKey)
+	if !exists {
+		return nil
+	}
+	principal, _ := value.(*service.Principal)
+	return principal
+}
+
+// currentActor 获取当前请求的调用者及其IP地址，用于记录审计日志
+func currentActor(c *gin.Context) *service.Actor {
+	actor := &service.Actor{IP: c.ClientIP()}
+	if principal := currentPrincipal(c); principal != nil {
+		actor.UserID = principal.UserID
+		actor.Username = principal.Username
+		if principal.Token != nil {
+			actor.TokenID = principal.Token.ID
+		}
+	}
+	return actor
 }
 
 // ErrorResponse 错误响应
@@ -83,6 +180,9 @@ func respondError(c *gin.Context, err error) {
 	if appErr, ok := errors.IsAppError(err); ok {
 		// 如果是应用错误，使用定义的错误码和消息
 		logger.Errorf("API错误: %v", appErr)
+		if appErr.RetryAfter > 0 {
+			c.Header("Retry-After", RetryAfterSeconds(appErr.RetryAfter))
+		}
 		c.JSON(appErr.Code, ErrorResponse(appErr.Code, appErr.Message))
 	} else {
 		// 其他错误作为内部服务器错误处理
@@ -90,3 +190,20 @@ func respondError(c *gin.Context, err error) {
 		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "服务器内部错误"))
 	}
 }
+
+// RetryAfterSeconds 将等待时长转换为 Retry-After 响应头的秒数，不足一秒按一秒计算
+func RetryAfterSeconds(d time.Duration) string {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// formatOptionalTime 格式化可为空的时间字段，为空时返回nil
+func formatOptionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02T15:04:05Z")
+}
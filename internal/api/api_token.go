@@ -0,0 +1,119 @@
+package api
+
+import (
+	"strconv"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenHandler API令牌管理处理器
+
+type APITokenHandler struct {
+	tokenService *service.APITokenService
+}
+
+// NewAPITokenHandler 创建API令牌管理处理器
+func NewAPITokenHandler(tokenService *service.APITokenService) *APITokenHandler {
+	return &APITokenHandler{tokenService: tokenService}
+}
+
+// CreateToken 创建API令牌接口
+// 明文令牌只在本接口的响应中返回一次
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	// 绑定请求体
+	var request struct {
+		Name          string   `json:"name" binding:"required"`
+		AKeys         []string `json:"akeys" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("创建API令牌请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	// 调用服务层创建令牌
+	principal := currentPrincipal(c)
+	plainToken, token, err := h.tokenService.CreateToken(principal, request.Name, request.AKeys, request.Scopes, request.ExpiresInDays)
+	if err != nil {
+		logger.Errorf("创建API令牌失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("用户 %s 创建API令牌: %s (%s)", principal.Username, token.Name, token.Prefix)
+
+	// 返回成功响应
+	result := formatAPIToken(token)
+	result["token"] = plainToken
+	respondSuccess(c, result)
+}
+
+// GetTokenList 获取API令牌列表接口
+// 所有者传入 all=true 时返回全部用户的令牌
+func (h *APITokenHandler) GetTokenList(c *gin.Context) {
+	tokens, err := h.tokenService.GetTokenList(currentPrincipal(c), c.Query("all") == "true")
+	if err != nil {
+		logger.Errorf("获取API令牌列表失败: %v", err)
+		respondError(c, errors.WrapError(err, "获取API令牌列表失败"))
+		return
+	}
+
+	// 构造响应数据
+	tokenList := make([]map[string]interface{}, 0, len(tokens))
+	for _, token := range tokens {
+		tokenList = append(tokenList, formatAPIToken(token))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  tokenList,
+		"total": len(tokenList),
+	})
+}
+
+// RevokeToken 吊销API令牌接口
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	// 获取令牌ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, errors.NewValidationError("令牌ID无效"))
+		return
+	}
+
+	// 调用服务层吊销令牌
+	if err := h.tokenService.RevokeToken(currentPrincipal(c), uint(id)); err != nil {
+		logger.Errorf("吊销API令牌失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功吊销API令牌 (ID: %d)", id)
+
+	// 返回成功响应
+	respondSuccess(c, map[string]interface{}{
+		"message": "吊销成功",
+	})
+}
+
+// formatAPIToken 格式化API令牌信息，不包含令牌哈希
+func formatAPIToken(token *model.APIToken) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           token.ID,
+		"user_id":      token.UserID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"akeys":        token.AKeyList(),
+		"scopes":       token.ScopeList(),
+		"expires_at":   formatOptionalTime(token.ExpiresAt),
+		"last_used_at": formatOptionalTime(token.LastUsedAt),
+		"revoked_at":   formatOptionalTime(token.RevokedAt),
+		"created_at":   token.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
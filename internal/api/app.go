@@ -1,10 +1,12 @@
 package api
 
 import (
+	"io"
 	"strconv"
 
 	"verkeyoss/internal/errors"
 	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
 	"verkeyoss/internal/service"
 	"verkeyoss/internal/validator"
 
@@ -26,9 +28,10 @@ func NewAppHandler(appService *service.AppService) *AppHandler {
 func (h *AppHandler) CreateApp(c *gin.Context) {
 	// 绑定请求体
 	var request struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-		IsPaid      bool   `json:"is_paid"`
+		Name                string `json:"name" binding:"required"`
+		Description         string `json:"description"`
+		IsPaid              bool   `json:"is_paid"`
+		MinSupportedVersion string `json:"min_supported_version"` // 最低支持版本，为空表示不限制
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -50,8 +53,14 @@ func (h *AppHandler) CreateApp(c *gin.Context) {
 		return
 	}
 
-	// 调用服务层创建应用
-	app, err := h.appService.CreateApp(1, request.Name, request.Description, request.IsPaid)
+	if err := validateMinSupportedVersion(request.MinSupportedVersion); err != nil {
+		logger.Errorf("最低支持版本验证失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	// 调用服务层创建应用，创建者为当前登录用户
+	app, err := h.appService.CreateApp(currentActor(c), request.Name, request.Description, request.IsPaid, request.MinSupportedVersion)
 	if err != nil {
 		logger.Errorf("创建应用失败: %v", err)
 		respondError(c, errors.WrapError(err, "创建应用失败"))
@@ -62,11 +71,14 @@ func (h *AppHandler) CreateApp(c *gin.Context) {
 
 	// 返回成功响应
 	respondSuccess(c, map[string]interface{}{
-		"akey":        app.AKey,
-		"name":        app.Name,
-		"description": app.Description,
-		"is_paid":     app.IsPaid,
-		"created_at":  app.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"akey":                    app.AKey,
+		"user_id":                 app.UserID,
+		"name":                    app.Name,
+		"description":             app.Description,
+		"is_paid":                 app.IsPaid,
+		"min_supported_version":   app.MinSupportedVersion,
+		"require_signed_requests": app.RequireSignedRequests,
+		"created_at":              app.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	})
 }
 
@@ -84,8 +96,14 @@ func (h *AppHandler) GetAppList(c *gin.Context) {
 		return
 	}
 
-	// 调用服务层获取应用列表
-	apps, total, err := h.appService.GetAppList(validPage, validSize)
+	// 调用服务层获取应用列表，mine=true 时只返回当前用户创建的应用
+	var apps []*model.App
+	var total int64
+	if c.Query("mine") == "true" {
+		apps, total, err = h.appService.GetAppListByUserID(currentPrincipal(c).UserID, validPage, validSize)
+	} else {
+		apps, total, err = h.appService.GetAppList(validPage, validSize)
+	}
 	if err != nil {
 		logger.Errorf("获取应用列表失败: %v", err)
 		respondError(c, errors.WrapError(err, "获取应用列表失败"))
@@ -96,12 +114,15 @@ func (h *AppHandler) GetAppList(c *gin.Context) {
 	var appList []map[string]interface{}
 	for _, app := range apps {
 		appInfo := map[string]interface{}{
-			"akey":          app.AKey,
-			"name":          app.Name,
-			"description":   app.Description,
-			"is_paid":       app.IsPaid,
-			"version_count": app.VersionCount,
-			"created_at":    app.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			"akey":                    app.AKey,
+			"user_id":                 app.UserID,
+			"name":                    app.Name,
+			"description":             app.Description,
+			"is_paid":                 app.IsPaid,
+			"version_count":           app.VersionCount,
+			"min_supported_version":   app.MinSupportedVersion,
+			"require_signed_requests": app.RequireSignedRequests,
+			"created_at":              app.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		}
 		appList = append(appList, appInfo)
 	}
@@ -129,9 +150,10 @@ func (h *AppHandler) UpdateApp(c *gin.Context) {
 
 	// 绑定请求体
 	var request struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		IsPaid      bool   `json:"is_paid"`
+		Name                string `json:"name"`
+		Description         string `json:"description"`
+		IsPaid              bool   `json:"is_paid"`
+		MinSupportedVersion string `json:"min_supported_version"` // 最低支持版本，为空表示不限制
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -153,8 +175,14 @@ func (h *AppHandler) UpdateApp(c *gin.Context) {
 		return
 	}
 
+	if err := validateMinSupportedVersion(request.MinSupportedVersion); err != nil {
+		logger.Errorf("最低支持版本验证失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
 	// 调用服务层更新应用
-	err := h.appService.UpdateApp(akey, request.Name, request.Description, request.IsPaid)
+	err := h.appService.UpdateApp(currentActor(c), akey, request.Name, request.Description, request.IsPaid, request.MinSupportedVersion)
 	if err != nil {
 		logger.Errorf("更新应用失败 (AKey: %s): %v", akey, err)
 		respondError(c, errors.WrapError(err, "更新应用失败"))
@@ -169,6 +197,44 @@ func (h *AppHandler) UpdateApp(c *gin.Context) {
 	})
 }
 
+// RotateAKey 轮换AKey接口
+// 旧AKey在宽限期内仍可通过校验，新AKey只在本接口的响应中返回
+func (h *AppHandler) RotateAKey(c *gin.Context) {
+	// 获取AKey
+	akey := c.Param("akey")
+	if err := validator.ValidateAKey(akey); err != nil {
+		logger.Errorf("AKey验证失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	// 绑定请求体，宽限期可选
+	var request struct {
+		GraceHours *int `json:"grace_hours"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		logger.Errorf("轮换AKey请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	rotation, err := h.appService.RotateAKey(currentActor(c), akey, request.GraceHours)
+	if err != nil {
+		logger.Errorf("轮换AKey失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功轮换AKey (旧AKey: %s, 新AKey: %s)", rotation.OldKey, rotation.NewKey)
+
+	respondSuccess(c, map[string]interface{}{
+		"akey":                rotation.NewKey,
+		"old_akey":            rotation.OldKey,
+		"old_akey_expires_at": rotation.OldKeyExpiresAt.Format("2006-01-02T15:04:05Z"),
+	})
+}
+
 // DeleteApp 删除应用接口
 func (h *AppHandler) DeleteApp(c *gin.Context) {
 	// 获取AKey
@@ -180,7 +246,7 @@ func (h *AppHandler) DeleteApp(c *gin.Context) {
 	}
 
 	// 调用服务层删除应用
-	err := h.appService.DeleteApp(akey)
+	err := h.appService.DeleteApp(currentActor(c), akey)
 	if err != nil {
 		logger.Errorf("删除应用失败 (AKey: %s): %v", akey, err)
 		respondError(c, errors.WrapError(err, "删除应用失败"))
@@ -194,3 +260,11 @@ func (h *AppHandler) DeleteApp(c *gin.Context) {
 		"message": "删除成功",
 	})
 }
+
+// validateMinSupportedVersion 验证最低支持版本，为空表示不限制
+func validateMinSupportedVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	return validator.ValidateVersion(version)
+}
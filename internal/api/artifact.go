@@ -0,0 +1,147 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	apperrors "verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead 上传制品时 multipart 表单边界和其他字段允许占用的额外空间
+const multipartOverhead = 1 << 20
+
+// ArtifactHandler 制品处理器
+type ArtifactHandler struct {
+	artifactService *service.ArtifactService
+}
+
+// NewArtifactHandler 创建制品处理器
+func NewArtifactHandler(artifactService *service.ArtifactService) *ArtifactHandler {
+	return &ArtifactHandler{artifactService: artifactService}
+}
+
+// UploadArtifact 上传制品接口
+// 通过 multipart 表单上传：file 为制品文件，os 和 arch 为适用的平台（为空时适用于全部平台），
+// sha256 为可选的文件摘要，提供时与服务端计算的摘要比较。同一平台已有制品时替换
+func (h *ArtifactHandler) UploadArtifact(c *gin.Context) {
+	vkey := c.Param("vkey")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.artifactService.MaxSize()+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, apperrors.NewValidationError(fmt.Sprintf("制品文件不能超过 %d MB", h.artifactService.MaxSize()>>20)))
+			return
+		}
+		respondError(c, apperrors.NewValidationError("请通过 file 字段上传制品文件"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, apperrors.WrapError(err, "读取制品文件失败"))
+		return
+	}
+	defer file.Close()
+
+	artifact, err := h.artifactService.UploadArtifact(currentActor(c), vkey, c.PostForm("os"), c.PostForm("arch"),
+		fileHeader.Filename, fileHeader.Header.Get("Content-Type"), file, fileHeader.Size, c.PostForm("sha256"))
+	if err != nil {
+		logger.Errorf("上传制品失败: %v", err)
+		respondVersionError(c, err, "上传制品失败")
+		return
+	}
+
+	logger.Infof("成功上传制品 (ID: %d, 平台: %s/%s, 大小: %d)", artifact.ID, artifact.OS, artifact.Arch, artifact.Size)
+	respondSuccess(c, h.formatArtifact(artifact))
+}
+
+// GetArtifactList 获取版本的制品列表接口，每个制品附带有时效的下载地址
+func (h *ArtifactHandler) GetArtifactList(c *gin.Context) {
+	artifacts, err := h.artifactService.GetArtifacts(c.Param("vkey"))
+	if err != nil {
+		respondVersionError(c, err, "获取制品列表失败")
+		return
+	}
+
+	artifactList := make([]map[string]interface{}, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		artifactList = append(artifactList, h.formatArtifact(artifact))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  artifactList,
+		"total": len(artifactList),
+	})
+}
+
+// DeleteArtifact 删除制品接口
+func (h *ArtifactHandler) DeleteArtifact(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "制品ID无效")
+	if !ok {
+		return
+	}
+
+	if err := h.artifactService.DeleteArtifact(currentActor(c), c.Param("vkey"), id); err != nil {
+		logger.Errorf("删除制品失败 (ID: %d): %v", id, err)
+		respondVersionError(c, err, "删除制品失败")
+		return
+	}
+
+	logger.Infof("成功删除制品 (ID: %d)", id)
+	respondSuccess(c, nil)
+}
+
+// DownloadArtifact 下载制品接口
+// 下载地址由检查更新接口或制品列表接口生成，凭地址中的签名和有效期访问，不需要登录
+func (h *ArtifactHandler) DownloadArtifact(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "制品ID无效")
+	if !ok {
+		return
+	}
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+
+	artifact, file, err := h.artifactService.OpenDownload(id, expires, c.Query("signature"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer file.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": artifact.FileName})
+	c.DataFromReader(http.StatusOK, artifact.Size, artifact.ContentType, file, map[string]string{
+		"Content-Disposition": disposition,
+		"X-Checksum-Sha256":   artifact.SHA256,
+	})
+}
+
+// formatArtifact 格式化制品信息，生成下载地址失败时不返回下载地址
+func (h *ArtifactHandler) formatArtifact(artifact *model.Artifact) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":           artifact.ID,
+		"os":           artifact.OS,
+		"arch":         artifact.Arch,
+		"file_name":    artifact.FileName,
+		"content_type": artifact.ContentType,
+		"size":         artifact.Size,
+		"sha256":       artifact.SHA256,
+		"created_at":   artifact.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+
+	download, err := h.artifactService.Download(artifact)
+	if err != nil {
+		logger.Errorf("生成制品下载地址失败 (ID: %d): %v", artifact.ID, err)
+		return result
+	}
+	result["download_url"] = download.URL
+	result["expires_at"] = download.ExpiresAt.Format("2006-01-02T15:04:05Z")
+	return result
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 审计日志处理器
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler 创建审计日志处理器
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditList 获取审计日志列表接口
+// 支持按执行者、操作类型、资源类型、资源标识和时间范围筛选
+func (h *AuditHandler) GetAuditList(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	validPage, validSize, err := validator.ValidatePagination(page, size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	query := &model.AuditQuery{
+		ActorName:    c.Query("actor"),
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+	}
+	var ok bool
+	if query.From, ok = parseTimeQuery(c, "from", false); !ok {
+		return
+	}
+	if query.To, ok = parseTimeQuery(c, "to", true); !ok {
+		return
+	}
+
+	entries, total, err := h.auditService.GetAuditEntryList(query, validPage, validSize)
+	if err != nil {
+		logger.Errorf("获取审计日志失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	entryList := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		entryList = append(entryList, formatAuditEntry(entry))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  entryList,
+		"total": total,
+		"page":  validPage,
+		"size":  validSize,
+	})
+}
+
+// formatAuditEntry 格式化审计日志，变更内容以JSON对象返回
+func formatAuditEntry(entry *model.AuditEntry) map[string]interface{} {
+	var changes interface{}
+	if entry.Changes != "" {
+		changes = json.RawMessage(entry.Changes)
+	}
+
+	return map[string]interface{}{
+		"id":            entry.ID,
+		"actor_id":      entry.ActorID,
+		"actor_name":    entry.ActorName,
+		"token_id":      entry.TokenID,
+		"ip":            entry.IP,
+		"action":        entry.Action,
+		"resource_type": entry.ResourceType,
+		"resource_id":   entry.ResourceID,
+		"changes":       changes,
+		"created_at":    entry.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
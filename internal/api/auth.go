@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"net/http"
-	"verkeyoss/internal/config"
+	apperrors "verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
 	"verkeyoss/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -33,8 +35,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// 调用服务层处理登录
-	token, expiresAt, err := h.service.Login(loginRequest.Username, loginRequest.Password)
+	token, expiresAt, user, err := h.service.Login(loginRequest.Username, loginRequest.Password, c.ClientIP())
 	if err != nil {
+		// 连续失败次数过多被锁定时返回429
+		if appErr, ok := apperrors.IsAppError(err); ok && appErr.Type == apperrors.ErrTypeTooManyRequests {
+			respondError(c, appErr)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, ErrorResponse(401, "用户名或密码错误"))
 		return
 	}
@@ -44,7 +51,8 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		"token":      token,
 		"expires_at": expiresAt,
 		"user_info": map[string]interface{}{
-			"username": loginRequest.Username,
+			"username": user.Username,
+			"role":     user.Role,
 		},
 	}))
 }
@@ -63,9 +71,16 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// 调用服务层修改密码
-	err := h.service.ChangePassword(passwordRequest.OldPassword, passwordRequest.NewPassword)
+	err := h.service.ChangePassword(currentActor(c), passwordRequest.OldPassword, passwordRequest.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ErrorResponse(401, err.Error()))
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, ErrorResponse(401, err.Error()))
+		} else if appErr, ok := apperrors.IsAppError(err); ok {
+			c.JSON(appErr.Code, ErrorResponse(appErr.Code, appErr.Message))
+		} else {
+			logger.Errorf("修改密码失败: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "修改密码失败"))
+		}
 		return
 	}
 
@@ -76,19 +91,13 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	})
 }
 
-// GetUserInfoByToken 通过token获取管理员信息接口
+// GetUserInfoByToken 通过token获取当前用户信息接口
 func (h *AuthHandler) GetUserInfoByToken(c *gin.Context) {
-	// 获取实际的管理员配置
-	adminConfig, err := config.GetAdminConfig()
-	if err != nil {
-		// 发生错误时返回默认管理员信息
-		c.JSON(http.StatusOK, SuccessResponse(map[string]interface{}{
-			"username": "verkeyoss",
-		}))
-		return
-	}
-	// 返回实际的管理员用户名
+	// 调用者身份由认证中间件从令牌中解析
+	principal := currentPrincipal(c)
 	c.JSON(http.StatusOK, SuccessResponse(map[string]interface{}{
-		"username": adminConfig.Username,
+		"user_id":  principal.UserID,
+		"username": principal.Username,
+		"role":     principal.Role,
 	}))
 }
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBackupSize 导入的备份文件大小上限
+const maxBackupSize = 64 << 20
+
+// BackupHandler 备份处理器
+type BackupHandler struct {
+	backupService *service.BackupService
+}
+
+// NewBackupHandler 创建备份处理器
+func NewBackupHandler(backupService *service.BackupService) *BackupHandler {
+	return &BackupHandler{backupService: backupService}
+}
+
+// ExportBackup 导出备份接口
+// 查询参数 format 为 json（默认）或 tar.gz，以附件形式返回备份文件
+func (h *BackupHandler) ExportBackup(c *gin.Context) {
+	format := c.DefaultQuery("format", service.BackupFormatJSON)
+	if format != service.BackupFormatJSON && format != service.BackupFormatTarGz {
+		respondError(c, service.ErrInvalidBackupFormat)
+		return
+	}
+
+	backup, err := h.backupService.Export(currentActor(c))
+	if err != nil {
+		logger.Errorf("导出备份失败: %v", err)
+		respondError(c, errors.WrapError(err, "导出备份失败"))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := service.WriteBackup(&buf, backup, format); err != nil {
+		logger.Errorf("写入备份文件失败: %v", err)
+		respondError(c, errors.WrapError(err, "导出备份失败"))
+		return
+	}
+
+	contentType := "application/json"
+	if format == service.BackupFormatTarGz {
+		contentType = "application/gzip"
+	}
+	filename := fmt.Sprintf("verkeyoss-backup-%s.%s", backup.CreatedAt.Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportBackup 导入备份接口
+// 请求体为备份文件内容，也可以通过 multipart 表单的 file 字段上传；
+// 查询参数 mode 为 merge（默认）或 replace，dry_run=true 时只返回导入结果而不保存
+func (h *BackupHandler) ImportBackup(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupSize)
+
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			respondError(c, errors.NewValidationError("请通过 file 字段上传备份文件"))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			respondError(c, errors.WrapError(err, "读取备份文件失败"))
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	backup, err := service.ReadBackup(reader)
+	if err != nil {
+		if _, ok := errors.IsAppError(err); !ok {
+			err = errors.NewValidationError("读取备份文件失败: " + err.Error())
+		}
+		respondError(c, err)
+		return
+	}
+
+	result, err := h.backupService.Import(currentActor(c), backup, c.Query("mode"), c.Query("dry_run") == "true")
+	if err != nil {
+		logger.Errorf("导入备份失败: %v", err)
+		if _, ok := errors.IsAppError(err); !ok {
+			err = errors.WrapError(err, "导入备份失败")
+		}
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("导入备份: 模式 %s，应用 %d，版本 %d，公告 %d，冲突 %d", result.Mode,
+		result.Apps.Imported, result.Versions.Imported, result.Announcements.Imported, len(result.Conflicts))
+	respondSuccess(c, result)
+}
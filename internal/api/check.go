@@ -1,7 +1,9 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"verkeyoss/internal/model"
 	"verkeyoss/internal/service"
@@ -12,50 +14,78 @@ import (
 // CheckHandler 校验API处理器
 
 type CheckHandler struct {
-	service *service.CheckService
+	service             *service.CheckService
+	licenseService      *service.LicenseService
+	signatureService    *service.SignatureService
+	announcementService *service.AnnouncementService
 }
 
 // NewCheckHandler 创建校验API处理器
-func NewCheckHandler(service *service.CheckService) *CheckHandler {
-	return &CheckHandler{service: service}
+func NewCheckHandler(service *service.CheckService, licenseService *service.LicenseService, signatureService *service.SignatureService, announcementService *service.AnnouncementService) *CheckHandler {
+	return &CheckHandler{service: service, licenseService: licenseService, signatureService: signatureService, announcementService: announcementService}
 }
 
+// 客户端随机数、设备标识和平台名称的最大长度
+const (
+	maxNonceLength    = 128
+	maxDeviceIDLength = 128
+	maxPlatformLength = 20
+)
+
 // Validate 校验AKey和VKey合法性接口
 func (h *CheckHandler) Validate(c *gin.Context) {
 	// 绑定请求体
 	var checkRequest model.CheckRequest
 
-	if err := c.ShouldBindJSON(&checkRequest); err != nil {
+	if err := c.ShouldBindJSON(&checkRequest); err != nil || len(checkRequest.Nonce) > maxNonceLength || len(checkRequest.DeviceID) > maxDeviceIDLength {
 		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
 		return
 	}
+	checkRequest.ClientIP = c.ClientIP()
+	checkRequest.UserAgent = c.Request.UserAgent()
 
 	// 调用服务层进行校验
-	result, err := h.service.Validate(checkRequest.AKey, checkRequest.VKey)
+	result, err := h.service.Validate(&checkRequest)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "校验失败"))
 		return
 	}
 
-	// 根据结果返回相应的状态码
+	// 根据结果返回相应的状态码，VKey已撤回时返回410，设备已被禁用时返回403
 	statusCode := http.StatusOK
-	if !result.Valid {
+	switch result.Status {
+	case model.KeyStatusRevoked:
+		statusCode = http.StatusGone
+	case model.KeyStatusInvalid:
 		statusCode = http.StatusNotFound
+	case model.KeyStatusBlocked:
+		statusCode = http.StatusForbidden
 	}
 
 	// 返回响应
 	responseData := map[string]interface{}{
 		"valid":   result.Valid,
+		"status":  result.Status,
 		"message": result.Message,
 	}
 
-	// 如果校验成功，添加应用名和版本号
+	// 如果校验成功，添加应用名和版本号；已撤回时添加撤回原因和时间
 	if result.Valid {
 		responseData["app_name"] = result.AppName
 		responseData["version"] = result.Version
+		responseData["key_rotated"] = result.KeyRotated
+		if result.License != nil {
+			responseData["license"] = result.License
+		}
+	} else if result.Status == model.KeyStatusRevoked {
+		responseData["version"] = result.Version
+		responseData["revoke_reason"] = result.RevokeReason
+		responseData["revoked_at"] = formatOptionalTime(result.RevokedAt)
+	} else if result.Status == model.KeyStatusBlocked {
+		responseData["block_reason"] = result.BlockReason
 	}
 
-	c.JSON(statusCode, SuccessResponse(responseData))
+	h.respondSigned(c, statusCode, checkRequest.Nonce, responseData)
 }
 
 // CheckUpdate 检查是否有新版本接口
@@ -63,18 +93,127 @@ func (h *CheckHandler) CheckUpdate(c *gin.Context) {
 	// 绑定请求体
 	var checkRequest model.CheckRequest
 
-	if err := c.ShouldBindJSON(&checkRequest); err != nil {
+	if err := c.ShouldBindJSON(&checkRequest); err != nil || len(checkRequest.Nonce) > maxNonceLength || len(checkRequest.DeviceID) > maxDeviceIDLength ||
+		len(checkRequest.OS) > maxPlatformLength || len(checkRequest.Arch) > maxPlatformLength {
 		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
 		return
 	}
+	checkRequest.ClientIP = c.ClientIP()
+	checkRequest.UserAgent = c.Request.UserAgent()
+
+	// 渠道为空时使用稳定版渠道
+	if checkRequest.Channel != "" && !model.IsValidChannel(checkRequest.Channel) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "无效的发布渠道"))
+		return
+	}
 
 	// 调用服务层检查更新
-	result, err := h.service.CheckUpdate(checkRequest.AKey, checkRequest.VKey)
+	result, err := h.service.CheckUpdate(&checkRequest)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "检查更新失败"))
 		return
 	}
 
+	// 设备已被禁用时返回403
+	statusCode := http.StatusOK
+	if blocked, _ := result["blocked"].(bool); blocked {
+		statusCode = http.StatusForbidden
+	}
+
 	// 返回响应
-	c.JSON(http.StatusOK, SuccessResponse(result))
+	h.respondSigned(c, statusCode, checkRequest.Nonce, result)
+}
+
+// Activate 激活许可证接口
+// 客户端使用许可证密钥和设备标识激活付费应用，响应与校验接口一样带有签名
+func (h *CheckHandler) Activate(c *gin.Context) {
+	// 绑定请求体
+	var activationRequest model.ActivationRequest
+
+	if err := c.ShouldBindJSON(&activationRequest); err != nil || len(activationRequest.Nonce) > maxNonceLength || len(activationRequest.DeviceID) > maxDeviceIDLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
+		return
+	}
+
+	result := h.licenseService.Activate(activationRequest.AKey, activationRequest.LicenseKey, activationRequest.DeviceID)
+
+	// 根据激活结果返回相应的状态码
+	statusCode := http.StatusOK
+	switch result.Status {
+	case model.LicenseStatusInvalid:
+		statusCode = http.StatusNotFound
+	case model.LicenseStatusRevoked:
+		statusCode = http.StatusGone
+	case model.LicenseStatusExpired, model.LicenseStatusBlocked:
+		statusCode = http.StatusForbidden
+	case model.LicenseStatusSeatLimit:
+		statusCode = http.StatusConflict
+	}
+
+	h.respondSigned(c, statusCode, activationRequest.Nonce, map[string]interface{}{
+		"activated": result.Status == model.LicenseStatusValid,
+		"license":   result,
+	})
+}
+
+// GetAnnouncements 获取客户端公告接口
+// 返回面向客户端所属应用、订阅渠道和当前版本的已发布公告，响应与校验接口一样带有签名
+func (h *CheckHandler) GetAnnouncements(c *gin.Context) {
+	// 绑定请求体
+	var checkRequest model.CheckRequest
+
+	if err := c.ShouldBindJSON(&checkRequest); err != nil || len(checkRequest.Nonce) > maxNonceLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
+		return
+	}
+
+	announcements, err := h.announcementService.GetClientAnnouncements(checkRequest.AKey, checkRequest.VKey, checkRequest.Channel)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	announcementList := make([]map[string]interface{}, 0, len(announcements))
+	for _, announcement := range announcements {
+		announcementList = append(announcementList, map[string]interface{}{
+			"id":           announcement.ID,
+			"title":        announcement.Title,
+			"content":      announcement.Content,
+			"url":          announcement.URL,
+			"publish_date": announcement.PublishDate.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	h.respondSigned(c, http.StatusOK, checkRequest.Nonce, map[string]interface{}{
+		"list":  announcementList,
+		"total": len(announcementList),
+	})
+}
+
+// GetPublicKey 获取响应签名公钥接口
+// 客户端应在发布时内置该公钥，而不是在运行时从本接口获取
+func (h *CheckHandler) GetPublicKey(c *gin.Context) {
+	c.JSON(http.StatusOK, SuccessResponse(map[string]interface{}{
+		"algorithm":  service.SignatureAlgorithm,
+		"key_id":     h.signatureService.KeyID(),
+		"public_key": h.signatureService.PublicKey(),
+	}))
+}
+
+// respondSigned 返回带签名的校验结果
+// 响应数据中加入服务端时间戳和客户端随机数，对序列化后的完整响应体签名，
+// 签名和密钥标识通过 X-Signature、X-Signature-Key-Id 响应头返回
+func (h *CheckHandler) respondSigned(c *gin.Context, statusCode int, nonce string, data map[string]interface{}) {
+	data["timestamp"] = time.Now().Unix()
+	data["nonce"] = nonce
+
+	body, err := json.Marshal(SuccessResponse(data))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "服务器内部错误"))
+		return
+	}
+
+	c.Header("X-Signature", h.signatureService.Sign(body))
+	c.Header("X-Signature-Key-Id", h.signatureService.KeyID())
+	c.Data(statusCode, "application/json; charset=utf-8", body)
 }
@@ -2,6 +2,11 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
 	"verkeyoss/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -60,3 +65,139 @@ func (h *DashboardHandler) GetAnnouncements(c *gin.Context) {
 		"data": announcements,
 	})
 }
+
+// GetActiveInstalls 获取应用各版本的活跃安装数
+// 路由: GET /api/dashboard/apps/:akey/installs?days=7
+// 需要认证
+func (h *DashboardHandler) GetActiveInstalls(c *gin.Context) {
+	akey := c.Param("akey")
+	days, ok := parseDaysQuery(c, 7)
+	if !ok {
+		return
+	}
+
+	installs, err := h.dashboardService.GetActiveInstalls(akey, days)
+	if err != nil {
+		logger.Errorf("获取活跃安装数失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, installs)
+}
+
+// GetAdoptionCurve 获取应用各版本的普及曲线
+// 路由: GET /api/dashboard/apps/:akey/adoption?days=30
+// 需要认证
+func (h *DashboardHandler) GetAdoptionCurve(c *gin.Context) {
+	akey := c.Param("akey")
+	days, ok := parseDaysQuery(c, 30)
+	if !ok {
+		return
+	}
+
+	curve, err := h.dashboardService.GetAdoptionCurve(akey, days)
+	if err != nil {
+		logger.Errorf("获取版本普及曲线失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, curve)
+}
+
+// GetCheckFailureStats 获取应用的校验失败率
+// 路由: GET /api/dashboard/apps/:akey/failures?days=30
+// 需要认证
+func (h *DashboardHandler) GetCheckFailureStats(c *gin.Context) {
+	akey := c.Param("akey")
+	days, ok := parseDaysQuery(c, 30)
+	if !ok {
+		return
+	}
+
+	stats, err := h.dashboardService.GetCheckFailureStats(akey, days)
+	if err != nil {
+		logger.Errorf("获取校验失败率失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, stats)
+}
+
+// parseDaysQuery 解析统计天数查询参数，未提供时使用默认值，参数无效时返回400
+func parseDaysQuery(c *gin.Context, defaultDays int) (int, bool) {
+	value := c.Query("days")
+	if value == "" {
+		return defaultDays, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil {
+		respondError(c, service.ErrInvalidAnalyticsDays)
+		return 0, false
+	}
+	return days, true
+}
+
+// GetTimeSeries 获取应用的时间序列统计
+// 路由: GET /api/dashboard/apps/:akey/timeseries?from=2024-01-01&to=2024-01-31&granularity=day
+// 需要认证
+func (h *DashboardHandler) GetTimeSeries(c *gin.Context) {
+	akey := c.Param("akey")
+	from, ok := parseTimeQuery(c, "from", false)
+	if !ok {
+		return
+	}
+	to, ok := parseTimeQuery(c, "to", true)
+	if !ok {
+		return
+	}
+
+	series, err := h.dashboardService.GetTimeSeries(akey, from, to, c.Query("granularity"))
+	if err != nil {
+		logger.Errorf("获取时间序列统计失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	points := make([]map[string]interface{}, 0, len(series.Points))
+	for _, point := range series.Points {
+		points = append(points, map[string]interface{}{
+			"time":           point.Time.Format(time.RFC3339),
+			"releases":       point.Releases,
+			"checks":         point.Checks,
+			"unique_devices": point.UniqueDevices,
+			"failures":       point.Failures,
+			"failure_rate":   point.FailureRate,
+		})
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"granularity": series.Granularity,
+		"from":        series.From.Format(time.RFC3339),
+		"to":          series.To.Format(time.RFC3339),
+		"points":      points,
+	})
+}
+
+// parseTimeQuery 解析时间查询参数，支持 RFC 3339 格式和 2006-01-02 格式的日期（按服务端时区），未提供时返回零值
+// 参数 endOfDay 为 true 时，日期格式表示包含当天，返回次日零点
+func parseTimeQuery(c *gin.Context, name string, endOfDay bool) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		respondError(c, apperrors.NewValidationError(name+" 参数必须为 RFC 3339 格式的时间或 YYYY-MM-DD 格式的日期"))
+		return time.Time{}, false
+	}
+	if endOfDay {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, true
+}
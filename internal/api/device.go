@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	apperrors "verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceHandler 设备管理处理器
+
+type DeviceHandler struct {
+	deviceService *service.DeviceService
+}
+
+// NewDeviceHandler 创建设备管理处理器
+func NewDeviceHandler(deviceService *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{deviceService: deviceService}
+}
+
+// GetDeviceList 获取设备列表接口
+// 支持按VKey、禁用状态筛选以及按设备标识或IP地址搜索
+func (h *DeviceHandler) GetDeviceList(c *gin.Context) {
+	akey := c.Param("akey")
+
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	validPage, validSize, err := validator.ValidatePagination(page, size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	query := &model.DeviceQuery{
+		VKey:    c.Query("vkey"),
+		Keyword: c.Query("keyword"),
+	}
+	if blocked := c.Query("blocked"); blocked != "" {
+		value, err := strconv.ParseBool(blocked)
+		if err != nil {
+			respondError(c, apperrors.NewValidationError("blocked 参数必须为 true 或 false"))
+			return
+		}
+		query.Blocked = &value
+	}
+
+	devices, total, err := h.deviceService.GetDeviceList(akey, query, validPage, validSize)
+	if err != nil {
+		logger.Errorf("获取设备列表失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	deviceList := make([]map[string]interface{}, 0, len(devices))
+	for _, device := range devices {
+		deviceList = append(deviceList, formatDevice(device))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  deviceList,
+		"total": total,
+		"page":  validPage,
+		"size":  validSize,
+	})
+}
+
+// BlockDevice 禁用设备接口
+func (h *DeviceHandler) BlockDevice(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "设备ID无效")
+	if !ok {
+		return
+	}
+
+	// 绑定请求体，禁用原因可选
+	var request struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, apperrors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	device, err := h.deviceService.BlockDevice(akey, id, request.Reason)
+	if err != nil {
+		logger.Errorf("禁用设备失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功禁用设备 (AKey: %s, 设备: %s)", akey, device.DeviceID)
+
+	respondSuccess(c, formatDevice(device))
+}
+
+// UnblockDevice 解除设备禁用接口
+func (h *DeviceHandler) UnblockDevice(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "设备ID无效")
+	if !ok {
+		return
+	}
+
+	device, err := h.deviceService.UnblockDevice(akey, id)
+	if err != nil {
+		logger.Errorf("解除设备禁用失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功解除设备禁用 (AKey: %s, 设备: %s)", akey, device.DeviceID)
+
+	respondSuccess(c, formatDevice(device))
+}
+
+// DeleteDevice 注销设备接口
+func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "设备ID无效")
+	if !ok {
+		return
+	}
+
+	if err := h.deviceService.DeleteDevice(akey, id); err != nil {
+		logger.Errorf("注销设备失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功注销设备 (AKey: %s, ID: %d)", akey, id)
+
+	respondSuccess(c, map[string]interface{}{
+		"message": "注销成功",
+	})
+}
+
+// formatDevice 格式化设备信息
+func formatDevice(device *model.Device) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            device.ID,
+		"akey":          device.AKey,
+		"device_id":     device.DeviceID,
+		"vkey":          device.VKey,
+		"version":       device.Version,
+		"ip":            device.IP,
+		"first_seen_at": device.FirstSeenAt.Format("2006-01-02T15:04:05Z"),
+		"last_seen_at":  device.LastSeenAt.Format("2006-01-02T15:04:05Z"),
+		"blocked":       device.IsBlocked(),
+		"blocked_at":    formatOptionalTime(device.BlockedAt),
+		"block_reason":  device.BlockReason,
+	}
+}
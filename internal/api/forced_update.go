@@ -0,0 +1,110 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForcedUpdateHandler 强制更新版本范围处理器
+
+type ForcedUpdateHandler struct {
+	forcedUpdateService *service.ForcedUpdateService
+}
+
+// NewForcedUpdateHandler 创建强制更新版本范围处理器
+func NewForcedUpdateHandler(forcedUpdateService *service.ForcedUpdateService) *ForcedUpdateHandler {
+	return &ForcedUpdateHandler{forcedUpdateService: forcedUpdateService}
+}
+
+// CreateRange 添加强制更新版本范围接口
+func (h *ForcedUpdateHandler) CreateRange(c *gin.Context) {
+	akey := c.Param("akey")
+
+	// 绑定请求体
+	var request struct {
+		MinVersion string `json:"min_version"`
+		MaxVersion string `json:"max_version"`
+		Reason     string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("添加强制更新版本范围请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	forcedRange, err := h.forcedUpdateService.CreateRange(akey, strings.TrimSpace(request.MinVersion), strings.TrimSpace(request.MaxVersion), request.Reason)
+	if err != nil {
+		logger.Errorf("添加强制更新版本范围失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功添加强制更新版本范围 (AKey: %s, ID: %d)", akey, forcedRange.ID)
+
+	respondSuccess(c, formatForcedUpdateRange(forcedRange))
+}
+
+// GetRangeList 获取强制更新版本范围列表接口
+func (h *ForcedUpdateHandler) GetRangeList(c *gin.Context) {
+	akey := c.Param("akey")
+
+	ranges, err := h.forcedUpdateService.GetRanges(akey)
+	if err != nil {
+		logger.Errorf("获取强制更新版本范围失败 (AKey: %s): %v", akey, err)
+		respondError(c, errors.WrapError(err, "获取强制更新版本范围失败"))
+		return
+	}
+
+	rangeList := make([]map[string]interface{}, 0, len(ranges))
+	for _, forcedRange := range ranges {
+		rangeList = append(rangeList, formatForcedUpdateRange(forcedRange))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  rangeList,
+		"total": len(rangeList),
+	})
+}
+
+// DeleteRange 删除强制更新版本范围接口
+func (h *ForcedUpdateHandler) DeleteRange(c *gin.Context) {
+	akey := c.Param("akey")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, errors.NewValidationError("范围ID无效"))
+		return
+	}
+
+	if err := h.forcedUpdateService.DeleteRange(akey, uint(id)); err != nil {
+		logger.Errorf("删除强制更新版本范围失败 (AKey: %s, ID: %d): %v", akey, id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功删除强制更新版本范围 (AKey: %s, ID: %d)", akey, id)
+
+	respondSuccess(c, map[string]interface{}{
+		"message": "删除成功",
+	})
+}
+
+// formatForcedUpdateRange 格式化强制更新版本范围信息
+func formatForcedUpdateRange(forcedRange *model.ForcedUpdateRange) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          forcedRange.ID,
+		"akey":        forcedRange.AKey,
+		"min_version": forcedRange.MinVersion,
+		"max_version": forcedRange.MaxVersion,
+		"reason":      forcedRange.Reason,
+		"created_at":  forcedRange.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LicenseHandler 许可证管理处理器
+
+type LicenseHandler struct {
+	licenseService *service.LicenseService
+}
+
+// NewLicenseHandler 创建许可证管理处理器
+func NewLicenseHandler(licenseService *service.LicenseService) *LicenseHandler {
+	return &LicenseHandler{licenseService: licenseService}
+}
+
+// CreateLicense 签发许可证接口
+func (h *LicenseHandler) CreateLicense(c *gin.Context) {
+	akey := c.Param("akey")
+
+	// 绑定请求体
+	var request struct {
+		Seats         int    `json:"seats" binding:"required"`
+		ExpiresInDays int    `json:"expires_in_days"`
+		Note          string `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("签发许可证请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	principal := currentPrincipal(c)
+	license, err := h.licenseService.CreateLicense(principal, akey, request.Seats, request.ExpiresInDays, request.Note)
+	if err != nil {
+		logger.Errorf("签发许可证失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("用户 %s 为应用 %s 签发许可证 (ID: %d)", principal.Username, akey, license.ID)
+
+	respondSuccess(c, formatLicense(license))
+}
+
+// GetLicenseList 获取许可证列表接口
+func (h *LicenseHandler) GetLicenseList(c *gin.Context) {
+	akey := c.Param("akey")
+
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	validPage, validSize, err := validator.ValidatePagination(page, size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	licenses, total, err := h.licenseService.GetLicenseList(akey, validPage, validSize)
+	if err != nil {
+		logger.Errorf("获取许可证列表失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	licenseList := make([]map[string]interface{}, 0, len(licenses))
+	for _, license := range licenses {
+		licenseList = append(licenseList, formatLicense(license))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  licenseList,
+		"total": total,
+		"page":  validPage,
+		"size":  validSize,
+	})
+}
+
+// GetLicense 获取许可证详情接口，包含已激活的设备
+func (h *LicenseHandler) GetLicense(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "许可证ID无效")
+	if !ok {
+		return
+	}
+
+	detail, err := h.licenseService.GetLicense(akey, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	activations := make([]map[string]interface{}, 0, len(detail.Activations))
+	for _, activation := range detail.Activations {
+		activations = append(activations, map[string]interface{}{
+			"id":           activation.ID,
+			"device_id":    activation.DeviceID,
+			"last_seen_at": activation.LastSeenAt.Format("2006-01-02T15:04:05Z"),
+			"created_at":   activation.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	result := formatLicense(detail.License)
+	result["activations"] = activations
+	respondSuccess(c, result)
+}
+
+// RevokeLicense 吊销许可证接口
+func (h *LicenseHandler) RevokeLicense(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "许可证ID无效")
+	if !ok {
+		return
+	}
+
+	if err := h.licenseService.RevokeLicense(akey, id); err != nil {
+		logger.Errorf("吊销许可证失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功吊销许可证 (ID: %d)", id)
+
+	respondSuccess(c, map[string]interface{}{
+		"message": "吊销成功",
+	})
+}
+
+// DeleteActivation 删除设备激活记录接口，释放席位
+func (h *LicenseHandler) DeleteActivation(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "许可证ID无效")
+	if !ok {
+		return
+	}
+	activationID, ok := parseIDParam(c, "activation_id", "激活记录ID无效")
+	if !ok {
+		return
+	}
+
+	if err := h.licenseService.DeleteActivation(akey, id, activationID); err != nil {
+		logger.Errorf("删除激活记录失败 (许可证ID: %d, 激活记录ID: %d): %v", id, activationID, err)
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"message": "删除成功",
+	})
+}
+
+// ExportOfflineLicense 导出离线许可证文件接口
+func (h *LicenseHandler) ExportOfflineLicense(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "许可证ID无效")
+	if !ok {
+		return
+	}
+
+	// 绑定请求体
+	var request struct {
+		DeviceID      string `json:"device_id" binding:"required"`
+		MinVersion    string `json:"min_version"`
+		MaxVersion    string `json:"max_version"`
+		ExpiresInDays int    `json:"expires_in_days"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("导出离线许可证请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	offlineLicense, err := h.licenseService.ExportOfflineLicense(akey, id, request.DeviceID, request.MinVersion, request.MaxVersion, request.ExpiresInDays)
+	if err != nil {
+		logger.Errorf("导出离线许可证失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功导出离线许可证 (ID: %d, 设备: %s)", id, offlineLicense.Claims.DeviceID)
+
+	claims := offlineLicense.Claims
+	var expiresAt *time.Time
+	if claims.ExpiresAt != 0 {
+		t := time.Unix(claims.ExpiresAt, 0)
+		expiresAt = &t
+	}
+	respondSuccess(c, map[string]interface{}{
+		"file":        offlineLicense.File,
+		"key_id":      claims.KeyID,
+		"akey":        claims.AKey,
+		"license_key": claims.LicenseKey,
+		"device_id":   claims.DeviceID,
+		"min_version": claims.MinVersion,
+		"max_version": claims.MaxVersion,
+		"issued_at":   time.Unix(claims.IssuedAt, 0).Format("2006-01-02T15:04:05Z"),
+		"expires_at":  formatOptionalTime(expiresAt),
+	})
+}
+
+// formatLicense 格式化许可证信息
+func formatLicense(license *model.License) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               license.ID,
+		"akey":             license.AKey,
+		"license_key":      license.LicenseKey,
+		"seats":            license.Seats,
+		"activation_count": license.ActivationCount,
+		"note":             license.Note,
+		"user_id":          license.UserID,
+		"expires_at":       formatOptionalTime(license.ExpiresAt),
+		"revoked_at":       formatOptionalTime(license.RevokedAt),
+		"created_at":       license.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// parseIDParam 解析路径中的数字ID参数，无效时返回400
+func parseIDParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		respondError(c, errors.NewValidationError(message))
+		return 0, false
+	}
+	return uint(id), true
+}
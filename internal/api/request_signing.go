@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 校验请求签名相关的请求头
+const (
+	headerRequestTimestamp = "X-Request-Timestamp"
+	headerRequestNonce     = "X-Request-Nonce"
+	headerRequestSignature = "X-Request-Signature"
+)
+
+// 校验请求体的最大长度
+const maxCheckBodySize = 1 << 20
+
+// 请求体和其中的AKey在请求上下文中的键名
+const (
+	requestBodyContextKey = "request_body"
+	requestAKeyContextKey = "request_akey"
+)
+
+// PeekRequestBody 读取原始请求体并缓存在请求上下文中，同时恢复请求体供处理器绑定
+// 供需要在处理器之前读取请求体的中间件使用
+func PeekRequestBody(c *gin.Context) ([]byte, error) {
+	if value, exists := c.Get(requestBodyContextKey); exists {
+		return value.([]byte), nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCheckBodySize))
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Set(requestBodyContextKey, body)
+	return body, nil
+}
+
+// RequestAKey 获取校验请求体中的AKey，请求体无法解析时返回空字符串
+func RequestAKey(c *gin.Context) string {
+	if value, exists := c.Get(requestAKeyContextKey); exists {
+		return value.(string)
+	}
+
+	var payload struct {
+		AKey string `json:"akey"`
+	}
+	if body, err := PeekRequestBody(c); err == nil {
+		_ = json.Unmarshal(body, &payload)
+	}
+	c.Set(requestAKeyContextKey, payload.AKey)
+	return payload.AKey
+}
+
+// RequestSignatureMiddleware 校验请求签名中间件
+// 读取请求体中的AKey并校验请求签名，校验后恢复请求体供处理器绑定；
+// 请求体无法解析时交给处理器返回参数错误
+func RequestSignatureMiddleware(requestSigningService *service.RequestSigningService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := PeekRequestBody(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
+			c.Abort()
+			return
+		}
+
+		akey := RequestAKey(c)
+		if akey == "" {
+			c.Next()
+			return
+		}
+
+		err = requestSigningService.VerifyRequest(&service.SignedRequest{
+			AKey:      akey,
+			Timestamp: c.GetHeader(headerRequestTimestamp),
+			Nonce:     c.GetHeader(headerRequestNonce),
+			Signature: c.GetHeader(headerRequestSignature),
+			Body:      body,
+		})
+		if err != nil {
+			logger.Infof("校验请求签名失败 (AKey: %s, IP: %s): %v", akey, c.ClientIP(), err)
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequestSigningHandler 校验请求签名设置处理器
+
+type RequestSigningHandler struct {
+	requestSigningService *service.RequestSigningService
+}
+
+// NewRequestSigningHandler 创建校验请求签名设置处理器
+func NewRequestSigningHandler(requestSigningService *service.RequestSigningService) *RequestSigningHandler {
+	return &RequestSigningHandler{requestSigningService: requestSigningService}
+}
+
+// GetSettings 获取请求签名设置接口，返回签名密钥
+func (h *RequestSigningHandler) GetSettings(c *gin.Context) {
+	app, err := h.requestSigningService.GetSettings(c.Param("akey"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, formatRequestSigning(app))
+}
+
+// UpdateSettings 设置是否要求校验请求必须签名接口
+func (h *RequestSigningHandler) UpdateSettings(c *gin.Context) {
+	akey := c.Param("akey")
+
+	// 绑定请求体
+	var request struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("更新请求签名设置请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	app, err := h.requestSigningService.SetRequired(akey, *request.Required)
+	if err != nil {
+		logger.Errorf("更新请求签名设置失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功更新请求签名设置 (AKey: %s, 要求签名: %t)", akey, app.RequireSignedRequests)
+
+	respondSuccess(c, formatRequestSigning(app))
+}
+
+// RotateSecret 轮换请求签名密钥接口
+func (h *RequestSigningHandler) RotateSecret(c *gin.Context) {
+	akey := c.Param("akey")
+
+	app, err := h.requestSigningService.RotateSecret(akey)
+	if err != nil {
+		logger.Errorf("轮换请求签名密钥失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功轮换请求签名密钥 (AKey: %s)", akey)
+
+	respondSuccess(c, formatRequestSigning(app))
+}
+
+// formatRequestSigning 格式化请求签名设置
+func formatRequestSigning(app *model.App) map[string]interface{} {
+	return map[string]interface{}{
+		"akey":     app.AKey,
+		"required": app.RequireSignedRequests,
+		"secret":   app.RequestSecret,
+	}
+}
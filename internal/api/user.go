@@ -0,0 +1,161 @@
+package api
+
+import (
+	"strconv"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler 用户管理API处理器
+
+type UserHandler struct {
+	userService *service.UserService
+}
+
+// NewUserHandler 创建用户管理API处理器
+func NewUserHandler(userService *service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+// CreateUser 创建用户接口
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	// 绑定请求体
+	var request struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		Role     string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("创建用户请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	// 调用服务层创建用户
+	user, err := h.userService.CreateUser(currentActor(c), request.Username, request.Password, request.Role)
+	if err != nil {
+		logger.Errorf("创建用户失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功创建用户: %s (角色: %s)", user.Username, user.Role)
+
+	// 返回成功响应
+	respondSuccess(c, formatUser(user))
+}
+
+// GetUserList 获取用户列表接口
+func (h *UserHandler) GetUserList(c *gin.Context) {
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	// 验证分页参数
+	validPage, validSize, err := validator.ValidatePagination(page, size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 调用服务层获取用户列表
+	users, total, err := h.userService.GetUserList(validPage, validSize)
+	if err != nil {
+		logger.Errorf("获取用户列表失败: %v", err)
+		respondError(c, errors.WrapError(err, "获取用户列表失败"))
+		return
+	}
+
+	// 构造响应数据
+	userList := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		userList = append(userList, formatUser(user))
+	}
+
+	// 返回成功响应
+	respondSuccess(c, map[string]interface{}{
+		"list":  userList,
+		"total": total,
+		"page":  validPage,
+		"size":  validSize,
+	})
+}
+
+// UpdateUser 更新用户接口
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, errors.NewValidationError("用户ID无效"))
+		return
+	}
+
+	// 绑定请求体
+	var request struct {
+		Role     string `json:"role" binding:"required"`
+		IsActive bool   `json:"is_active"`
+		Password string `json:"password"` // 可选，非空时重置密码
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("更新用户请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	// 调用服务层更新用户
+	user, err := h.userService.UpdateUser(currentActor(c), uint(id), request.Role, request.IsActive, request.Password)
+	if err != nil {
+		logger.Errorf("更新用户失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功更新用户: %s (角色: %s)", user.Username, user.Role)
+
+	// 返回成功响应
+	respondSuccess(c, formatUser(user))
+}
+
+// DeleteUser 删除用户接口
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	// 获取用户ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, errors.NewValidationError("用户ID无效"))
+		return
+	}
+
+	// 调用服务层删除用户
+	if err := h.userService.DeleteUser(currentActor(c), uint(id)); err != nil {
+		logger.Errorf("删除用户失败 (ID: %d): %v", id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功删除用户 (ID: %d)", id)
+
+	// 返回成功响应
+	respondSuccess(c, map[string]interface{}{
+		"message": "删除成功",
+	})
+}
+
+// formatUser 格式化用户信息，不包含密码
+func formatUser(user *model.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            user.ID,
+		"username":      user.Username,
+		"role":          user.Role,
+		"is_active":     user.IsActive,
+		"last_login_at": formatOptionalTime(user.LastLoginAt),
+		"created_at":    user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
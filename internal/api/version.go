@@ -1,10 +1,16 @@
 package api
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
+	apperrors "verkeyoss/internal/errors"
+
+	"verkeyoss/internal/model"
 	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,9 +34,11 @@ func (h *VersionHandler) CreateVersion(c *gin.Context) {
 	// 绑定请求体
 	var versionRequest struct {
 		Version        string `json:"version" binding:"required"`
+		Channel        string `json:"channel"`
 		Description    string `json:"description"`
 		IsLatest       bool   `json:"is_latest"`
 		IsForcedUpdate bool   `json:"is_forced_update"`
+		RolloutPercent int    `json:"rollout_percent"`
 	}
 
 	if err := c.ShouldBindJSON(&versionRequest); err != nil {
@@ -38,10 +46,16 @@ func (h *VersionHandler) CreateVersion(c *gin.Context) {
 		return
 	}
 
+	// 版本号必须符合语义化版本号格式，以便计算最新版本
+	if err := validator.ValidateVersion(versionRequest.Version); err != nil {
+		respondError(c, err)
+		return
+	}
+
 	// 调用服务层创建版本
-	version, err := h.service.CreateVersion(akey, versionRequest.Version, versionRequest.Description, versionRequest.IsLatest, versionRequest.IsForcedUpdate)
+	version, err := h.service.CreateVersion(currentActor(c), akey, versionRequest.Version, versionRequest.Channel, versionRequest.Description, versionRequest.IsLatest, versionRequest.IsForcedUpdate, versionRequest.RolloutPercent)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "创建版本失败"))
+		respondVersionError(c, err, "创建版本失败")
 		return
 	}
 
@@ -50,9 +64,14 @@ func (h *VersionHandler) CreateVersion(c *gin.Context) {
 		"vkey":             version.VKey,
 		"akey":             version.AKey,
 		"version":          version.Version,
+		"channel":          version.Channel,
 		"description":      version.Description,
 		"is_latest":        version.IsLatest,
 		"is_forced_update": version.IsForcedUpdate,
+		"rollout_percent":  version.RolloutPercent,
+		"rollout_status":   version.RolloutStatus,
+		"revoked_at":       formatOptionalTime(version.RevokedAt),
+		"revoke_reason":    version.RevokeReason,
 		"created_at":       version.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}))
 }
@@ -66,10 +85,10 @@ func (h *VersionHandler) GetVersionList(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
 
-	// 调用服务层获取版本列表
-	versions, total, err := h.service.GetVersionList(akey, page, size)
+	// 调用服务层获取版本列表，channel 为空时返回所有渠道的版本
+	versions, total, err := h.service.GetVersionList(akey, c.Query("channel"), page, size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "获取版本列表失败"))
+		respondVersionError(c, err, "获取版本列表失败")
 		return
 	}
 
@@ -79,9 +98,14 @@ func (h *VersionHandler) GetVersionList(c *gin.Context) {
 		resultList = append(resultList, map[string]interface{}{
 			"vkey":             version.VKey,
 			"version":          version.Version,
+			"channel":          version.Channel,
 			"description":      version.Description,
 			"is_latest":        version.IsLatest,
 			"is_forced_update": version.IsForcedUpdate,
+			"rollout_percent":  version.RolloutPercent,
+			"rollout_status":   version.RolloutStatus,
+			"revoked_at":       formatOptionalTime(version.RevokedAt),
+			"revoke_reason":    version.RevokeReason,
 			"created_at":       version.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		})
 	}
@@ -101,6 +125,7 @@ func (h *VersionHandler) UpdateVersion(c *gin.Context) {
 	// 绑定请求体
 	var updateRequest struct {
 		Version        string `json:"version"`
+		Channel        string `json:"channel"`
 		Description    string `json:"description"`
 		IsLatest       bool   `json:"is_latest"`
 		IsForcedUpdate bool   `json:"is_forced_update"`
@@ -111,14 +136,18 @@ func (h *VersionHandler) UpdateVersion(c *gin.Context) {
 		return
 	}
 
+	// 版本号为空表示不修改，非空时必须符合语义化版本号格式
+	if updateRequest.Version != "" {
+		if err := validator.ValidateVersion(updateRequest.Version); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
 	// 调用服务层更新版本
-	err := h.service.UpdateVersion(vkey, updateRequest.Version, updateRequest.Description, updateRequest.IsLatest, updateRequest.IsForcedUpdate)
+	err := h.service.UpdateVersion(currentActor(c), vkey, updateRequest.Version, updateRequest.Channel, updateRequest.Description, updateRequest.IsLatest, updateRequest.IsForcedUpdate)
 	if err != nil {
-		if err.Error() == "版本不存在" {
-			c.JSON(http.StatusNotFound, ErrorResponse(404, "VKey不存在"))
-		} else {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "更新版本失败"))
-		}
+		respondVersionError(c, err, "更新版本失败")
 		return
 	}
 
@@ -135,13 +164,9 @@ func (h *VersionHandler) DeleteVersion(c *gin.Context) {
 	vkey := c.Param("vkey")
 
 	// 调用服务层删除版本
-	err := h.service.DeleteVersion(vkey)
+	err := h.service.DeleteVersion(currentActor(c), vkey)
 	if err != nil {
-		if err.Error() == "版本不存在" {
-			c.JSON(http.StatusNotFound, ErrorResponse(404, "VKey不存在"))
-		} else {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "删除版本失败"))
-		}
+		respondVersionError(c, err, "删除版本失败")
 		return
 	}
 
@@ -151,3 +176,194 @@ func (h *VersionHandler) DeleteVersion(c *gin.Context) {
 		"message": "删除成功",
 	})
 }
+
+// SetRolloutPercent 设置灰度发布比例接口
+func (h *VersionHandler) SetRolloutPercent(c *gin.Context) {
+	// 获取VKey
+	vkey := c.Param("vkey")
+
+	// 绑定请求体
+	var rolloutRequest struct {
+		Percent int `json:"percent" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&rolloutRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
+		return
+	}
+
+	version, err := h.service.SetRolloutPercent(currentActor(c), vkey, rolloutRequest.Percent)
+	if err != nil {
+		respondVersionError(c, err, "设置灰度发布比例失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(formatRollout(version)))
+}
+
+// PauseRollout 暂停灰度发布接口
+func (h *VersionHandler) PauseRollout(c *gin.Context) {
+	version, err := h.service.PauseRollout(currentActor(c), c.Param("vkey"))
+	if err != nil {
+		respondVersionError(c, err, "暂停灰度发布失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(formatRollout(version)))
+}
+
+// ResumeRollout 恢复灰度发布接口
+func (h *VersionHandler) ResumeRollout(c *gin.Context) {
+	version, err := h.service.ResumeRollout(currentActor(c), c.Param("vkey"))
+	if err != nil {
+		respondVersionError(c, err, "恢复灰度发布失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(formatRollout(version)))
+}
+
+// HaltRollout 终止灰度发布接口
+func (h *VersionHandler) HaltRollout(c *gin.Context) {
+	version, err := h.service.HaltRollout(currentActor(c), c.Param("vkey"))
+	if err != nil {
+		respondVersionError(c, err, "终止灰度发布失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(formatRollout(version)))
+}
+
+// formatRollout 格式化版本的灰度发布状态
+func formatRollout(version *model.Version) map[string]interface{} {
+	return map[string]interface{}{
+		"vkey":            version.VKey,
+		"version":         version.Version,
+		"rollout_percent": version.RolloutPercent,
+		"rollout_status":  version.RolloutStatus,
+	}
+}
+
+// RevokeVersion 撤回版本接口
+func (h *VersionHandler) RevokeVersion(c *gin.Context) {
+	// 获取VKey
+	vkey := c.Param("vkey")
+
+	// 绑定请求体，撤回原因可选
+	var revokeRequest struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&revokeRequest); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
+		return
+	}
+
+	if err := validator.ValidateDescription(revokeRequest.Reason); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	version, err := h.service.RevokeVersion(currentActor(c), vkey, revokeRequest.Reason)
+	if err != nil {
+		respondVersionError(c, err, "撤回版本失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(formatRevocation(version)))
+}
+
+// RestoreVersion 恢复已撤回的版本接口
+func (h *VersionHandler) RestoreVersion(c *gin.Context) {
+	version, err := h.service.RestoreVersion(currentActor(c), c.Param("vkey"))
+	if err != nil {
+		respondVersionError(c, err, "恢复版本失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(formatRevocation(version)))
+}
+
+// formatRevocation 格式化版本的撤回状态
+func formatRevocation(version *model.Version) map[string]interface{} {
+	return map[string]interface{}{
+		"vkey":          version.VKey,
+		"version":       version.Version,
+		"revoked_at":    formatOptionalTime(version.RevokedAt),
+		"revoke_reason": version.RevokeReason,
+	}
+}
+
+// RotateVKey 轮换VKey接口
+// 旧VKey在宽限期内仍可通过校验，新VKey只在本接口的响应中返回
+func (h *VersionHandler) RotateVKey(c *gin.Context) {
+	// 获取VKey
+	vkey := c.Param("vkey")
+
+	// 绑定请求体，宽限期可选
+	var rotateRequest struct {
+		GraceHours *int `json:"grace_hours"`
+	}
+
+	if err := c.ShouldBindJSON(&rotateRequest); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "参数错误"))
+		return
+	}
+
+	rotation, err := h.service.RotateVKey(currentActor(c), vkey, rotateRequest.GraceHours)
+	if err != nil {
+		respondVersionError(c, err, "轮换VKey失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(map[string]interface{}{
+		"vkey":                rotation.NewKey,
+		"old_vkey":            rotation.OldKey,
+		"old_vkey_expires_at": rotation.OldKeyExpiresAt.Format("2006-01-02T15:04:05Z"),
+	}))
+}
+
+// GetChannelSummaries 获取应用各发布渠道概要接口
+// 返回每个渠道的版本数量以及订阅该渠道的客户端将获取的最新版本
+func (h *VersionHandler) GetChannelSummaries(c *gin.Context) {
+	// 获取AKey
+	akey := c.Param("akey")
+
+	summaries, err := h.service.GetChannelSummaries(akey)
+	if err != nil {
+		respondVersionError(c, err, "获取渠道信息失败")
+		return
+	}
+
+	resultList := make([]map[string]interface{}, 0, len(summaries))
+	for _, summary := range summaries {
+		item := map[string]interface{}{
+			"channel":        summary.Channel,
+			"version_count":  summary.VersionCount,
+			"latest_version": nil,
+		}
+		if latest := summary.LatestVersion; latest != nil {
+			item["latest_version"] = map[string]interface{}{
+				"vkey":       latest.VKey,
+				"version":    latest.Version,
+				"channel":    latest.Channel,
+				"created_at": latest.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			}
+		}
+		resultList = append(resultList, item)
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(resultList))
+}
+
+// respondVersionError 返回版本接口的错误响应
+// 版本不存在返回404，应用错误使用其定义的错误码，其他错误返回500
+func respondVersionError(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrVersionNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "VKey不存在"))
+	} else if appErr, ok := apperrors.IsAppError(err); ok {
+		c.JSON(appErr.Code, ErrorResponse(appErr.Code, appErr.Message))
+	} else {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, message))
+	}
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/service"
+	"verkeyoss/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler Webhook处理器
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+// NewWebhookHandler 创建Webhook处理器
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// CreateWebhook 创建Webhook订阅接口
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	akey := c.Param("akey")
+
+	// 绑定请求体
+	var request struct {
+		Name   string   `json:"name" binding:"required"`
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("创建Webhook请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(akey, request.Name, request.URL, request.Events)
+	if err != nil {
+		logger.Errorf("创建Webhook失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功创建Webhook (AKey: %s, ID: %d)", akey, webhook.ID)
+
+	respondSuccess(c, formatWebhook(webhook))
+}
+
+// GetWebhookList 获取Webhook订阅列表接口
+func (h *WebhookHandler) GetWebhookList(c *gin.Context) {
+	akey := c.Param("akey")
+
+	webhooks, err := h.webhookService.GetWebhooks(akey)
+	if err != nil {
+		logger.Errorf("获取Webhook列表失败 (AKey: %s): %v", akey, err)
+		respondError(c, err)
+		return
+	}
+
+	webhookList := make([]map[string]interface{}, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		webhookList = append(webhookList, formatWebhook(webhook))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  webhookList,
+		"total": len(webhookList),
+	})
+}
+
+// UpdateWebhook 更新Webhook订阅接口
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "Webhook ID无效")
+	if !ok {
+		return
+	}
+
+	// 绑定请求体，未提供的字段不修改
+	var request struct {
+		Name     string   `json:"name"`
+		URL      string   `json:"url"`
+		Events   []string `json:"events"`
+		IsActive *bool    `json:"is_active"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Errorf("更新Webhook请求参数错误: %v", err)
+		respondError(c, errors.NewValidationError("请求参数错误"))
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(akey, id, request.Name, request.URL, request.Events, request.IsActive)
+	if err != nil {
+		logger.Errorf("更新Webhook失败 (AKey: %s, ID: %d): %v", akey, id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功更新Webhook (AKey: %s, ID: %d)", akey, id)
+
+	respondSuccess(c, formatWebhook(webhook))
+}
+
+// DeleteWebhook 删除Webhook订阅接口
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "Webhook ID无效")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(akey, id); err != nil {
+		logger.Errorf("删除Webhook失败 (AKey: %s, ID: %d): %v", akey, id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功删除Webhook (AKey: %s, ID: %d)", akey, id)
+
+	respondSuccess(c, map[string]interface{}{
+		"message": "删除成功",
+	})
+}
+
+// RotateSecret 轮换Webhook签名密钥接口
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "Webhook ID无效")
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.RotateSecret(akey, id)
+	if err != nil {
+		logger.Errorf("轮换Webhook签名密钥失败 (AKey: %s, ID: %d): %v", akey, id, err)
+		respondError(c, err)
+		return
+	}
+
+	logger.Infof("成功轮换Webhook签名密钥 (AKey: %s, ID: %d)", akey, id)
+
+	respondSuccess(c, formatWebhook(webhook))
+}
+
+// SendTestEvent 发送测试事件接口
+func (h *WebhookHandler) SendTestEvent(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "Webhook ID无效")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.SendTestEvent(akey, id)
+	if err != nil {
+		logger.Errorf("发送Webhook测试事件失败 (AKey: %s, ID: %d): %v", akey, id, err)
+		respondError(c, err)
+		return
+	}
+
+	respondSuccess(c, formatWebhookDelivery(delivery))
+}
+
+// GetDeliveryList 获取Webhook投递记录接口
+// 支持按投递状态筛选
+func (h *WebhookHandler) GetDeliveryList(c *gin.Context) {
+	akey := c.Param("akey")
+	id, ok := parseIDParam(c, "id", "Webhook ID无效")
+	if !ok {
+		return
+	}
+
+	// 获取分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	validPage, validSize, err := validator.ValidatePagination(page, size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	deliveries, total, err := h.webhookService.GetDeliveries(akey, id, c.Query("status"), validPage, validSize)
+	if err != nil {
+		logger.Errorf("获取Webhook投递记录失败 (AKey: %s, ID: %d): %v", akey, id, err)
+		respondError(c, err)
+		return
+	}
+
+	deliveryList := make([]map[string]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryList = append(deliveryList, formatWebhookDelivery(delivery))
+	}
+
+	respondSuccess(c, map[string]interface{}{
+		"list":  deliveryList,
+		"total": total,
+		"page":  validPage,
+		"size":  validSize,
+	})
+}
+
+// formatWebhook 格式化Webhook订阅信息，包含签名密钥
+func formatWebhook(webhook *model.Webhook) map[string]interface{} {
+	events := webhook.EventList()
+	if events == nil {
+		events = []string{}
+	}
+	return map[string]interface{}{
+		"id":         webhook.ID,
+		"name":       webhook.Name,
+		"url":        webhook.URL,
+		"secret":     webhook.Secret,
+		"events":     events,
+		"is_active":  webhook.IsActive,
+		"created_at": webhook.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"updated_at": webhook.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// formatWebhookDelivery 格式化Webhook投递记录
+func formatWebhookDelivery(delivery *model.WebhookDelivery) map[string]interface{} {
+	// 下次尝试时间仅对等待重试的记录有意义
+	var nextAttemptAt *time.Time
+	if delivery.Status == model.DeliveryPending {
+		nextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return map[string]interface{}{
+		"id":              delivery.ID,
+		"webhook_id":      delivery.WebhookID,
+		"event_id":        delivery.EventID,
+		"event":           delivery.Event,
+		"payload":         delivery.Payload,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"last_attempt_at": formatOptionalTime(delivery.LastAttemptAt),
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"error":           delivery.Error,
+		"delivered_at":    formatOptionalTime(delivery.DeliveredAt),
+		"next_attempt_at": formatOptionalTime(nextAttemptAt),
+		"created_at":      delivery.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,68 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"verkeyoss/internal/config"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// ErrDigestMismatch 写入的内容与声明的大小或SHA-256摘要不一致
+var ErrDigestMismatch = errors.New("对象内容与声明的大小或SHA-256摘要不一致")
+
+// Object 写入对象时的元数据
+type Object struct {
+	Key         string // 对象键，使用 / 分隔的相对路径
+	Size        int64  // 内容长度（字节）
+	SHA256      string // 内容的SHA-256摘要（十六进制），存储在写入时校验内容是否完整
+	ContentType string // 内容类型
+}
+
+// Store 制品存储接口
+// 服务端只保存制品的元数据，文件内容保存在实现了该接口的存储中
+type Store interface {
+	// Put 写入对象，内容与 obj 中的大小或摘要不一致时返回 ErrDigestMismatch 且不保留对象
+	Put(ctx context.Context, obj Object, r io.Reader) error
+	// Open 读取对象，对象不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// DownloadURL 生成客户端可直接访问的下载地址，在 ttl 之后失效；
+	// 返回空字符串表示存储不支持直接下载，需要通过服务端的下载接口获取
+	DownloadURL(key string, ttl time.Duration) (string, error)
+}
+
+// Open 根据配置创建制品存储
+func Open(appConfig *config.Config) (Store, error) {
+	storage := appConfig.Storage
+	switch storage.Driver {
+	case config.StorageDriverLocal:
+		return NewLocalStore(storage.Local.Path)
+	case config.StorageDriverS3:
+		return NewS3Store(S3Config{
+			Endpoint:           storage.S3.Endpoint,
+			PublicEndpoint:     storage.S3.PublicEndpoint,
+			Region:             storage.S3.Region,
+			Bucket:             storage.S3.Bucket,
+			AccessKeyID:        storage.S3.AccessKeyID,
+			SecretAccessKey:    storage.S3.SecretAccessKey,
+			VirtualHostedStyle: storage.S3.VirtualHostedStyle,
+		})
+	}
+	return nil, fmt.Errorf("不支持的制品存储类型: %s", storage.Driver)
+}
+
+// Describe 返回便于日志输出的制品存储描述，不包含访问密钥
+func Describe(appConfig *config.Config) string {
+	storage := appConfig.Storage
+	if storage.Driver == config.StorageDriverS3 {
+		return fmt.Sprintf("s3://%s (%s)", storage.S3.Bucket, storage.S3.Endpoint)
+	}
+	return fmt.Sprintf("local://%s", storage.Local.Path)
+}
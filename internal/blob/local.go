@@ -0,0 +1,113 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore 本地文件系统制品存储
+// 对象保存在根目录下与对象键相同的相对路径中，不支持直接下载，由服务端下载接口读取文件
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore 创建本地文件系统制品存储，根目录不存在时自动创建
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("制品存储目录不能为空")
+	}
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return nil, fmt.Errorf("创建制品存储目录失败: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// path 返回对象键对应的文件路径，拒绝指向根目录之外的对象键
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("无效的对象键: %s", key)
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+// Put 写入对象
+// 先写入同目录下的临时文件，校验大小和摘要后再重命名，避免读取到写入不完整的文件
+func (s *LocalStore) Put(ctx context.Context, obj Object, r io.Reader) error {
+	path, err := s.path(obj.Key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("创建制品目录失败: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	// 出错时清理临时文件，重命名成功后该操作不会产生影响
+	defer os.Remove(tmpPath)
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmpFile, hash), r)
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("写入制品文件失败: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("写入制品文件失败: %w", err)
+	}
+	if written != obj.Size || (obj.SHA256 != "" && hex.EncodeToString(hash.Sum(nil)) != obj.SHA256) {
+		return ErrDigestMismatch
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("保存制品文件失败: %w", err)
+	}
+	return nil
+}
+
+// Open 读取对象
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete 删除对象，同时删除变为空的上级目录
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for dir := filepath.Dir(path); dir != filepath.Clean(s.root); dir = filepath.Dir(dir) {
+		// 目录不为空时删除失败，停止向上清理
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// DownloadURL 本地存储不支持直接下载，始终返回空字符串
+func (s *LocalStore) DownloadURL(key string, ttl time.Duration) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,315 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3Algorithm AWS Signature Version 4 签名算法
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	// s3TimeFormat 签名使用的时间格式
+	s3TimeFormat = "20060102T150405Z"
+	// s3MaxPresignTTL 预签名地址的最长有效期
+	s3MaxPresignTTL = 7 * 24 * time.Hour
+	// s3MaxErrorBody 读取的错误响应内容的最大长度
+	s3MaxErrorBody = 1000
+)
+
+// S3Config S3兼容存储配置
+type S3Config struct {
+	Endpoint           string // 服务地址，如 https://s3.amazonaws.com 或 http://minio:9000
+	PublicEndpoint     string // 生成下载链接时使用的地址，为空时使用 Endpoint
+	Region             string
+	Bucket             string
+	AccessKeyID        string
+	SecretAccessKey    string
+	VirtualHostedStyle bool // 是否使用虚拟主机风格的地址，否则使用路径风格
+}
+
+// S3Store S3兼容制品存储，适用于 AWS S3、MinIO 等实现了 S3 API 的服务
+// 请求使用 AWS Signature Version 4 签名，下载地址为预签名的 GET 地址
+type S3Store struct {
+	config         S3Config
+	endpoint       *url.URL
+	publicEndpoint *url.URL
+	client         *http.Client
+	now            func() time.Time
+}
+
+// NewS3Store 创建S3兼容制品存储
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3存储的 bucket、access_key_id 和 secret_access_key 不能为空")
+	}
+	endpoint, err := parseS3Endpoint(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	publicEndpoint := endpoint
+	if config.PublicEndpoint != "" {
+		if publicEndpoint, err = parseS3Endpoint(config.PublicEndpoint); err != nil {
+			return nil, err
+		}
+	}
+	return &S3Store{
+		config:         config,
+		endpoint:       endpoint,
+		publicEndpoint: publicEndpoint,
+		client:         &http.Client{},
+		now:            time.Now,
+	}, nil
+}
+
+// parseS3Endpoint 解析S3服务地址，只允许 http 和 https 地址
+func parseS3Endpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("无效的S3服务地址: %q", endpoint)
+	}
+	return u, nil
+}
+
+// objectURL 返回对象在指定服务地址下的访问地址
+func (s *S3Store) objectURL(endpoint *url.URL, key string) *url.URL {
+	u := *endpoint
+	if s.config.VirtualHostedStyle {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = u.Path + "/" + key
+	} else {
+		u.Path = u.Path + "/" + s.config.Bucket + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+// Put 写入对象
+// 请求携带内容的SHA-256摘要，由存储服务校验上传的内容是否完整
+func (s *S3Store) Put(ctx context.Context, obj Object, r io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(s.endpoint, obj.Key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = obj.Size
+	if obj.ContentType != "" {
+		req.Header.Set("Content-Type", obj.ContentType)
+	}
+	payloadHash := obj.SHA256
+	if payloadHash == "" {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	resp, err := s.do(req, payloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		// 内容与签名中的摘要不一致时返回 XAmzContentSHA256Mismatch 错误
+		if body, _ := io.ReadAll(io.LimitReader(resp.Body, s3MaxErrorBody)); strings.Contains(string(body), "SHA256Mismatch") {
+			return ErrDigestMismatch
+		}
+		return fmt.Errorf("写入对象失败: 状态码 %d", resp.StatusCode)
+	}
+	return checkS3Response(resp, "写入对象失败")
+}
+
+// Open 读取对象
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(s.endpoint, key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if err := checkS3Response(resp, "读取对象失败"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除对象
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(s.endpoint, key).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkS3Response(resp, "删除对象失败")
+}
+
+// DownloadURL 生成预签名的下载地址，有效期不超过7天
+func (s *S3Store) DownloadURL(key string, ttl time.Duration) (string, error) {
+	if ttl > s3MaxPresignTTL {
+		ttl = s3MaxPresignTTL
+	}
+	u := s.objectURL(s.publicEndpoint, key)
+	now := s.now().UTC()
+	amzDate := now.Format(s3TimeFormat)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		s3EscapeQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, canonicalRequest))
+
+	u.RawQuery = s3EscapeQuery(query)
+	return u.String(), nil
+}
+
+// emptyPayloadHash 空请求体的SHA-256摘要
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// do 为请求添加签名并发送
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, s.now().UTC())
+	return s.client.Do(req)
+}
+
+// sign 使用请求头方式为请求签名，参与签名的请求头为 Host 和已设置的全部请求头
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3EscapeQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyID, s.scope(now), signedHeaders, s.signature(now, canonicalRequest)))
+}
+
+// scope 返回签名的凭证范围
+func (s *S3Store) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// signature 计算规范请求的签名
+func (s *S3Store) signature(now time.Time, canonicalRequest string) string {
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		s.scope(now),
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// checkS3Response 检查响应状态码，非2xx时返回包含状态码和错误信息的错误
+func checkS3Response(resp *http.Response, message string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, s3MaxErrorBody))
+	return fmt.Errorf("%s: 状态码 %d: %s", message, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// s3Escape 按 S3 签名规范对字符串做URI编码，只保留非保留字符
+func s3Escape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3EscapePath 对路径的每一段分别编码，保留分隔符 /
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3EscapeQuery 按参数名排序并编码查询参数
+func s3EscapeQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Escape(name)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -32,19 +32,29 @@ func GetAdminConfig() (*AdminConfig, error) {
 }
 
 // UpdateAdminPassword 更新管理员密码
+// 新密码的哈希会写回配置文件，写入成功后才更新内存中的配置，
+// 保证重启后密码依然生效
 func UpdateAdminPassword(newPassword string) error {
-	if adminConfig == nil {
-		return fmt.Errorf("管理员配置未初始化")
-	}
-
 	// 加密新密码
 	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("加密新密码失败: %w", err)
 	}
+	return SetAdminPasswordHash(string(newHashedPassword))
+}
 
-	// 更新密码
-	adminConfig.Password = string(newHashedPassword)
+// SetAdminPasswordHash 将管理员密码哈希写回配置文件并更新内存中的配置
+// 只修改配置文件中的 admin.password，保留其他配置项和注释；也用于在后续操作失败时恢复原密码哈希
+func SetAdminPasswordHash(hashedPassword string) error {
+	if adminConfig == nil || appConfig == nil || configFilePath == "" {
+		return fmt.Errorf("管理员配置未初始化")
+	}
+
+	if err := updateConfigFile(configFilePath, hashedPassword, "admin", "password"); err != nil {
+		return fmt.Errorf("保存管理员密码失败: %w", err)
+	}
 
+	appConfig.Admin.Password = hashedPassword
+	adminConfig.Password = hashedPassword
 	return nil
 }
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
@@ -14,15 +17,20 @@ import (
 // Config 应用配置结构
 type Config struct {
 	DB struct {
+		Driver   string `yaml:"driver"` // 数据库类型：mysql、postgres 或 sqlite
 		Host     string `yaml:"host"`
 		Port     int    `yaml:"port"`
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
 		Name     string `yaml:"name"`
+		SSLMode  string `yaml:"sslmode"` // PostgreSQL的SSL模式，仅 postgres 使用
+		Path     string `yaml:"path"`    // SQLite数据库文件路径，仅 sqlite 使用
 	} `yaml:"db"`
 	Server struct {
 		Port  int  `yaml:"port"`
 		Debug bool `yaml:"debug"` // 是否为调试模式
+		// 受信任的反向代理地址（IP或CIDR），只有来自这些地址的请求才会使用 X-Forwarded-For 中的客户端IP
+		TrustedProxies []string `yaml:"trusted_proxies"`
 	} `yaml:"server"`
 	JWT struct {
 		Secret      string `yaml:"secret"`
@@ -32,16 +40,97 @@ type Config struct {
 		Username string `yaml:"username"`
 		Password string `yaml:"password"` // 存储加密后的密码
 	} `yaml:"admin"`
+	Security struct {
+		// 轮换AKey/VKey后旧密钥的默认有效时长（小时），为0时旧密钥立即失效；
+		// 使用指针区分未配置和配置为0
+		KeyGraceHours *int `yaml:"key_grace_hours"`
+		// 签名请求的时间戳与服务端时间允许的最大偏差（秒）
+		RequestMaxSkewSeconds int `yaml:"request_max_skew_seconds"`
+		// 登录连续失败多少次后锁定账号
+		LoginMaxFailures int `yaml:"login_max_failures"`
+		// 首次锁定的时长（秒），此后每次失败翻倍
+		LoginLockoutSeconds int `yaml:"login_lockout_seconds"`
+		// 锁定时长上限（秒）
+		LoginMaxLockoutSeconds int `yaml:"login_max_lockout_seconds"`
+	} `yaml:"security"`
+	RateLimit struct {
+		CheckPerIP   RateLimitRule `yaml:"check_per_ip"`   // 校验接口按客户端IP限流
+		CheckPerAKey RateLimitRule `yaml:"check_per_akey"` // 校验接口按AKey限流
+		LoginPerIP   RateLimitRule `yaml:"login_per_ip"`   // 登录接口按客户端IP限流
+	} `yaml:"rate_limit"`
+	Telemetry struct {
+		BufferSize           int `yaml:"buffer_size"`            // 等待写入的遥测记录缓冲区大小，缓冲区满时丢弃新记录
+		BatchSize            int `yaml:"batch_size"`             // 每批写入的最大记录数
+		FlushIntervalSeconds int `yaml:"flush_interval_seconds"` // 写入间隔（秒）
+		RetentionDays        int `yaml:"retention_days"`         // 遥测记录保留天数，为负数时永久保留
+	} `yaml:"telemetry"`
+	Audit struct {
+		RetentionDays int `yaml:"retention_days"` // 审计日志保留天数，为负数时永久保留
+	} `yaml:"audit"`
+	Webhook struct {
+		MaxAttempts    int `yaml:"max_attempts"`    // 每次投递的最大尝试次数，超过后标记为失败
+		TimeoutSeconds int `yaml:"timeout_seconds"` // 单次请求的超时时间（秒）
+		RetentionDays  int `yaml:"retention_days"`  // 已完成的投递记录保留天数，为负数时永久保留
+	} `yaml:"webhook"`
+	Signing struct {
+		PrivateKey string `yaml:"private_key"` // 校验接口响应签名的Ed25519私钥种子（Base64），为空时自动生成
+	} `yaml:"signing"`
+	Storage struct {
+		Driver string `yaml:"driver"` // 制品存储类型：local 或 s3
+		// 服务的外部访问地址（如 https://update.example.com），用于生成本地存储制品的下载链接，为空时返回相对路径
+		BaseURL               string `yaml:"base_url"`
+		DownloadURLTTLSeconds int    `yaml:"download_url_ttl_seconds"` // 下载链接有效期（秒）
+		MaxUploadMB           int    `yaml:"max_upload_mb"`            // 单个制品的大小上限（MB）
+		Local                 struct {
+			Path string `yaml:"path"` // 制品文件的保存目录
+		} `yaml:"local"`
+		S3 struct {
+			Endpoint string `yaml:"endpoint"` // S3兼容服务的地址，如 https://s3.amazonaws.com 或 http://minio:9000
+			// 生成下载链接时使用的地址，服务端通过内网地址访问存储时填写客户端可访问的地址，为空时使用 endpoint
+			PublicEndpoint  string `yaml:"public_endpoint"`
+			Region          string `yaml:"region"`
+			Bucket          string `yaml:"bucket"`
+			AccessKeyID     string `yaml:"access_key_id"`
+			SecretAccessKey string `yaml:"secret_access_key"`
+			// 是否使用虚拟主机风格的地址（bucket.endpoint/key），默认使用路径风格（endpoint/bucket/key），MinIO 需使用路径风格
+			VirtualHostedStyle bool `yaml:"virtual_hosted_style"`
+		} `yaml:"s3"`
+	} `yaml:"storage"`
+}
+
+// RateLimitRule 令牌桶限流规则
+type RateLimitRule struct {
+	Rate  float64 `yaml:"rate"`  // 每秒补充的令牌数，为负数时不限流
+	Burst int     `yaml:"burst"` // 令牌桶容量，即允许的突发请求数
+}
+
+// Enabled 判断是否启用限流
+func (r RateLimitRule) Enabled() bool {
+	return r.Rate > 0 && r.Burst > 0
+}
+
+// mergeRateLimitRule 未配置的限流规则使用默认值
+func mergeRateLimitRule(rule *RateLimitRule, defaults RateLimitRule) {
+	if rule.Rate == 0 {
+		rule.Rate = defaults.Rate
+	}
+	if rule.Burst == 0 {
+		rule.Burst = defaults.Burst
+	}
 }
 
 // 全局变量存储应用配置
 var appConfig *Config
 
+// 配置文件路径，用于将运行时修改写回配置文件
+var configFilePath string
+
 // LoadConfig 加载配置文件并初始化全局配置
 // 当配置文件中缺少某些字段时，会自动使用默认值
 func LoadConfig(configPath string) (*Config, error) {
 	// 创建默认配置
 	defaultConfig := createDefaultConfig()
+	configFilePath = configPath
 
 	// 检查配置文件是否存在
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -71,9 +160,24 @@ func LoadConfig(configPath string) (*Config, error) {
 		appConfig = &config
 	}
 
+	if !IsValidDBDriver(appConfig.DB.Driver) {
+		return nil, fmt.Errorf("不支持的数据库类型: %s（可选值: mysql、postgres、sqlite）", appConfig.DB.Driver)
+	}
+	if graceHours := *appConfig.Security.KeyGraceHours; graceHours < 0 || graceHours > MaxKeyGraceHours {
+		return nil, fmt.Errorf("security.key_grace_hours 必须在0-%d之间: %d", MaxKeyGraceHours, graceHours)
+	}
+	if !IsValidStorageDriver(appConfig.Storage.Driver) {
+		return nil, fmt.Errorf("不支持的制品存储类型: %s（可选值: local、s3）", appConfig.Storage.Driver)
+	}
+
 	// 设置管理员配置
 	SetAdminConfigFromAppConfig(appConfig.Admin.Username, appConfig.Admin.Password)
 
+	// 初始化响应签名密钥
+	if err := initSigningKey(appConfig); err != nil {
+		return nil, err
+	}
+
 	return appConfig, nil
 }
 
@@ -81,11 +185,16 @@ func LoadConfig(configPath string) (*Config, error) {
 // 对于配置文件中缺失的字段，使用默认配置中的对应值
 func mergeConfigWithDefaults(config *Config, defaults *Config) {
 	// 合并数据库配置
+	// 未指定数据库类型时沿用 MySQL，保证旧配置文件无需修改即可继续使用
+	config.DB.Driver = strings.ToLower(strings.TrimSpace(config.DB.Driver))
+	if config.DB.Driver == "" {
+		config.DB.Driver = defaults.DB.Driver
+	}
 	if config.DB.Host == "" {
 		config.DB.Host = defaults.DB.Host
 	}
 	if config.DB.Port == 0 {
-		config.DB.Port = defaults.DB.Port
+		config.DB.Port = DefaultDBPort(config.DB.Driver)
 	}
 	if config.DB.User == "" {
 		config.DB.User = defaults.DB.User
@@ -96,6 +205,12 @@ func mergeConfigWithDefaults(config *Config, defaults *Config) {
 	if config.DB.Name == "" {
 		config.DB.Name = defaults.DB.Name
 	}
+	if config.DB.SSLMode == "" {
+		config.DB.SSLMode = defaults.DB.SSLMode
+	}
+	if config.DB.Path == "" {
+		config.DB.Path = defaults.DB.Path
+	}
 
 	// 合并服务器配置
 	if config.Server.Port == 0 {
@@ -118,6 +233,118 @@ func mergeConfigWithDefaults(config *Config, defaults *Config) {
 	if config.Admin.Password == "" || config.Admin.Password == "verkeyoss" {
 		config.Admin.Password = defaults.Admin.Password
 	}
+
+	// 合并安全配置
+	if config.Security.KeyGraceHours == nil {
+		config.Security.KeyGraceHours = defaults.Security.KeyGraceHours
+	}
+	if config.Security.RequestMaxSkewSeconds <= 0 {
+		config.Security.RequestMaxSkewSeconds = defaults.Security.RequestMaxSkewSeconds
+	}
+	if config.Security.LoginMaxFailures == 0 {
+		config.Security.LoginMaxFailures = defaults.Security.LoginMaxFailures
+	}
+	if config.Security.LoginLockoutSeconds <= 0 {
+		config.Security.LoginLockoutSeconds = defaults.Security.LoginLockoutSeconds
+	}
+	if config.Security.LoginMaxLockoutSeconds <= 0 {
+		config.Security.LoginMaxLockoutSeconds = defaults.Security.LoginMaxLockoutSeconds
+	}
+
+	// 合并限流配置
+	mergeRateLimitRule(&config.RateLimit.CheckPerIP, defaults.RateLimit.CheckPerIP)
+	mergeRateLimitRule(&config.RateLimit.CheckPerAKey, defaults.RateLimit.CheckPerAKey)
+	mergeRateLimitRule(&config.RateLimit.LoginPerIP, defaults.RateLimit.LoginPerIP)
+
+	// 合并遥测配置
+	if config.Telemetry.BufferSize <= 0 {
+		config.Telemetry.BufferSize = defaults.Telemetry.BufferSize
+	}
+	if config.Telemetry.BatchSize <= 0 {
+		config.Telemetry.BatchSize = defaults.Telemetry.BatchSize
+	}
+	if config.Telemetry.FlushIntervalSeconds <= 0 {
+		config.Telemetry.FlushIntervalSeconds = defaults.Telemetry.FlushIntervalSeconds
+	}
+	if config.Telemetry.RetentionDays == 0 {
+		config.Telemetry.RetentionDays = defaults.Telemetry.RetentionDays
+	}
+
+	// 合并审计日志配置
+	if config.Audit.RetentionDays == 0 {
+		config.Audit.RetentionDays = defaults.Audit.RetentionDays
+	}
+
+	// 合并Webhook配置
+	if config.Webhook.MaxAttempts <= 0 {
+		config.Webhook.MaxAttempts = defaults.Webhook.MaxAttempts
+	}
+	if config.Webhook.TimeoutSeconds <= 0 {
+		config.Webhook.TimeoutSeconds = defaults.Webhook.TimeoutSeconds
+	}
+	if config.Webhook.RetentionDays == 0 {
+		config.Webhook.RetentionDays = defaults.Webhook.RetentionDays
+	}
+
+	// 合并制品存储配置
+	config.Storage.Driver = strings.ToLower(strings.TrimSpace(config.Storage.Driver))
+	if config.Storage.Driver == "" {
+		config.Storage.Driver = defaults.Storage.Driver
+	}
+	config.Storage.BaseURL = strings.TrimRight(config.Storage.BaseURL, "/")
+	if config.Storage.DownloadURLTTLSeconds <= 0 {
+		config.Storage.DownloadURLTTLSeconds = defaults.Storage.DownloadURLTTLSeconds
+	}
+	if config.Storage.MaxUploadMB <= 0 {
+		config.Storage.MaxUploadMB = defaults.Storage.MaxUploadMB
+	}
+	if config.Storage.Local.Path == "" {
+		config.Storage.Local.Path = defaults.Storage.Local.Path
+	}
+	if config.Storage.S3.Region == "" {
+		config.Storage.S3.Region = defaults.Storage.S3.Region
+	}
+}
+
+// 支持的数据库类型
+const (
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
+// IsValidDBDriver 检查数据库类型是否受支持
+func IsValidDBDriver(driver string) bool {
+	switch driver {
+	case DBDriverMySQL, DBDriverPostgres, DBDriverSQLite:
+		return true
+	}
+	return false
+}
+
+// DefaultDBPort 返回数据库类型对应的默认端口，SQLite 不使用端口
+func DefaultDBPort(driver string) int {
+	switch driver {
+	case DBDriverPostgres:
+		return 5432
+	case DBDriverSQLite:
+		return 0
+	}
+	return 3306
+}
+
+// MaxKeyGraceHours 旧密钥宽限期的上限（30天）
+const MaxKeyGraceHours = 720
+
+// 支持的制品存储类型
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+)
+
+// IsValidStorageDriver 检查制品存储类型是否受支持
+func IsValidStorageDriver(driver string) bool {
+	return driver == StorageDriverLocal || driver == StorageDriverS3
 }
 
 // GetAppConfig 获取应用配置
@@ -140,35 +367,156 @@ func createDefaultConfig() *Config {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(defaultPassword), bcrypt.DefaultCost)
 
 	config := &Config{}
+	config.DB.Driver = DBDriverMySQL
 	config.DB.Host = "localhost"
-	config.DB.Port = 3306
+	config.DB.Port = DefaultDBPort(DBDriverMySQL)
 	config.DB.User = "verkeyoss"
 	config.DB.Password = "verkeyoss"
 	config.DB.Name = "verkeyoss"
+	config.DB.SSLMode = "disable"
+	config.DB.Path = "verkeyoss.db"
 	config.Server.Port = 8913
 	config.Server.Debug = false // 默认非调试模式
 	config.JWT.Secret = jwtSecret
 	config.JWT.ExpireHours = 24
 	config.Admin.Username = defaultUsername
 	config.Admin.Password = string(hashedPassword)
+	keyGraceHours := 72
+	config.Security.KeyGraceHours = &keyGraceHours
+	config.Security.RequestMaxSkewSeconds = 300
+	config.Security.LoginMaxFailures = 5
+	config.Security.LoginLockoutSeconds = 60
+	config.Security.LoginMaxLockoutSeconds = 3600
+	config.RateLimit.CheckPerIP = RateLimitRule{Rate: 5, Burst: 20}
+	config.RateLimit.CheckPerAKey = RateLimitRule{Rate: 200, Burst: 400}
+	config.RateLimit.LoginPerIP = RateLimitRule{Rate: 0.2, Burst: 10}
+	config.Telemetry.BufferSize = 10000
+	config.Telemetry.BatchSize = 500
+	config.Telemetry.FlushIntervalSeconds = 5
+	config.Telemetry.RetentionDays = 90
+	config.Audit.RetentionDays = 365
+	config.Webhook.MaxAttempts = 8
+	config.Webhook.TimeoutSeconds = 10
+	config.Webhook.RetentionDays = 30
+	config.Signing.PrivateKey, _ = generateSigningKey()
+	config.Storage.Driver = StorageDriverLocal
+	config.Storage.DownloadURLTTLSeconds = 3600
+	config.Storage.MaxUploadMB = 1024
+	config.Storage.Local.Path = "artifacts"
+	config.Storage.S3.Region = "us-east-1"
 
 	return config
 }
 
 // 保存配置到文件
 func saveConfig(config *Config, filePath string) error {
+	if err := writeConfigFile(config, filePath); err != nil {
+		return err
+	}
+
+	log.Printf("默认配置已创建，请在首次登录后修改默认密码")
+	log.Printf("用户名: %s", config.Admin.Username)
+	log.Printf("请使用默认密码登录后立即修改密码")
+
+	return nil
+}
+
+// updateConfigFile 修改配置文件中指定路径的值，路径不存在时自动创建
+// 只修改对应的节点，保留文件中的其他配置项和注释，不会写入内存中合并的默认值
+func updateConfigFile(filePath, value string, path ...string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	node := doc.Content[0]
+	for i, key := range path {
+		// 空的配置节（如只写了 "signing:"）解析为空值，按空映射处理
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			node.Kind, node.Tag, node.Value = yaml.MappingNode, "!!map", ""
+		}
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("配置项 %s 不是映射", strings.Join(path[:i], "."))
+		}
+
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				child = node.Content[j+1]
+				break
+			}
+		}
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		node = child
+	}
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("配置项 %s 不是标量", strings.Join(path, "."))
+	}
+	node.Tag, node.Value, node.Style = "!!str", value, 0
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	return writeFileAtomic(filePath, buf.Bytes())
+}
+
+// writeConfigFile 将完整的配置写入配置文件，只用于创建默认配置文件
+func writeConfigFile(config *Config, filePath string) error {
 	content, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("序列化配置失败: %w", err)
 	}
+	return writeFileAtomic(filePath, content)
+}
+
+// writeFileAtomic 以原子方式写入配置文件
+// 先写入同目录下的临时文件并同步到磁盘，再通过重命名替换原文件，
+// 避免写入过程中断导致配置文件损坏
+func writeFileAtomic(filePath string, content []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时配置文件失败: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	// 出错时清理临时文件，重命名成功后该操作不会产生影响
+	defer os.Remove(tmpPath)
 
-	if err := os.WriteFile(filePath, content, 0600); err != nil {
+	if err := tmpFile.Chmod(0600); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("设置配置文件权限失败: %w", err)
+	}
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
 		return fmt.Errorf("写入配置文件失败: %w", err)
 	}
 
-	log.Printf("默认配置已创建，请在首次登录后修改默认密码")
-	log.Printf("用户名: %s", config.Admin.Username)
-	log.Printf("请使用默认密码登录后立即修改密码")
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("替换配置文件失败: %w", err)
+	}
 
 	return nil
 }
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// 全局变量存储响应签名私钥
+var signingKey ed25519.PrivateKey
+
+// GetSigningKey 获取校验接口响应签名使用的Ed25519私钥
+func GetSigningKey() (ed25519.PrivateKey, error) {
+	if signingKey == nil {
+		return nil, fmt.Errorf("签名密钥未初始化")
+	}
+	return signingKey, nil
+}
+
+// generateSigningKey 生成新的Ed25519私钥，返回Base64编码的32字节种子
+func generateSigningKey() (string, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(privateKey.Seed()), nil
+}
+
+// parseSigningKey 解析Base64编码的Ed25519私钥种子
+func parseSigningKey(encoded string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("签名密钥格式无效，应为Base64编码的%d字节Ed25519私钥种子", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// initSigningKey 从配置中加载响应签名私钥
+// 配置文件中没有签名密钥时不做处理，由 EnsureSigningKey 在启动服务时生成，
+// 保证只读的命令行子命令（如 config check、migrate）不会修改配置文件
+func initSigningKey(config *Config) error {
+	signingKey = nil
+	if config.Signing.PrivateKey == "" {
+		return nil
+	}
+
+	privateKey, err := parseSigningKey(config.Signing.PrivateKey)
+	if err != nil {
+		return err
+	}
+	signingKey = privateKey
+	return nil
+}
+
+// HasSigningKey 判断配置文件中是否已有响应签名私钥
+func HasSigningKey() bool {
+	return signingKey != nil
+}
+
+// EnsureSigningKey 确保响应签名私钥已初始化
+// 配置文件中没有签名密钥时自动生成，并只将 signing.private_key 写回配置文件，保证重启后公钥不变
+func EnsureSigningKey() error {
+	if signingKey != nil {
+		return nil
+	}
+	if appConfig == nil || configFilePath == "" {
+		return fmt.Errorf("应用配置未初始化")
+	}
+
+	encoded, err := generateSigningKey()
+	if err != nil {
+		return err
+	}
+	if err := updateConfigFile(configFilePath, encoded, "signing", "private_key"); err != nil {
+		return fmt.Errorf("保存签名密钥失败: %w", err)
+	}
+	appConfig.Signing.PrivateKey = encoded
+	return initSigningKey(appConfig)
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"verkeyoss/internal/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Open 根据配置中的数据库类型建立数据库连接并配置连接池
+func Open(appConfig *config.Config) (*gorm.DB, error) {
+	dialector, err := newDialector(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// 配置GORM参数
+		NowFunc: time.Now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	// 配置连接池
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取底层数据库连接失败: %w", err)
+	}
+
+	if appConfig.DB.Driver == config.DBDriverSQLite {
+		// SQLite 同一时间只允许一个写入者，使用单连接避免 "database is locked" 错误
+		sqlDB.SetMaxOpenConns(1)
+		return db, nil
+	}
+
+	// 设置最大开放连接数
+	sqlDB.SetMaxOpenConns(100)
+	// 设置最大空闲连接数
+	sqlDB.SetMaxIdleConns(10)
+	// 设置连接的最大可复用时间
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	return db, nil
+}
+
+// Describe 返回便于日志输出的数据库连接描述，不包含密码
+func Describe(appConfig *config.Config) string {
+	if appConfig.DB.Driver == config.DBDriverSQLite {
+		return fmt.Sprintf("sqlite://%s", appConfig.DB.Path)
+	}
+	return fmt.Sprintf("%s://%s:%d/%s", appConfig.DB.Driver, appConfig.DB.Host, appConfig.DB.Port, appConfig.DB.Name)
+}
+
+// newDialector 根据数据库类型构造对应的GORM驱动
+func newDialector(appConfig *config.Config) (gorm.Dialector, error) {
+	db := appConfig.DB
+	switch db.Driver {
+	case config.DBDriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			db.User, db.Password, db.Host, db.Port, db.Name)
+		return mysql.Open(dsn), nil
+	case config.DBDriverPostgres:
+		dsn := (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(db.User, db.Password),
+			Host:     fmt.Sprintf("%s:%d", db.Host, db.Port),
+			Path:     "/" + db.Name,
+			RawQuery: url.Values{"sslmode": {db.SSLMode}}.Encode(),
+		}).String()
+		return postgres.Open(dsn), nil
+	case config.DBDriverSQLite:
+		// 启用外键约束以支持级联更新和删除，WAL 模式下读操作不会阻塞写操作
+		dsn := db.Path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("不支持的数据库类型: %s", db.Driver)
+}
@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // 定义错误类型
@@ -19,6 +20,10 @@ const (
 	ErrTypeInternal
 	// ErrTypeConflict 资源冲突错误
 	ErrTypeConflict
+	// ErrTypeForbidden 权限不足错误
+	ErrTypeForbidden
+	// ErrTypeTooManyRequests 请求过于频繁错误
+	ErrTypeTooManyRequests
 )
 
 // AppError 应用错误结构
@@ -27,6 +32,8 @@ type AppError struct {
 	Code    int
 	Message string
 	Err     error
+	// 客户端需要等待的时长，仅请求过于频繁错误使用
+	RetryAfter time.Duration
 }
 
 // Error 实现error接口
@@ -51,6 +58,19 @@ var (
 	ErrTokenInvalid    = NewUnauthorizedError("令牌无效或已过期")
 	ErrAppExists       = NewConflictError("应用已存在")
 	ErrVersionExists   = NewConflictError("版本已存在")
+	ErrForbidden       = NewForbiddenError("权限不足")
+	ErrUserNotFound    = NewNotFoundError("用户不存在")
+	ErrUserExists      = NewConflictError("用户名已存在")
+	ErrLastOwner       = NewConflictError("至少需要保留一个启用状态的所有者")
+	ErrTokenNotFound   = NewNotFoundError("API令牌不存在")
+
+	ErrForcedRangeNotFound  = NewNotFoundError("强制更新版本范围不存在")
+	ErrLicenseNotFound      = NewNotFoundError("许可证不存在")
+	ErrActivationNotFound   = NewNotFoundError("激活记录不存在")
+	ErrDeviceNotFound       = NewNotFoundError("设备不存在")
+	ErrWebhookNotFound      = NewNotFoundError("Webhook不存在")
+	ErrAnnouncementNotFound = NewNotFoundError("公告不存在")
+	ErrArtifactNotFound     = NewNotFoundError("制品不存在")
 )
 
 // NewValidationError 创建参数验证错误
@@ -99,6 +119,25 @@ func NewConflictError(message string) *AppError {
 	}
 }
 
+// NewForbiddenError 创建权限不足错误
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Type:    ErrTypeForbidden,
+		Code:    403,
+		Message: message,
+	}
+}
+
+// NewTooManyRequestsError 创建请求过于频繁错误
+func NewTooManyRequestsError(message string, retryAfter time.Duration) *AppError {
+	return &AppError{
+		Type:       ErrTypeTooManyRequests,
+		Code:       429,
+		Message:    message,
+		RetryAfter: retryAfter,
+	}
+}
+
 // WrapError 包装现有错误
 func WrapError(err error, message string) *AppError {
 	return &AppError{
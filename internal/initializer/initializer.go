@@ -4,27 +4,29 @@ import (
 	"log"
 	"time"
 
+	"verkeyoss/internal/config"
+	"verkeyoss/internal/migration"
 	"verkeyoss/internal/model"
 
 	"gorm.io/gorm"
 )
 
-// Initialize 执行程序初始化，包括表创建和初始数据插入
+// Initialize 执行程序初始化，包括数据库迁移和初始数据插入
 // 如果初始化过程中发生错误，程序将退出
 func Initialize(db *gorm.DB) {
 	log.Println("开始执行程序初始化...")
 
-	// 暂时禁用外键约束，以避免表创建顺序导致的问题
-	db.Exec("SET FOREIGN_KEY_CHECKS = 0;")
-
-	// 创建所有表
-	createTableIfNotExists(db, &model.App{}, "应用")
-	createTableIfNotExists(db, &model.Version{}, "版本")
-	createTableIfNotExists(db, &model.Announcement{}, "公告")
-
-	// 重新启用外键约束
-	db.Exec("SET FOREIGN_KEY_CHECKS = 1;")
+	// 执行数据库迁移，创建或升级所有表
+	count, err := migration.NewRunner(db, false, nil).Up(0)
+	if err != nil {
+		log.Fatalf("数据库迁移失败: %v", err)
+	}
+	if count > 0 {
+		log.Printf("已执行 %d 个数据库迁移\n", count)
+	}
 
+	// 初始化所有者账号
+	initDefaultOwner(db)
 	// 初始化默认应用和版本
 	initDefaultSoftwareAndVersion(db)
 	// 初始化测试公告
@@ -33,23 +35,41 @@ func Initialize(db *gorm.DB) {
 	log.Println("程序初始化完成！")
 }
 
-// createTableIfNotExists 创建表并返回是否是新创建的表
-// 参数 db 是数据库连接
-// 参数 model 是要创建表的模型指针
-// 参数 tableName 是表的中文名称
-// 返回值表示表是否是新创建的
-func createTableIfNotExists(db *gorm.DB, model interface{}, tableName string) bool {
-	// 检查表是否存在
-	if db.Migrator().HasTable(model) {
-		return false
+// 初始化所有者账号
+// 用户表为空时，使用配置文件中的管理员账号创建第一个所有者
+func initDefaultOwner(db *gorm.DB) {
+	var count int64
+	db.Model(&model.User{}).Count(&count)
+	if count > 0 {
+		// 已存在用户，跳过初始化
+		return
 	}
 
-	// 创建表
-	if err := db.AutoMigrate(model); err != nil {
-		log.Fatalf("创建%s表失败: %v", tableName, err)
+	adminConfig, err := config.GetAdminConfig()
+	if err != nil {
+		log.Fatalf("创建所有者账号失败: %v", err)
 	}
-	log.Printf("%s表创建成功\n", tableName)
-	return true
+
+	// 兼容配置文件中直接填写明文密码的情况
+	password := adminConfig.Password
+	if !model.IsBcryptHash(password) {
+		password, err = model.HashPassword(password)
+		if err != nil {
+			log.Fatalf("创建所有者账号失败: %v", err)
+		}
+	}
+
+	owner := model.User{
+		Username: adminConfig.Username,
+		Password: password,
+		Role:     model.RoleOwner,
+		IsActive: true,
+	}
+	if err := db.Create(&owner).Error; err != nil {
+		log.Fatalf("创建所有者账号失败: %v", err)
+	}
+
+	log.Printf("所有者账号 %s 创建成功!", owner.Username)
 }
 
 // 初始化默认应用和版本
@@ -67,7 +87,7 @@ func initDefaultSoftwareAndVersion(db *gorm.DB) {
 
 	// 创建默认应用
 	defaultApp := model.App{
-		UserID:      1, // 管理员ID固定为1
+		UserID:      1, // 归属于初始化时创建的第一个所有者
 		AKey:        "test",
 		Name:        "测试应用",
 		Description: "这是一个用于测试的默认应用",
@@ -93,7 +113,6 @@ func initDefaultSoftwareAndVersion(db *gorm.DB) {
 			AKey:        "test",
 			Version:     "1.0.0",
 			Description: "这是测试应用的初始版本",
-			IsLatest:    true,
 		}
 
 		// 保存版本到数据库
@@ -0,0 +1,251 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次编号的表结构迁移
+// Up 和 Down 中的每个操作都应可重复执行，迁移中途失败时修复问题后重新执行即可
+type Migration struct {
+	Version uint   // 迁移编号，按从小到大的顺序执行
+	Name    string // 迁移名称
+	Up      func(s *Schema) error
+	Down    func(s *Schema) error
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:100;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 迁移记录表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time // 执行时间，为空表示尚未执行
+}
+
+// Runner 迁移执行器
+type Runner struct {
+	db         *gorm.DB
+	dryRun     bool
+	out        io.Writer
+	migrations []Migration
+}
+
+// NewRunner 创建迁移执行器
+// dryRun 为 true 时只向 out 输出将要执行的SQL，不修改数据库
+func NewRunner(db *gorm.DB, dryRun bool, out io.Writer) *Runner {
+	return &Runner{
+		db:         db,
+		dryRun:     dryRun,
+		out:        out,
+		migrations: migrations,
+	}
+}
+
+// Latest 返回最新的迁移编号
+func (r *Runner) Latest() uint {
+	if len(r.migrations) == 0 {
+		return 0
+	}
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Up 按顺序执行尚未执行的迁移，target 为 0 时执行到最新版本
+// 返回本次执行的迁移数量
+func (r *Runner) Up(target uint) (int, error) {
+	if target > r.Latest() {
+		return 0, fmt.Errorf("迁移版本 %d 不存在，最新版本为 %d", target, r.Latest())
+	}
+	if target == 0 {
+		target = r.Latest()
+	}
+
+	schema := r.newSchema()
+	if err := r.ensureTable(schema); err != nil {
+		return 0, err
+	}
+	applied, err := r.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range r.migrations {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		r.begin(m, "up")
+		if err := m.Up(schema); err != nil {
+			return count, fmt.Errorf("执行迁移 %s 失败: %w", m.label(), err)
+		}
+		record := SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		if err := schema.exec.Create(&record).Error; err != nil {
+			return count, fmt.Errorf("记录迁移 %s 失败: %w", m.label(), err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down 按倒序回滚最近执行的 steps 个迁移
+// 返回本次回滚的迁移数量
+func (r *Runner) Down(steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("回滚步数必须大于0")
+	}
+
+	schema := r.newSchema()
+	applied, err := r.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(r.migrations) - 1; i >= 0 && count < steps; i-- {
+		m := r.migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		r.begin(m, "down")
+		if err := m.Down(schema); err != nil {
+			return count, fmt.Errorf("回滚迁移 %s 失败: %w", m.label(), err)
+		}
+		if err := schema.exec.Delete(&SchemaMigration{}, m.Version).Error; err != nil {
+			return count, fmt.Errorf("删除迁移记录 %s 失败: %w", m.label(), err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Status 返回所有迁移的执行状态
+func (r *Runner) Status() ([]Status, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ensureTable 创建迁移记录表
+func (r *Runner) ensureTable(schema *Schema) error {
+	if err := schema.CreateTable(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	return nil
+}
+
+// applied 查询已执行的迁移，迁移记录表不存在时视为尚未执行任何迁移
+func (r *Runner) applied() (map[uint]SchemaMigration, error) {
+	result := make(map[uint]SchemaMigration)
+	if !r.db.Migrator().HasTable(&SchemaMigration{}) {
+		return result, nil
+	}
+
+	var records []SchemaMigration
+	if err := r.db.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败: %w", err)
+	}
+	for _, record := range records {
+		result[record.Version] = record
+	}
+	return result, nil
+}
+
+// begin 输出迁移开始执行的提示
+func (r *Runner) begin(m Migration, direction string) {
+	if r.dryRun {
+		fmt.Fprintf(r.out, "-- %s (%s)\n", m.label(), direction)
+		return
+	}
+	log.Printf("执行数据库迁移 %s (%s)", m.label(), direction)
+}
+
+// newSchema 创建迁移使用的表结构操作对象
+// 预演模式下变更语句由 dryRunPool 截获并输出，查询语句仍在数据库上执行以判断当前表结构
+func (r *Runner) newSchema() *Schema {
+	exec := r.db
+	if r.dryRun {
+		// 指定 Context 使会话复制一份独立的 Statement，替换连接池时不影响原连接
+		exec = r.db.Session(&gorm.Session{NewDB: true, Context: context.Background()})
+		exec.Statement.ConnPool = &dryRunPool{
+			ConnPool:  r.db.Statement.ConnPool,
+			dialector: r.db.Dialector,
+			out:       r.out,
+		}
+	}
+	return &Schema{db: r.db, exec: exec}
+}
+
+// label 返回迁移的显示名称
+func (m Migration) label() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// dryRunPool 预演模式使用的连接池
+// 写操作只输出SQL而不执行，读操作透传到实际的数据库连接
+type dryRunPool struct {
+	gorm.ConnPool
+	dialector gorm.Dialector
+	out       io.Writer
+}
+
+// ExecContext 输出SQL而不执行
+func (p *dryRunPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	fmt.Fprintf(p.out, "%s;\n", p.dialector.Explain(query, args...))
+	return driver.RowsAffected(0), nil
+}
+
+// BeginTx 预演模式下不开启实际事务
+func (p *dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryRunTx{dryRunPool: p}, nil
+}
+
+// dryRunTx 预演模式下的事务，提交和回滚均不执行任何操作
+type dryRunTx struct {
+	*dryRunPool
+}
+
+// Commit 预演模式下无需提交
+func (tx *dryRunTx) Commit() error {
+	return nil
+}
+
+// Rollback 预演模式下无需回滚
+func (tx *dryRunTx) Rollback() error {
+	return nil
+}
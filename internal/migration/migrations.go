@@ -0,0 +1,490 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations 所有迁移，按编号从小到大排列
+// 迁移中使用的模型是编写迁移时表结构的快照，后续修改 model 包中的模型不会影响已有迁移，
+// 模型新增字段或表时，应在末尾追加新的迁移
+var migrations = []Migration{
+	{Version: 1, Name: "create_core_tables", Up: upCoreTables, Down: downCoreTables},
+	{Version: 2, Name: "create_users", Up: upUsers, Down: downUsers},
+	{Version: 3, Name: "create_api_tokens", Up: upAPITokens, Down: downAPITokens},
+	{Version: 4, Name: "add_version_channel", Up: upVersionChannel, Down: downVersionChannel},
+	{Version: 5, Name: "add_version_rollout", Up: upVersionRollout, Down: downVersionRollout},
+	{Version: 6, Name: "add_forced_update", Up: upForcedUpdate, Down: downForcedUpdate},
+	{Version: 7, Name: "add_version_revocation", Up: upVersionRevocation, Down: downVersionRevocation},
+	{Version: 8, Name: "add_key_rotation", Up: upKeyRotation, Down: downKeyRotation},
+	{Version: 9, Name: "create_licenses", Up: upLicenses, Down: downLicenses},
+	{Version: 10, Name: "create_devices", Up: upDevices, Down: downDevices},
+	{Version: 11, Name: "add_request_signing", Up: upRequestSigning, Down: downRequestSigning},
+	{Version: 12, Name: "create_check_events", Up: upCheckEvents, Down: downCheckEvents},
+	{Version: 13, Name: "create_audit_entries", Up: upAuditEntries, Down: downAuditEntries},
+	{Version: 14, Name: "create_webhooks", Up: upWebhooks, Down: downWebhooks},
+	{Version: 15, Name: "add_announcement_targeting", Up: upAnnouncementTargeting, Down: downAnnouncementTargeting},
+	{Version: 16, Name: "create_artifacts", Up: upArtifacts, Down: downArtifacts},
+	{Version: 17, Name: "clear_legacy_latest_pins", Up: upClearLatestPins, Down: downClearLatestPins},
+}
+
+// 0001 应用、版本和公告表
+
+type appV1 struct {
+	gorm.Model
+	UserID      uint        `gorm:"not null;index"`
+	AKey        string      `gorm:"size:100;not null;uniqueIndex"`
+	Name        string      `gorm:"size:100;not null"`
+	Description string      `gorm:"size:500"`
+	CreatedAt   time.Time   `gorm:"autoCreateTime"`
+	IsPaid      bool        `gorm:"not null;default:false"`
+	Versions    []versionV1 `gorm:"foreignKey:AKey;references:AKey;constraint:OnDelete:CASCADE"`
+}
+
+func (appV1) TableName() string { return "apps" }
+
+type versionV1 struct {
+	gorm.Model
+	VKey           string    `gorm:"size:100;not null;uniqueIndex"`
+	AKey           string    `gorm:"size:100;not null;index"`
+	Version        string    `gorm:"size:50;not null"`
+	Description    string    `gorm:"size:500"`
+	IsLatest       bool      `gorm:"not null;default:false"`
+	IsForcedUpdate bool      `gorm:"not null;default:false"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (versionV1) TableName() string { return "versions" }
+
+type announcementV1 struct {
+	gorm.Model
+	Title       string `gorm:"size:100;not null"`
+	Content     string `gorm:"type:text;not null"`
+	IsActive    bool   `gorm:"not null;default:true"`
+	PublishDate time.Time
+	URL         string `gorm:"size:500"`
+}
+
+func (announcementV1) TableName() string { return "announcements" }
+
+// 版本表的外键由应用模型中的关联定义，创建版本表时一并创建
+func upCoreTables(s *Schema) error {
+	return s.CreateTable(&appV1{}, &versionV1{}, &announcementV1{})
+}
+
+func downCoreTables(s *Schema) error {
+	return s.DropTable(&announcementV1{}, &versionV1{}, &appV1{})
+}
+
+// 0002 管理用户表
+
+type userV2 struct {
+	gorm.Model
+	Username    string `gorm:"size:50;not null;uniqueIndex"`
+	Password    string `gorm:"size:100;not null"`
+	Role        string `gorm:"size:20;not null;default:viewer"`
+	IsActive    bool   `gorm:"not null;default:true"`
+	LastLoginAt *time.Time
+}
+
+func (userV2) TableName() string { return "users" }
+
+func upUsers(s *Schema) error {
+	return s.CreateTable(&userV2{})
+}
+
+func downUsers(s *Schema) error {
+	return s.DropTable(&userV2{})
+}
+
+// 0003 API令牌表
+
+type apiTokenV3 struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"size:100;not null"`
+	TokenHash  string `gorm:"size:64;not null;uniqueIndex"`
+	Prefix     string `gorm:"size:20;not null"`
+	AKeys      string `gorm:"type:text;not null"`
+	Scopes     string `gorm:"size:500;not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time `gorm:"index"`
+}
+
+func (apiTokenV3) TableName() string { return "api_tokens" }
+
+func upAPITokens(s *Schema) error {
+	return s.CreateTable(&apiTokenV3{})
+}
+
+func downAPITokens(s *Schema) error {
+	return s.DropTable(&apiTokenV3{})
+}
+
+// 0004 版本发布渠道
+
+type versionV4 struct {
+	Channel string `gorm:"size:20;not null;default:stable;index"`
+}
+
+func (versionV4) TableName() string { return "versions" }
+
+func upVersionChannel(s *Schema) error {
+	if err := s.AddColumn(&versionV4{}, "Channel"); err != nil {
+		return err
+	}
+	return s.CreateIndex(&versionV4{}, "Channel")
+}
+
+func downVersionChannel(s *Schema) error {
+	if err := s.DropIndex(&versionV4{}, "Channel"); err != nil {
+		return err
+	}
+	return s.DropColumn(&versionV4{}, "Channel")
+}
+
+// 0005 版本灰度发布
+
+type versionV5 struct {
+	RolloutPercent int    `gorm:"not null;default:100"`
+	RolloutStatus  string `gorm:"size:20;not null;default:active"`
+}
+
+func (versionV5) TableName() string { return "versions" }
+
+func upVersionRollout(s *Schema) error {
+	return s.AddColumn(&versionV5{}, "RolloutPercent", "RolloutStatus")
+}
+
+func downVersionRollout(s *Schema) error {
+	return s.DropColumn(&versionV5{}, "RolloutPercent", "RolloutStatus")
+}
+
+// 0006 最低支持版本和强制更新版本范围
+
+type appV6 struct {
+	MinSupportedVersion string `gorm:"size:50"`
+}
+
+func (appV6) TableName() string { return "apps" }
+
+type forcedUpdateRangeV6 struct {
+	gorm.Model
+	AKey       string `gorm:"size:100;not null;index"`
+	MinVersion string `gorm:"size:50"`
+	MaxVersion string `gorm:"size:50"`
+	Reason     string `gorm:"size:500"`
+}
+
+func (forcedUpdateRangeV6) TableName() string { return "forced_update_ranges" }
+
+func upForcedUpdate(s *Schema) error {
+	if err := s.AddColumn(&appV6{}, "MinSupportedVersion"); err != nil {
+		return err
+	}
+	return s.CreateTable(&forcedUpdateRangeV6{})
+}
+
+func downForcedUpdate(s *Schema) error {
+	if err := s.DropTable(&forcedUpdateRangeV6{}); err != nil {
+		return err
+	}
+	return s.DropColumn(&appV6{}, "MinSupportedVersion")
+}
+
+// 0007 版本撤回
+
+type versionV7 struct {
+	RevokedAt    *time.Time `gorm:"index"`
+	RevokeReason string     `gorm:"size:500"`
+}
+
+func (versionV7) TableName() string { return "versions" }
+
+func upVersionRevocation(s *Schema) error {
+	if err := s.AddColumn(&versionV7{}, "RevokedAt", "RevokeReason"); err != nil {
+		return err
+	}
+	return s.CreateIndex(&versionV7{}, "RevokedAt")
+}
+
+func downVersionRevocation(s *Schema) error {
+	if err := s.DropIndex(&versionV7{}, "RevokedAt"); err != nil {
+		return err
+	}
+	return s.DropColumn(&versionV7{}, "RevokedAt", "RevokeReason")
+}
+
+// 0008 密钥轮换
+// 轮换AKey时需要同步更新版本表中的AKey，因此版本表外键增加 ON UPDATE CASCADE
+
+type appV8 struct {
+	AKey     string          `gorm:"size:100;not null;uniqueIndex"`
+	Versions []versionAKeyV8 `gorm:"foreignKey:AKey;references:AKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (appV8) TableName() string { return "apps" }
+
+type versionAKeyV8 struct {
+	AKey string `gorm:"size:100;not null;index"`
+}
+
+func (versionAKeyV8) TableName() string { return "versions" }
+
+type retiredKeyV8 struct {
+	gorm.Model
+	KeyType   string    `gorm:"size:10;not null"`
+	OldKey    string    `gorm:"size:100;not null;uniqueIndex"`
+	NewKey    string    `gorm:"size:100;not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (retiredKeyV8) TableName() string { return "retired_keys" }
+
+func upKeyRotation(s *Schema) error {
+	if err := s.CreateTable(&retiredKeyV8{}); err != nil {
+		return err
+	}
+	return s.ReplaceConstraint(&appV8{}, "Versions")
+}
+
+func downKeyRotation(s *Schema) error {
+	if err := s.ReplaceConstraint(&appV1{}, "Versions"); err != nil {
+		return err
+	}
+	return s.DropTable(&retiredKeyV8{})
+}
+
+// 0009 许可证和设备激活记录
+
+type licenseV9 struct {
+	gorm.Model
+	AKey       string `gorm:"size:100;not null;index"`
+	LicenseKey string `gorm:"size:50;not null;uniqueIndex"`
+	Seats      int    `gorm:"not null;default:1"`
+	Note       string `gorm:"size:200"`
+	UserID     uint   `gorm:"not null;index"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+}
+
+func (licenseV9) TableName() string { return "licenses" }
+
+type licenseActivationV9 struct {
+	gorm.Model
+	LicenseID  uint   `gorm:"not null;uniqueIndex:idx_license_device"`
+	DeviceID   string `gorm:"size:128;not null;uniqueIndex:idx_license_device"`
+	LastSeenAt time.Time
+}
+
+func (licenseActivationV9) TableName() string { return "license_activations" }
+
+func upLicenses(s *Schema) error {
+	return s.CreateTable(&licenseV9{}, &licenseActivationV9{})
+}
+
+func downLicenses(s *Schema) error {
+	return s.DropTable(&licenseActivationV9{}, &licenseV9{})
+}
+
+// 0010 设备表
+
+type deviceV10 struct {
+	gorm.Model
+	AKey        string `gorm:"size:100;not null;uniqueIndex:idx_app_device"`
+	DeviceID    string `gorm:"size:128;not null;uniqueIndex:idx_app_device"`
+	VKey        string `gorm:"size:100;index"`
+	Version     string `gorm:"size:50"`
+	IP          string `gorm:"size:45"`
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time `gorm:"index"`
+	BlockedAt   *time.Time
+	BlockReason string `gorm:"size:500"`
+}
+
+func (deviceV10) TableName() string { return "devices" }
+
+func upDevices(s *Schema) error {
+	return s.CreateTable(&deviceV10{})
+}
+
+func downDevices(s *Schema) error {
+	return s.DropTable(&deviceV10{})
+}
+
+// 0011 校验请求签名
+
+type appV11 struct {
+	RequestSecret         string `gorm:"size:100"`
+	RequireSignedRequests bool   `gorm:"not null;default:false"`
+}
+
+func (appV11) TableName() string { return "apps" }
+
+func upRequestSigning(s *Schema) error {
+	return s.AddColumn(&appV11{}, "RequestSecret", "RequireSignedRequests")
+}
+
+func downRequestSigning(s *Schema) error {
+	return s.DropColumn(&appV11{}, "RequestSecret", "RequireSignedRequests")
+}
+
+// 0012 校验请求遥测
+
+type checkEventV12 struct {
+	ID        uint      `gorm:"primarykey"`
+	AKey      string    `gorm:"size:100;not null;index:idx_check_event_app_hour"`
+	Hour      int64     `gorm:"not null;index:idx_check_event_app_hour"`
+	Endpoint  string    `gorm:"size:20;not null"`
+	Version   string    `gorm:"size:50"`
+	Result    string    `gorm:"size:20;not null"`
+	DeviceID  string    `gorm:"size:128"`
+	ClientIP  string    `gorm:"size:50"`
+	UserAgent string    `gorm:"size:200"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (checkEventV12) TableName() string { return "check_events" }
+
+func upCheckEvents(s *Schema) error {
+	return s.CreateTable(&checkEventV12{})
+}
+
+func downCheckEvents(s *Schema) error {
+	return s.DropTable(&checkEventV12{})
+}
+
+// 0013 审计日志
+
+type auditEntryV13 struct {
+	ID           uint   `gorm:"primarykey"`
+	ActorID      uint   `gorm:"index"`
+	ActorName    string `gorm:"size:50;index"`
+	TokenID      uint
+	IP           string    `gorm:"size:45"`
+	Action       string    `gorm:"size:30;not null;index"`
+	ResourceType string    `gorm:"size:20;not null;index:idx_audit_resource"`
+	ResourceID   string    `gorm:"size:100;index:idx_audit_resource"`
+	Changes      string    `gorm:"type:text"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+func (auditEntryV13) TableName() string { return "audit_entries" }
+
+func upAuditEntries(s *Schema) error {
+	return s.CreateTable(&auditEntryV13{})
+}
+
+func downAuditEntries(s *Schema) error {
+	return s.DropTable(&auditEntryV13{})
+}
+
+// 0014 Webhook订阅和投递记录
+
+type webhookV14 struct {
+	gorm.Model
+	AKey     string `gorm:"size:100;not null;index"`
+	Name     string `gorm:"size:100;not null"`
+	URL      string `gorm:"size:500;not null"`
+	Secret   string `gorm:"size:100;not null"`
+	Events   string `gorm:"size:500;not null"`
+	IsActive bool   `gorm:"not null;default:true"`
+}
+
+func (webhookV14) TableName() string { return "webhooks" }
+
+type webhookDeliveryV14 struct {
+	ID             uint      `gorm:"primarykey"`
+	WebhookID      uint      `gorm:"not null;index"`
+	AKey           string    `gorm:"size:100;not null;index"`
+	EventID        string    `gorm:"size:64;not null"`
+	Event          string    `gorm:"size:50;not null"`
+	Payload        string    `gorm:"type:text;not null"`
+	Status         string    `gorm:"size:20;not null;default:pending;index:idx_delivery_due"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"index:idx_delivery_due"`
+	LastAttemptAt  *time.Time
+	ResponseStatus int
+	ResponseBody   string `gorm:"size:1000"`
+	Error          string `gorm:"size:500"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"index"`
+	UpdatedAt      time.Time
+}
+
+func (webhookDeliveryV14) TableName() string { return "webhook_deliveries" }
+
+func upWebhooks(s *Schema) error {
+	return s.CreateTable(&webhookV14{}, &webhookDeliveryV14{})
+}
+
+func downWebhooks(s *Schema) error {
+	return s.DropTable(&webhookDeliveryV14{}, &webhookV14{})
+}
+
+// 0015 公告定向推送和定时下线
+
+type announcementV15 struct {
+	PublishDate time.Time `gorm:"index"`
+	UnpublishAt *time.Time
+	AKeys       string `gorm:"type:text"`
+	Channels    string `gorm:"size:100"`
+	MinVersion  string `gorm:"size:50"`
+	MaxVersion  string `gorm:"size:50"`
+}
+
+func (announcementV15) TableName() string { return "announcements" }
+
+func upAnnouncementTargeting(s *Schema) error {
+	if err := s.AddColumn(&announcementV15{}, "UnpublishAt", "AKeys", "Channels", "MinVersion", "MaxVersion"); err != nil {
+		return err
+	}
+	return s.CreateIndex(&announcementV15{}, "PublishDate")
+}
+
+func downAnnouncementTargeting(s *Schema) error {
+	if err := s.DropIndex(&announcementV15{}, "PublishDate"); err != nil {
+		return err
+	}
+	return s.DropColumn(&announcementV15{}, "UnpublishAt", "AKeys", "Channels", "MinVersion", "MaxVersion")
+}
+
+// 0016 版本制品
+
+type artifactV16 struct {
+	ID          uint   `gorm:"primarykey"`
+	VersionID   uint   `gorm:"not null;uniqueIndex:idx_artifact_platform"`
+	OS          string `gorm:"size:20;not null;uniqueIndex:idx_artifact_platform"`
+	Arch        string `gorm:"size:20;not null;uniqueIndex:idx_artifact_platform"`
+	FileName    string `gorm:"size:255;not null"`
+	ContentType string `gorm:"size:100"`
+	Size        int64  `gorm:"not null"`
+	SHA256      string `gorm:"size:64;not null"`
+	StorageKey  string `gorm:"size:500;not null"`
+	CreatedAt   time.Time
+}
+
+func (artifactV16) TableName() string { return "artifacts" }
+
+func upArtifacts(s *Schema) error {
+	return s.CreateTable(&artifactV16{})
+}
+
+func downArtifacts(s *Schema) error {
+	return s.DropTable(&artifactV16{})
+}
+
+// 0017 清除旧版本遗留的最新版本标记
+// 旧版本程序自动为每个应用维护一个 is_latest 版本（创建首个版本、删除最新版本时自动设置），
+// 现在该标记表示手动固定的最新版本，保留下来会使之后发布的版本都不会被推送，因此全部清除，恢复按版本号判定
+
+func upClearLatestPins(s *Schema) error {
+	return s.Exec("UPDATE versions SET is_latest = ?", false)
+}
+
+// downClearLatestPins 清除的标记无法恢复，回滚时不做处理
+func downClearLatestPins(s *Schema) error {
+	return nil
+}
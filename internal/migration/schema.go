@@ -0,0 +1,197 @@
+package migration
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Schema 迁移中使用的表结构操作
+// 每个操作执行前都会检查当前表结构，已存在的表、列、索引不会重复创建，不存在的也不会重复删除，
+// 因此迁移可以安全地在由旧版本程序创建的数据库上执行
+type Schema struct {
+	db   *gorm.DB // 用于查询当前表结构
+	exec *gorm.DB // 用于执行变更，预演模式下只输出SQL
+}
+
+// Exec 执行数据迁移语句，预演模式下只输出SQL
+func (s *Schema) Exec(sql string, values ...interface{}) error {
+	if err := s.exec.Exec(sql, values...).Error; err != nil {
+		return fmt.Errorf("执行数据迁移失败: %w", err)
+	}
+	return nil
+}
+
+// CreateTable 创建不存在的表，表结构以传入的模型为准
+func (s *Schema) CreateTable(models ...interface{}) error {
+	for _, model := range models {
+		if s.db.Migrator().HasTable(model) {
+			continue
+		}
+		if err := s.exec.Migrator().CreateTable(model); err != nil {
+			return fmt.Errorf("创建表 %s 失败: %w", s.tableName(model), err)
+		}
+	}
+	return nil
+}
+
+// DropTable 删除存在的表
+func (s *Schema) DropTable(models ...interface{}) error {
+	for _, model := range models {
+		if !s.db.Migrator().HasTable(model) {
+			continue
+		}
+		if err := s.exec.Migrator().DropTable(model); err != nil {
+			return fmt.Errorf("删除表 %s 失败: %w", s.tableName(model), err)
+		}
+	}
+	return nil
+}
+
+// AddColumn 添加不存在的列，fields 为模型中的字段名
+func (s *Schema) AddColumn(model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if s.db.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := s.exec.Migrator().AddColumn(model, field); err != nil {
+			return fmt.Errorf("添加列 %s.%s 失败: %w", s.tableName(model), field, err)
+		}
+	}
+	return nil
+}
+
+// DropColumn 删除存在的列，fields 为模型中的字段名
+func (s *Schema) DropColumn(model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if !s.db.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := s.dropColumn(model, field); err != nil {
+			return fmt.Errorf("删除列 %s.%s 失败: %w", s.tableName(model), field, err)
+		}
+	}
+	return nil
+}
+
+// CreateIndex 创建不存在的索引，names 为索引名或建立索引的字段名
+func (s *Schema) CreateIndex(model interface{}, names ...string) error {
+	for _, name := range names {
+		if s.db.Migrator().HasIndex(model, name) {
+			continue
+		}
+		if err := s.exec.Migrator().CreateIndex(model, name); err != nil {
+			return fmt.Errorf("创建索引 %s.%s 失败: %w", s.tableName(model), name, err)
+		}
+	}
+	return nil
+}
+
+// DropIndex 删除存在的索引，names 为索引名或建立索引的字段名
+func (s *Schema) DropIndex(model interface{}, names ...string) error {
+	for _, name := range names {
+		if !s.db.Migrator().HasIndex(model, name) {
+			continue
+		}
+		if err := s.exec.Migrator().DropIndex(model, name); err != nil {
+			return fmt.Errorf("删除索引 %s.%s 失败: %w", s.tableName(model), name, err)
+		}
+	}
+	return nil
+}
+
+// ReplaceConstraint 按模型中的定义重建约束，用于修改外键的级联规则
+// name 为约束名或定义约束的关联字段名
+func (s *Schema) ReplaceConstraint(model interface{}, name string) error {
+	return s.alterConstraint(model, name, func() error {
+		if s.db.Migrator().HasConstraint(model, name) {
+			if err := s.exec.Migrator().DropConstraint(model, name); err != nil {
+				return fmt.Errorf("删除约束 %s 失败: %w", name, err)
+			}
+		}
+		if err := s.exec.Migrator().CreateConstraint(model, name); err != nil {
+			return fmt.Errorf("创建约束 %s 失败: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// alterConstraint 执行约束变更
+// SQLite 不支持修改约束，驱动会重建约束所在的表，重建时表上的索引会一并删除，因此变更后重新创建原有索引
+func (s *Schema) alterConstraint(model interface{}, name string, fn func() error) error {
+	if !s.isSQLite() {
+		return fn()
+	}
+
+	indexes, err := s.sqliteIndexes(model, name)
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err := s.exec.Exec(index).Error; err != nil {
+			return fmt.Errorf("重建索引失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropColumn 删除列
+// SQLite 驱动默认通过重建表删除列，被外键引用的表重建时会触发级联删除，因此改用原生的 DROP COLUMN 语句
+func (s *Schema) dropColumn(model interface{}, field string) error {
+	if !s.isSQLite() {
+		return s.exec.Migrator().DropColumn(model, field)
+	}
+
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	column := field
+	if f := stmt.Schema.LookUpField(field); f != nil {
+		column = f.DBName
+	}
+	return s.exec.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: stmt.Table}, clause.Column{Name: column}).Error
+}
+
+// sqliteIndexes 返回约束所在表上的索引定义语句
+func (s *Schema) sqliteIndexes(model interface{}, name string) ([]string, error) {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	// 外键约束定义在关联字段上时，约束位于被关联的表
+	table := stmt.Table
+	if rel, ok := stmt.Schema.Relationships.Relations[name]; ok {
+		if constraint := rel.ParseConstraint(); constraint != nil {
+			table = constraint.Schema.Table
+		}
+	}
+
+	var indexes []string
+	err := s.db.Table("sqlite_master").
+		Where("type = ? AND tbl_name = ? AND sql IS NOT NULL", "index", table).
+		Pluck("sql", &indexes).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询索引定义失败: %w", err)
+	}
+	return indexes, nil
+}
+
+// isSQLite 判断当前数据库是否为SQLite
+func (s *Schema) isSQLite() bool {
+	return s.db.Dialector.Name() == "sqlite"
+}
+
+// tableName 返回模型对应的表名，用于错误信息
+func (s *Schema) tableName(model interface{}) string {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(model); err != nil {
+		return fmt.Sprintf("%T", model)
+	}
+	return stmt.Table
+}
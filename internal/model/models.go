@@ -1,8 +1,12 @@
 package model
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
+	"verkeyoss/internal/semver"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -28,18 +32,190 @@ func IsBcryptHash(password string) bool {
 	return prefix == "$2a$" || prefix == "$2b$" || prefix == "$2y$"
 }
 
+// 用户角色，按权限从高到低排列
+const (
+	RoleOwner      = "owner"      // 所有者：管理用户及全部资源
+	RoleMaintainer = "maintainer" // 维护者：管理应用和版本
+	RoleViewer     = "viewer"     // 查看者：只读访问
+)
+
+// RoleLevel 返回角色的权限等级，数值越大权限越高，未知角色返回0
+func RoleLevel(role string) int {
+	switch role {
+	case RoleOwner:
+		return 3
+	case RoleMaintainer:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsValidRole 判断角色是否合法
+func IsValidRole(role string) bool {
+	return RoleLevel(role) > 0
+}
+
+// User 管理用户模型
+type User struct {
+	gorm.Model
+	Username    string     `gorm:"size:50;not null;uniqueIndex" json:"username"`
+	Password    string     `gorm:"size:100;not null" json:"-"`                  // 存储加密后的密码
+	Role        string     `gorm:"size:20;not null;default:viewer" json:"role"` // 用户角色
+	IsActive    bool       `gorm:"not null;default:true" json:"is_active"`      // 是否启用
+	LastLoginAt *time.Time `json:"last_login_at"`                               // 最近登录时间
+}
+
+// API令牌权限范围
+const (
+	ScopeVersionRead   = "version:read"   // 查看版本列表
+	ScopeVersionCreate = "version:create" // 发布新版本
+	ScopeVersionUpdate = "version:update" // 更新版本信息
+	ScopeVersionDelete = "version:delete" // 删除版本
+)
+
+// IsValidScope 判断权限范围是否合法
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeVersionRead, ScopeVersionCreate, ScopeVersionUpdate, ScopeVersionDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// APIToken API令牌模型
+// 用于CI等自动化场景，长期有效且可随时吊销，只保存令牌的哈希值
+type APIToken struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"user_id"`         // 创建者ID，令牌权限不超过创建者的角色
+	Name       string     `gorm:"size:100;not null" json:"name"`         // 令牌名称
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // 令牌的SHA-256哈希
+	Prefix     string     `gorm:"size:20;not null" json:"prefix"`        // 令牌前缀，便于识别
+	AKeys      string     `gorm:"type:text;not null" json:"-"`           // 授权的应用AKey，逗号分隔
+	Scopes     string     `gorm:"size:500;not null" json:"-"`            // 权限范围，逗号分隔
+	ExpiresAt  *time.Time `json:"expires_at"`                            // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`                          // 最近使用时间
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at"`               // 吊销时间
+}
+
+// AKeyList 返回令牌授权的应用列表
+func (t *APIToken) AKeyList() []string {
+	return splitList(t.AKeys)
+}
+
+// ScopeList 返回令牌的权限范围列表
+func (t *APIToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+// AllowsApp 判断令牌是否被授权访问指定应用
+func (t *APIToken) AllowsApp(akey string) bool {
+	for _, item := range t.AKeyList() {
+		if item == akey {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope 判断令牌是否拥有指定权限范围
+func (t *APIToken) HasScope(scope string) bool {
+	for _, item := range t.ScopeList() {
+		if item == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUsable 判断令牌当前是否可用（未吊销且未过期）
+func (t *APIToken) IsUsable(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// JoinList 将字符串列表拼接为逗号分隔的字段值
+func JoinList(items []string) string {
+	return strings.Join(items, ",")
+}
+
+// containsItem 判断列表是否包含指定项
+func containsItem(items []string, item string) bool {
+	for _, value := range items {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList 将逗号分隔的字段值拆分为列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// 发布渠道，按稳定程度从高到低排列
+const (
+	ChannelStable  = "stable"  // 稳定版
+	ChannelBeta    = "beta"    // 测试版
+	ChannelNightly = "nightly" // 每日构建版
+)
+
+// Channels 所有发布渠道，按稳定程度从高到低排列
+var Channels = []string{ChannelStable, ChannelBeta, ChannelNightly}
+
+// ChannelLevel 返回发布渠道的等级，数值越大越不稳定，未知渠道返回0
+// 订阅某个渠道的客户端可以收到该渠道及所有更稳定渠道的版本
+func ChannelLevel(channel string) int {
+	for i, item := range Channels {
+		if item == channel {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// IsValidChannel 判断发布渠道是否合法
+func IsValidChannel(channel string) bool {
+	return ChannelLevel(channel) > 0
+}
+
+// 灰度发布状态
+const (
+	RolloutActive = "active" // 按比例向客户端推送
+	RolloutPaused = "paused" // 已暂停，暂不向尚未更新的客户端推送，可恢复
+	RolloutHalted = "halted" // 已终止，需重新设置比例才会继续推送
+)
+
 // App 应用模型
 type App struct {
 	gorm.Model
-	UserID       uint      `gorm:"not null;index" json:"user_id"`             // 创建者ID，固定为管理员ID
+	UserID       uint      `gorm:"not null;index" json:"user_id"`             // 创建者ID
 	AKey         string    `gorm:"size:100;not null;uniqueIndex" json:"akey"` // 应用唯一标识
 	Name         string    `gorm:"size:100;not null" json:"name"`
 	Description  string    `gorm:"size:500" json:"description"`
 	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
 	VersionCount int64     `gorm:"-" json:"version_count"`                // 版本数量，不映射到数据库字段
 	IsPaid       bool      `gorm:"not null;default:false" json:"is_paid"` // 是否收费
+	// 最低支持版本，低于该版本的客户端必须更新，为空表示不限制
+	MinSupportedVersion string `gorm:"size:50" json:"min_supported_version"`
+	// 校验请求签名密钥，客户端使用该密钥对校验请求做HMAC签名
+	RequestSecret string `gorm:"size:100" json:"-"`
+	// 是否要求校验请求必须签名
+	RequireSignedRequests bool `gorm:"not null;default:false" json:"require_signed_requests"`
 	// 关联版本（一对多）
-	Versions []Version `gorm:"foreignKey:AKey;references:AKey;constraint:OnDelete:CASCADE" json:"versions,omitempty"`
+	Versions []Version `gorm:"foreignKey:AKey;references:AKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"versions,omitempty"`
 }
 
 // Version 版本模型
@@ -48,34 +224,598 @@ type Version struct {
 	VKey           string    `gorm:"size:100;not null;uniqueIndex" json:"vkey"`                      // 版本唯一标识
 	AKey           string    `gorm:"size:100;not null;index;foreignKey;references:AKey" json:"akey"` // 外键，关联App结构体
 	Version        string    `gorm:"size:50;not null" json:"version"`                                // 版本号
+	Channel        string    `gorm:"size:20;not null;default:stable;index" json:"channel"`           // 发布渠道
 	Description    string    `gorm:"size:500" json:"description"`
-	IsLatest       bool      `gorm:"not null;default:false" json:"is_latest"`
-	IsForcedUpdate bool      `gorm:"not null;default:false" json:"is_forced_update"` // 是否强制更新
+	IsLatest       bool      `gorm:"not null;default:false" json:"is_latest"`               // 是否固定为所在渠道的最新版本，为false时按语义化版本号自动判定
+	IsForcedUpdate bool      `gorm:"not null;default:false" json:"is_forced_update"`        // 是否强制更新
+	RolloutPercent int       `gorm:"not null;default:100" json:"rollout_percent"`           // 灰度发布比例（1-100）
+	RolloutStatus  string    `gorm:"size:20;not null;default:active" json:"rollout_status"` // 灰度发布状态
 	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	// 撤回时间，已撤回的VKey无法通过校验且不再作为更新推送，为空表示未撤回
+	RevokedAt    *time.Time `gorm:"index" json:"revoked_at"`
+	RevokeReason string     `gorm:"size:500" json:"revoke_reason"` // 撤回原因
+	// 版本的制品，不映射到数据库字段，仅在导出和导入备份时使用
+	Artifacts []*Artifact `gorm:"-" json:"-"`
+}
+
+// IsRevoked 判断版本是否已被撤回
+func (v *Version) IsRevoked() bool {
+	return v.RevokedAt != nil
+}
+
+// 制品适用于全部操作系统或架构时使用的平台标识
+const PlatformAny = "any"
+
+// 常见的操作系统和架构别名，客户端上报时统一转换为 Go 的 GOOS、GOARCH 取值
+var platformAliases = map[string]string{
+	"macos":   "darwin",
+	"mac":     "darwin",
+	"osx":     "darwin",
+	"win":     "windows",
+	"win32":   "windows",
+	"win64":   "windows",
+	"x86_64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"x86":     "386",
+	"i386":    "386",
+	"i686":    "386",
+	"armv7":   "arm",
+	"armv7l":  "arm",
+}
+
+// platformPattern 操作系统和架构标识的格式
+var platformPattern = regexp.MustCompile(`^[a-z0-9_]{1,20}$`)
+
+// NormalizePlatform 将操作系统或架构标识转换为小写并替换常见别名，为空时返回 any
+func NormalizePlatform(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return PlatformAny
+	}
+	if alias, ok := platformAliases[value]; ok {
+		return alias
+	}
+	return value
+}
+
+// IsValidPlatform 判断操作系统或架构标识是否合法，只允许小写字母、数字和下划线
+func IsValidPlatform(value string) bool {
+	return platformPattern.MatchString(value)
+}
+
+// Artifact 版本制品，即版本在某个操作系统和架构上的安装包或更新包
+// 文件内容保存在制品存储中，同一版本的每个平台只保留一个制品
+type Artifact struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	VersionID   uint      `gorm:"not null;uniqueIndex:idx_artifact_platform" json:"version_id"`   // 所属版本ID
+	OS          string    `gorm:"size:20;not null;uniqueIndex:idx_artifact_platform" json:"os"`   // 操作系统，any 表示适用于全部操作系统
+	Arch        string    `gorm:"size:20;not null;uniqueIndex:idx_artifact_platform" json:"arch"` // 架构，any 表示适用于全部架构
+	FileName    string    `gorm:"size:255;not null" json:"file_name"`                             // 文件名
+	ContentType string    `gorm:"size:100" json:"content_type"`                                   // 文件类型
+	Size        int64     `gorm:"not null" json:"size"`                                           // 文件大小（字节）
+	SHA256      string    `gorm:"size:64;not null" json:"sha256"`                                 // 文件内容的SHA-256摘要（十六进制）
+	StorageKey  string    `gorm:"size:500;not null" json:"-"`                                     // 在制品存储中的对象键
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MatchRank 返回制品与客户端平台的匹配程度，数值越大越匹配，不匹配时返回0
+// 操作系统和架构都相同时最匹配，其次为操作系统相同、适用于全部架构的制品，最后为适用于全部平台的制品
+func (a *Artifact) MatchRank(os, arch string) int {
+	osMatch := a.OS == os && os != PlatformAny
+	archMatch := a.Arch == arch && arch != PlatformAny
+	switch {
+	case osMatch && archMatch:
+		return 4
+	case osMatch && a.Arch == PlatformAny:
+		return 3
+	case a.OS == PlatformAny && archMatch:
+		return 2
+	case a.OS == PlatformAny && a.Arch == PlatformAny:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ArtifactDownload 制品的下载信息，下载地址在 ExpiresAt 之后失效
+type ArtifactDownload struct {
+	ID          uint      `json:"id"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	URL         string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// 密钥类型
+const (
+	KeyTypeAKey = "akey" // 应用唯一标识
+	KeyTypeVKey = "vkey" // 版本唯一标识
+)
+
+// RetiredKey 已轮换的旧密钥
+// 宽限期内旧密钥仍可通过校验，并映射到轮换后的新密钥，过期后失效
+type RetiredKey struct {
+	gorm.Model
+	KeyType   string    `gorm:"size:10;not null" json:"key_type"`             // 密钥类型
+	OldKey    string    `gorm:"size:100;not null;uniqueIndex" json:"old_key"` // 旧密钥
+	NewKey    string    `gorm:"size:100;not null;index" json:"new_key"`       // 轮换后的新密钥
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`             // 旧密钥失效时间
+}
+
+// KeyStatus AKey和VKey的校验状态
+type KeyStatus string
+
+const (
+	KeyStatusValid   KeyStatus = "valid"   // 校验通过
+	KeyStatusInvalid KeyStatus = "invalid" // AKey和VKey不存在对应关系
+	KeyStatusRevoked KeyStatus = "revoked" // VKey已被撤回
+	KeyStatusBlocked KeyStatus = "blocked" // 设备已被禁用
+)
+
+// ForcedUpdateRange 强制更新版本范围
+// 当前版本落在范围内的客户端必须更新，用于停用存在漏洞的版本而无需逐个修改版本记录
+type ForcedUpdateRange struct {
+	gorm.Model
+	AKey       string `gorm:"size:100;not null;index" json:"akey"` // 所属应用
+	MinVersion string `gorm:"size:50" json:"min_version"`          // 范围下界（含），为空表示不限
+	MaxVersion string `gorm:"size:50" json:"max_version"`          // 范围上界（含），为空表示不限
+	Reason     string `gorm:"size:500" json:"reason"`              // 强制更新原因，返回给客户端
+}
+
+// Contains 判断版本号是否落在范围内，版本号无法解析时返回false
+func (r *ForcedUpdateRange) Contains(version string) bool {
+	if r.MinVersion != "" {
+		if c, err := semver.Compare(version, r.MinVersion); err != nil || c < 0 {
+			return false
+		}
+	}
+	if r.MaxVersion != "" {
+		if c, err := semver.Compare(version, r.MaxVersion); err != nil || c > 0 {
+			return false
+		}
+	}
+	return semver.IsValid(version)
+}
+
+// 强制更新原因
+const (
+	ForceReasonRevoked       = "version_revoked"       // 当前版本已被撤回
+	ForceReasonMinVersion    = "min_supported_version" // 低于应用的最低支持版本
+	ForceReasonVersionRange  = "version_range"         // 落在强制更新版本范围内
+	ForceReasonForcedRelease = "forced_release"        // 存在标记为强制更新的新版本
+)
+
+// ForceUpdateDecision 强制更新判定结果
+type ForceUpdateDecision struct {
+	Required bool   `json:"required"`         // 是否必须更新
+	Reason   string `json:"reason,omitempty"` // 原因代码
+	Message  string `json:"message,omitempty"`
+}
+
+// License 许可证模型
+// 管理员为付费应用签发许可证，客户端使用设备标识激活，激活设备数不超过席位数
+type License struct {
+	gorm.Model
+	AKey       string     `gorm:"size:100;not null;index" json:"akey"`             // 所属应用
+	LicenseKey string     `gorm:"size:50;not null;uniqueIndex" json:"license_key"` // 许可证密钥
+	Seats      int        `gorm:"not null;default:1" json:"seats"`                 // 席位数，即最多可激活的设备数
+	Note       string     `gorm:"size:200" json:"note"`                            // 备注，如客户名称或订单号
+	UserID     uint       `gorm:"not null;index" json:"user_id"`                   // 签发者ID
+	ExpiresAt  *time.Time `json:"expires_at"`                                      // 过期时间，为空表示永久有效
+	RevokedAt  *time.Time `json:"revoked_at"`                                      // 吊销时间
+	// 已激活的设备数，不映射到数据库字段
+	ActivationCount int64 `gorm:"-" json:"activation_count"`
+}
+
+// LicenseActivation 许可证激活记录
+type LicenseActivation struct {
+	gorm.Model
+	LicenseID  uint      `gorm:"not null;uniqueIndex:idx_license_device" json:"license_id"`         // 许可证ID
+	DeviceID   string    `gorm:"size:128;not null;uniqueIndex:idx_license_device" json:"device_id"` // 设备标识
+	LastSeenAt time.Time `json:"last_seen_at"`                                                      // 最近校验时间
+}
+
+// 许可证状态
+const (
+	LicenseStatusValid        = "valid"         // 有效且当前设备已激活
+	LicenseStatusMissing      = "missing"       // 付费应用未提供许可证
+	LicenseStatusInvalid      = "invalid"       // 许可证不存在或不属于该应用
+	LicenseStatusExpired      = "expired"       // 许可证已过期
+	LicenseStatusRevoked      = "revoked"       // 许可证已吊销
+	LicenseStatusNotActivated = "not_activated" // 当前设备未激活该许可证
+	LicenseStatusSeatLimit    = "seat_limit"    // 席位已满，无法激活新设备
+	LicenseStatusBlocked      = "blocked"       // 当前设备已被禁用
+)
+
+// LicenseCheck 许可证校验结果
+type LicenseCheck struct {
+	Status    string     `json:"status"`
+	Message   string     `json:"message"`
+	Seats     int        `json:"seats,omitempty"`      // 席位数
+	UsedSeats int64      `json:"used_seats,omitempty"` // 已激活的设备数
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 过期时间
+}
+
+// Device 设备模型
+// 客户端在校验和检查更新时携带设备标识即登记设备，记录每个应用下设备的首次和最近访问情况，
+// 用于发现泄露的VKey被大量设备使用的情况，并可禁用指定设备
+type Device struct {
+	gorm.Model
+	AKey        string     `gorm:"size:100;not null;uniqueIndex:idx_app_device" json:"akey"`      // 所属应用
+	DeviceID    string     `gorm:"size:128;not null;uniqueIndex:idx_app_device" json:"device_id"` // 设备标识
+	VKey        string     `gorm:"size:100;index" json:"vkey"`                                    // 最近使用的VKey
+	Version     string     `gorm:"size:50" json:"version"`                                        // 最近使用的版本号
+	IP          string     `gorm:"size:45" json:"ip"`                                             // 最近访问的IP地址
+	FirstSeenAt time.Time  `json:"first_seen_at"`                                                 // 首次访问时间
+	LastSeenAt  time.Time  `gorm:"index" json:"last_seen_at"`                                     // 最近访问时间
+	BlockedAt   *time.Time `json:"blocked_at"`                                                    // 禁用时间，为空表示未禁用
+	BlockReason string     `gorm:"size:500" json:"block_reason"`                                  // 禁用原因
+}
+
+// IsBlocked 判断设备是否已被禁用
+func (d *Device) IsBlocked() bool {
+	return d.BlockedAt != nil
+}
+
+// DeviceQuery 设备列表查询条件
+type DeviceQuery struct {
+	VKey    string // 按最近使用的VKey筛选
+	Keyword string // 按设备标识或IP地址模糊搜索
+	Blocked *bool  // 按禁用状态筛选，为nil时不筛选
+}
+
+// 校验请求遥测记录的接口类型
+const (
+	CheckEndpointValidate = "validate" // 校验 AKey 和 VKey
+	CheckEndpointUpdate   = "update"   // 检查更新
+)
+
+// CheckEvent 校验请求遥测记录
+// 每次调用校验和检查更新接口都会异步写入一条记录，用于统计各版本的活跃安装数、版本普及情况和校验失败率。
+// 记录量较大，不使用软删除，超过保留期限后直接删除
+type CheckEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AKey      string    `gorm:"size:100;not null;index:idx_check_event_app_hour" json:"akey"` // 所属应用
+	Hour      int64     `gorm:"not null;index:idx_check_event_app_hour" json:"hour"`          // 请求时间所在小时的Unix时间戳，用于按时间分组统计
+	Endpoint  string    `gorm:"size:20;not null" json:"endpoint"`                             // 接口类型
+	Version   string    `gorm:"size:50" json:"version"`                                       // 版本号，AKey或VKey无效时为空
+	Result    KeyStatus `gorm:"size:20;not null" json:"result"`                               // 校验结果
+	DeviceID  string    `gorm:"size:128" json:"device_id"`                                    // 设备标识，未提供时为空
+	ClientIP  string    `gorm:"size:50" json:"client_ip"`                                     // 客户端IP所在网段，IPv4保留前24位，IPv6保留前48位
+	UserAgent string    `gorm:"size:200" json:"user_agent"`                                   // 客户端User-Agent
+	CreatedAt time.Time `gorm:"index" json:"created_at"`                                      // 请求时间
+}
+
+// VersionInstalls 版本的活跃安装数
+type VersionInstalls struct {
+	Version  string `json:"version"`
+	Installs int64  `json:"installs"`
+}
+
+// CheckEventCount 按小时、版本和校验结果分组的请求数
+type CheckEventCount struct {
+	Hour    int64
+	Version string
+	Result  KeyStatus
+	Count   int64
+}
+
+// CheckEventDevice 某小时内访问过某版本的设备
+type CheckEventDevice struct {
+	Hour     int64
+	Version  string
+	DeviceID string
+}
+
+// 审计日志的操作类型
+const (
+	AuditActionCreate         = "create"          // 创建
+	AuditActionUpdate         = "update"          // 更新
+	AuditActionDelete         = "delete"          // 删除
+	AuditActionRotateKey      = "rotate_key"      // 轮换密钥
+	AuditActionRollout        = "rollout"         // 调整灰度发布
+	AuditActionRevoke         = "revoke"          // 撤回版本
+	AuditActionRestore        = "restore"         // 恢复版本
+	AuditActionLogin          = "login"           // 登录成功
+	AuditActionLoginFailed    = "login_failed"    // 登录失败
+	AuditActionPasswordChange = "password_change" // 修改密码
+	AuditActionExport         = "export"          // 导出备份
+	AuditActionImport         = "import"          // 导入备份
+)
+
+// 审计日志的资源类型
+const (
+	AuditResourceApp     = "app"
+	AuditResourceVersion = "version"
+	AuditResourceUser    = "user"
+	AuditResourceBackup  = "backup"
+	// 制品的资源标识为制品ID
+	AuditResourceArtifact = "artifact"
+)
+
+// AuditEntry 审计日志模型
+// 记录管理操作的执行者、来源IP、操作对象及变更前后的字段差异，只追加不修改，超过保留期限后删除
+type AuditEntry struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	ActorID      uint      `gorm:"index" json:"actor_id"`                // 执行者用户ID，登录失败等未认证的操作为0
+	ActorName    string    `gorm:"size:50;index" json:"actor_name"`      // 执行者用户名，登录失败时为尝试登录的用户名
+	TokenID      uint      `json:"token_id"`                             // 通过API令牌操作时的令牌ID，否则为0
+	IP           string    `gorm:"size:45" json:"ip"`                    // 执行者的IP地址
+	Action       string    `gorm:"size:30;not null;index" json:"action"` // 操作类型
+	ResourceType string    `gorm:"size:20;not null;index:idx_audit_resource" json:"resource_type"`
+	ResourceID   string    `gorm:"size:100;index:idx_audit_resource" json:"resource_id"` // 操作对象标识，如AKey、VKey或用户ID
+	Changes      string    `gorm:"type:text" json:"changes"`                             // 变更前后的字段差异（JSON）
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// AuditQuery 审计日志查询条件
+type AuditQuery struct {
+	ActorName    string    // 按执行者用户名筛选
+	Action       string    // 按操作类型筛选
+	ResourceType string    // 按资源类型筛选
+	ResourceID   string    // 按操作对象标识筛选
+	From         time.Time // 开始时间（包含），为零值时不限
+	To           time.Time // 结束时间（不包含），为零值时不限
+}
+
+// Webhook事件类型
+const (
+	WebhookEventVersionCreated = "version.created" // 发布新版本
+	WebhookEventVersionUpdated = "version.updated" // 更新版本信息
+	WebhookEventVersionDeleted = "version.deleted" // 删除版本
+	WebhookEventPing           = "ping"            // 测试事件，只能通过发送测试事件接口触发
+)
+
+// WebhookEvents 可订阅的Webhook事件类型
+// 只包含版本管理事件，校验接口调用量大且由客户端触发，不提供校验结果事件
+var WebhookEvents = []string{WebhookEventVersionCreated, WebhookEventVersionUpdated, WebhookEventVersionDeleted}
+
+// IsValidWebhookEvent 判断事件类型是否可订阅
+func IsValidWebhookEvent(event string) bool {
+	for _, item := range WebhookEvents {
+		if item == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook 应用的Webhook订阅
+// 订阅的事件发生时，向URL发送使用 Secret 签名的JSON请求
+type Webhook struct {
+	gorm.Model
+	AKey     string `gorm:"size:100;not null;index" json:"akey"`    // 所属应用
+	Name     string `gorm:"size:100;not null" json:"name"`          // 订阅名称
+	URL      string `gorm:"size:500;not null" json:"url"`           // 接收事件的地址
+	Secret   string `gorm:"size:100;not null" json:"-"`             // 签名密钥
+	Events   string `gorm:"size:500;not null" json:"-"`             // 订阅的事件类型，逗号分隔
+	IsActive bool   `gorm:"not null;default:true" json:"is_active"` // 是否启用，停用后不再投递新事件
+}
+
+// EventList 返回订阅的事件类型列表
+func (w *Webhook) EventList() []string {
+	return splitList(w.Events)
+}
+
+// Subscribes 判断是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	for _, item := range w.EventList() {
+		if item == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook投递状态
+const (
+	DeliveryPending   = "pending"   // 等待投递或等待重试
+	DeliverySucceeded = "succeeded" // 投递成功
+	DeliveryFailed    = "failed"    // 超过最大尝试次数，不再重试
+)
+
+// WebhookDelivery Webhook投递记录
+// 事件发生时为每个订阅写入一条记录，由后台协程投递，服务重启后未完成的投递会继续进行；
+// 请求体在写入时生成，重试时保持不变。不使用软删除，超过保留期限后直接删除
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	WebhookID      uint       `gorm:"not null;index" json:"webhook_id"`
+	AKey           string     `gorm:"size:100;not null;index" json:"akey"`
+	EventID        string     `gorm:"size:64;not null" json:"event_id"` // 事件唯一标识，重试时不变，接收方可用于去重
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`                                     // 请求体（JSON）
+	Status         string     `gorm:"size:20;not null;default:pending;index:idx_delivery_due" json:"status"` // 投递状态
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`                                    // 已尝试次数
+	NextAttemptAt  time.Time  `gorm:"index:idx_delivery_due" json:"next_attempt_at"`                         // 下次尝试时间
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`                                                       // 最近一次尝试时间
+	ResponseStatus int        `json:"response_status"`                                                       // 最近一次尝试的响应状态码，请求失败时为0
+	ResponseBody   string     `gorm:"size:1000" json:"response_body"`                                        // 最近一次尝试的响应内容（截断）
+	Error          string     `gorm:"size:500" json:"error"`                                                 // 最近一次尝试的错误信息
+	DeliveredAt    *time.Time `json:"delivered_at"`                                                          // 投递成功时间
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 // Announcement 公告模型
+// 公告在发布日期之后、下线时间之前对目标客户端可见；目标应用、渠道和版本范围均为空时面向全部客户端
 type Announcement struct {
 	gorm.Model
-	Title       string    `gorm:"size:100;not null" json:"title"`         // 公告标题
-	Content     string    `gorm:"type:text;not null" json:"content"`      // 公告内容
-	IsActive    bool      `gorm:"not null;default:true" json:"is_active"` // 是否激活
-	PublishDate time.Time `json:"publish_date"`                           // 发布日期
-	URL         string    `gorm:"size:500" json:"url,omitempty"`          // 公告链接，可选
+	Title       string     `gorm:"size:100;not null" json:"title"`         // 公告标题
+	Content     string     `gorm:"type:text;not null" json:"content"`      // 公告内容
+	IsActive    bool       `gorm:"not null;default:true" json:"is_active"` // 是否激活
+	PublishDate time.Time  `gorm:"index" json:"publish_date"`              // 发布日期，早于该时间时不可见，可用于定时发布
+	URL         string     `gorm:"size:500" json:"url,omitempty"`          // 公告链接，可选
+	UnpublishAt *time.Time `json:"unpublish_at"`                           // 下线时间，为空表示不自动下线
+	AKeys       string     `gorm:"type:text" json:"-"`                     // 目标应用AKey，逗号分隔，为空时面向全部应用
+	Channels    string     `gorm:"size:100" json:"-"`                      // 目标发布渠道，逗号分隔，为空时面向全部渠道
+	MinVersion  string     `gorm:"size:50" json:"min_version"`             // 目标版本下界（含），为空表示不限
+	MaxVersion  string     `gorm:"size:50" json:"max_version"`             // 目标版本上界（含），为空表示不限
+}
+
+// 公告状态，由激活状态、发布日期和下线时间计算得出
+const (
+	AnnouncementDraft     = "draft"     // 未激活
+	AnnouncementScheduled = "scheduled" // 已激活，尚未到发布日期
+	AnnouncementPublished = "published" // 已发布
+	AnnouncementExpired   = "expired"   // 已过下线时间
+)
+
+// IsValidAnnouncementStatus 判断公告状态是否合法
+func IsValidAnnouncementStatus(status string) bool {
+	switch status {
+	case AnnouncementDraft, AnnouncementScheduled, AnnouncementPublished, AnnouncementExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status 返回公告在指定时间的状态
+func (a *Announcement) Status(now time.Time) string {
+	switch {
+	case !a.IsActive:
+		return AnnouncementDraft
+	case a.UnpublishAt != nil && !now.Before(*a.UnpublishAt):
+		return AnnouncementExpired
+	case now.Before(a.PublishDate):
+		return AnnouncementScheduled
+	default:
+		return AnnouncementPublished
+	}
+}
+
+// AKeyList 返回公告的目标应用列表
+func (a *Announcement) AKeyList() []string {
+	return splitList(a.AKeys)
+}
+
+// ChannelList 返回公告的目标发布渠道列表
+func (a *Announcement) ChannelList() []string {
+	return splitList(a.Channels)
+}
+
+// Targets 判断公告是否面向指定应用、渠道和版本的客户端
+// 设置了版本范围时，版本号无法解析的客户端不在目标范围内
+func (a *Announcement) Targets(akey, channel, version string) bool {
+	if akeys := a.AKeyList(); len(akeys) > 0 && !containsItem(akeys, akey) {
+		return false
+	}
+	if channels := a.ChannelList(); len(channels) > 0 && !containsItem(channels, channel) {
+		return false
+	}
+	if a.MinVersion == "" && a.MaxVersion == "" {
+		return true
+	}
+	versionRange := ForcedUpdateRange{MinVersion: a.MinVersion, MaxVersion: a.MaxVersion}
+	return versionRange.Contains(version)
+}
+
+// AnnouncementQuery 公告查询条件
+type AnnouncementQuery struct {
+	Status string // 按状态筛选，为空时不限
+	AKey   string // 只返回面向该应用的公告（包括面向全部应用的公告），为空时不限
+}
+
+// 备份导入模式
+const (
+	BackupModeMerge   = "merge"   // 合并：保留现有数据，跳过与现有数据重复的记录
+	BackupModeReplace = "replace" // 替换：删除备份中不存在的应用及其关联数据，清空版本和公告后导入
+)
+
+// IsValidBackupMode 判断备份导入模式是否合法
+func IsValidBackupMode(mode string) bool {
+	return mode == BackupModeMerge || mode == BackupModeReplace
+}
+
+// 备份中的记录类型
+const (
+	BackupResourceApp          = "app"
+	BackupResourceVersion      = "version"
+	BackupResourceAnnouncement = "announcement"
+)
+
+// BackupConflict 导入时因冲突被跳过的记录
+type BackupConflict struct {
+	Resource string `json:"resource"` // 记录类型：app、version 或 announcement
+	Key      string `json:"key"`      // 应用为AKey，版本为VKey，公告为标题
+	Reason   string `json:"reason"`
+}
+
+// BackupImportCount 某类记录的导入数量
+type BackupImportCount struct {
+	Imported int `json:"imported"` // 导入的记录数
+	Skipped  int `json:"skipped"`  // 因冲突跳过的记录数
+	Removed  int `json:"removed"`  // 替换模式下删除的现有记录数
+}
+
+// BackupImportResult 备份导入结果
+type BackupImportResult struct {
+	Mode          string            `json:"mode"`
+	DryRun        bool              `json:"dry_run"` // 是否为预演，预演时不保存任何修改
+	Apps          BackupImportCount `json:"apps"`
+	Versions      BackupImportCount `json:"versions"`
+	Announcements BackupImportCount `json:"announcements"`
+	Conflicts     []BackupConflict  `json:"conflicts"`
+}
+
+// AddConflict 记录一条被跳过的记录
+func (r *BackupImportResult) AddConflict(resource, key, reason string) {
+	switch resource {
+	case BackupResourceApp:
+		r.Apps.Skipped++
+	case BackupResourceVersion:
+		r.Versions.Skipped++
+	case BackupResourceAnnouncement:
+		r.Announcements.Skipped++
+	}
+	r.Conflicts = append(r.Conflicts, BackupConflict{Resource: resource, Key: key, Reason: reason})
 }
 
 // CheckRequest 校验请求模型
 
 type CheckRequest struct {
-	AKey string `json:"akey" binding:"required"`
-	VKey string `json:"vkey" binding:"required"`
+	AKey     string `json:"akey" binding:"required"`
+	VKey     string `json:"vkey" binding:"required"`
+	Channel  string `json:"channel"`   // 订阅的发布渠道，为空时使用稳定版渠道
+	DeviceID string `json:"device_id"` // 客户端设备标识，用于设备登记和灰度发布分组
+	Nonce    string `json:"nonce"`     // 客户端随机数，原样写入签名的响应中，防止响应被重放
+	// 许可证密钥，付费应用校验时需要与设备标识一起提供
+	LicenseKey string `json:"license_key"`
+	// 客户端的操作系统和架构，检查更新时用于选择对应平台的制品，为空时只匹配适用于全部平台的制品
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// 客户端IP地址，由处理器填写，用于设备登记
+	ClientIP string `json:"-"`
+	// 客户端User-Agent，由处理器填写，用于遥测记录
+	UserAgent string `json:"-"`
+}
+
+// ActivationRequest 许可证激活请求模型
+type ActivationRequest struct {
+	AKey       string `json:"akey" binding:"required"`
+	LicenseKey string `json:"license_key" binding:"required"`
+	DeviceID   string `json:"device_id" binding:"required"` // 设备标识，同一设备应保持不变
+	Nonce      string `json:"nonce"`
 }
 
 // ValidationResponse 合法性校验响应模型
 
 type ValidationResponse struct {
-	Valid   bool   `json:"valid"`
-	Message string `json:"message"`
-	AppName string `json:"app_name,omitempty"`
-	Version string `json:"version,omitempty"`
+	Valid        bool       `json:"valid"`
+	Status       KeyStatus  `json:"status"`
+	Message      string     `json:"message"`
+	AppName      string     `json:"app_name,omitempty"`
+	Version      string     `json:"version,omitempty"`
+	RevokeReason string     `json:"revoke_reason,omitempty"` // 撤回原因，仅在VKey已撤回时返回
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	KeyRotated   bool       `json:"key_rotated"` // 是否使用了宽限期内的旧密钥，客户端应尽快更换为新密钥
+	// 设备禁用原因，仅在设备已被禁用时返回
+	BlockReason string `json:"block_reason,omitempty"`
+	// 许可证校验结果，仅付费应用返回
+	License *LicenseCheck `json:"license,omitempty"`
 }
@@ -0,0 +1,123 @@
+package router
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"verkeyoss/internal/api"
+	"verkeyoss/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitBackend 限流后端，保存各个限流键的令牌桶状态
+// 默认使用进程内存实现，多实例部署时可以替换为各实例共享的实现
+type RateLimitBackend interface {
+	// Take 从限流键对应的令牌桶中取出一个令牌，令牌不足时返回false及需要等待的时长
+	Take(key string, rule config.RateLimitRule) (bool, time.Duration)
+}
+
+// 清理空闲令牌桶的间隔
+const bucketPruneInterval = time.Minute
+
+// MemoryRateLimitBackend 基于进程内存的令牌桶限流后端
+type MemoryRateLimitBackend struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	nextPrune time.Time
+}
+
+// tokenBucket 令牌桶状态
+type tokenBucket struct {
+	tokens    float64   // 当前剩余的令牌数
+	updatedAt time.Time // 上次计算令牌数的时间
+	fullAt    time.Time // 令牌桶补满的时间，此后可以清理
+}
+
+// NewMemoryRateLimitBackend 创建基于进程内存的限流后端
+func NewMemoryRateLimitBackend() *MemoryRateLimitBackend {
+	return &MemoryRateLimitBackend{buckets: make(map[string]*tokenBucket)}
+}
+
+// Take 从限流键对应的令牌桶中取出一个令牌，令牌不足时返回false及需要等待的时长
+func (b *MemoryRateLimitBackend) Take(key string, rule config.RateLimitRule) (bool, time.Duration) {
+	now := time.Now()
+	burst := float64(rule.Burst)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 定期清理已补满的令牌桶，它们与新建的令牌桶等价
+	if now.After(b.nextPrune) {
+		for k, bucket := range b.buckets {
+			if now.After(bucket.fullAt) {
+				delete(b.buckets, k)
+			}
+		}
+		b.nextPrune = now.Add(bucketPruneInterval)
+	}
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, updatedAt: now}
+		b.buckets[key] = bucket
+	}
+
+	// 按经过的时间补充令牌
+	bucket.tokens += now.Sub(bucket.updatedAt).Seconds() * rule.Rate
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	bucket.fullAt = now.Add(secondsToDuration((burst - bucket.tokens) / rule.Rate))
+
+	if !allowed {
+		return false, secondsToDuration((1 - bucket.tokens) / rule.Rate)
+	}
+	return true, 0
+}
+
+// RateLimitMiddleware 令牌桶限流中间件
+// 参数 name 用于区分不同的限流规则，keyFunc 返回限流键，返回空字符串时不限流。
+// 超出限制时返回429，并通过 Retry-After 响应头告知客户端需要等待的秒数
+func RateLimitMiddleware(backend RateLimitBackend, name string, rule config.RateLimitRule, keyFunc func(c *gin.Context) string) gin.HandlerFunc {
+	if !rule.Enabled() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter := backend.Take(name+":"+key, rule)
+		if !allowed {
+			c.Header("Retry-After", api.RetryAfterSeconds(retryAfter))
+			c.JSON(http.StatusTooManyRequests, api.ErrorResponse(429, "请求过于频繁，请稍后再试"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientIPKey 以客户端IP作为限流键
+func clientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// secondsToDuration 将秒数转换为时长
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
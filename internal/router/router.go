@@ -6,6 +6,7 @@ import (
 
 	"verkeyoss/internal/api"
 	"verkeyoss/internal/config"
+	"verkeyoss/internal/model"
 	"verkeyoss/internal/service"
 
 	"github.com/gin-contrib/cors"
@@ -17,8 +18,9 @@ import (
 // 参数 appConfig 提供应用配置信息
 // 参数 version 应用版本号
 // 参数 staticHandler 和 frontendHandler 用于处理前端文件
+// 参数 rateLimitBackend 为公开接口的限流后端
 // 返回配置好的 gin.Engine 实例
-func SetupRouter(services *service.Services, appConfig *config.Config, version string, staticHandler, frontendHandler gin.HandlerFunc) *gin.Engine {
+func SetupRouter(services *service.Services, appConfig *config.Config, version string, staticHandler, frontendHandler gin.HandlerFunc, rateLimitBackend RateLimitBackend) *gin.Engine {
 	// 根据配置设置gin模式
 	if appConfig.Server.Debug {
 		gin.SetMode(gin.DebugMode)
@@ -32,6 +34,11 @@ func SetupRouter(services *service.Services, appConfig *config.Config, version s
 	// 创建gin引擎实例
 	r := gin.New()
 
+	// 只信任配置的反向代理传递的客户端IP，避免伪造 X-Forwarded-For 绕过按IP限流
+	if err := r.SetTrustedProxies(appConfig.Server.TrustedProxies); err != nil {
+		log.Fatalf("反向代理配置错误: %v", err)
+	}
+
 	// 添加中间件
 	r.Use(gin.Recovery()) // 恢复中间件，处理panic
 	if appConfig.Server.Debug {
@@ -41,7 +48,7 @@ func SetupRouter(services *service.Services, appConfig *config.Config, version s
 			AllowAllOrigins:  true, // 允许所有来源
 			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD", "PATCH"},
 			AllowHeaders:     []string{"*"}, // 允许所有请求头
-			ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+			ExposeHeaders:    []string{"Content-Length", "Content-Type", "X-Signature", "X-Signature-Key-Id"},
 			AllowCredentials: false, // 当AllowAllOrigins为true时，必须设置为false
 		}))
 		log.Println("CORS已启用：允许所有域名访问")
@@ -50,51 +57,182 @@ func SetupRouter(services *service.Services, appConfig *config.Config, version s
 	// API路由组
 	apiGroup := r.Group("/api")
 
+	// 管理接口同时接受登录令牌和API令牌，每个路由都必须声明所需角色或权限范围：
+	// AdminMiddleware 只允许登录用户访问，ScopeMiddleware 额外允许拥有对应权限范围的API令牌访问
+	authRequired := api.AuthMiddleware(services.AuthService)
+	viewerOnly := api.AdminMiddleware(model.RoleViewer)
+	maintainerOnly := api.AdminMiddleware(model.RoleMaintainer)
+
 	// 认证接口
 	authGroup := apiGroup.Group("/auth")
 	{
 		authHandler := api.NewAuthHandler(services.AuthService)
-		authGroup.POST("/login", authHandler.Login)
+		authGroup.POST("/login", RateLimitMiddleware(rateLimitBackend, "login_ip", appConfig.RateLimit.LoginPerIP, clientIPKey), authHandler.Login)
 		// 修改密码需要认证
-		authGroup.PUT("/password", api.AuthMiddleware(services.AuthService), authHandler.ChangePassword)
+		authGroup.PUT("/password", authRequired, viewerOnly, authHandler.ChangePassword)
 		// 通过token获取用户信息接口
-		authGroup.GET("/user-info", api.AuthMiddleware(services.AuthService), authHandler.GetUserInfoByToken)
+		authGroup.GET("/user-info", authRequired, viewerOnly, authHandler.GetUserInfoByToken)
+	}
+
+	// 用户管理接口（仅所有者）
+	userGroup := apiGroup.Group("/users")
+	userHandler := api.NewUserHandler(services.UserService)
+	{
+		userGroup.Use(authRequired, api.AdminMiddleware(model.RoleOwner))
+		userGroup.GET("", userHandler.GetUserList)
+		userGroup.POST("", userHandler.CreateUser)
+		userGroup.PUT("/:id", userHandler.UpdateUser)
+		userGroup.DELETE("/:id", userHandler.DeleteUser)
+	}
+
+	// 审计日志接口（仅所有者）
+	auditGroup := apiGroup.Group("/audit")
+	auditHandler := api.NewAuditHandler(services.AuditService)
+	{
+		auditGroup.Use(authRequired, api.AdminMiddleware(model.RoleOwner))
+		auditGroup.GET("", auditHandler.GetAuditList)
+	}
+
+	// 备份导出与导入接口（仅所有者），备份包含AKey、VKey和签名密钥
+	backupGroup := apiGroup.Group("/backup")
+	backupHandler := api.NewBackupHandler(services.BackupService)
+	{
+		backupGroup.Use(authRequired, api.AdminMiddleware(model.RoleOwner))
+		backupGroup.GET("/export", backupHandler.ExportBackup)
+		backupGroup.POST("/import", backupHandler.ImportBackup)
+	}
+
+	// 公告管理接口
+	announcementGroup := apiGroup.Group("/announcements")
+	announcementHandler := api.NewAnnouncementHandler(services.AnnouncementService)
+	{
+		announcementGroup.Use(authRequired)
+		announcementGroup.GET("", viewerOnly, announcementHandler.GetAnnouncementList)
+		announcementGroup.GET("/:id", viewerOnly, announcementHandler.GetAnnouncement)
+		announcementGroup.POST("", maintainerOnly, announcementHandler.CreateAnnouncement)
+		announcementGroup.PUT("/:id", maintainerOnly, announcementHandler.UpdateAnnouncement)
+		announcementGroup.DELETE("/:id", maintainerOnly, announcementHandler.DeleteAnnouncement)
+	}
+
+	// API令牌管理接口
+	tokenGroup := apiGroup.Group("/tokens")
+	tokenHandler := api.NewAPITokenHandler(services.APITokenService)
+	{
+		tokenGroup.Use(authRequired)
+		tokenGroup.GET("", viewerOnly, tokenHandler.GetTokenList)
+		tokenGroup.POST("", maintainerOnly, tokenHandler.CreateToken)
+		tokenGroup.DELETE("/:id", viewerOnly, tokenHandler.RevokeToken)
 	}
 
 	// 应用管理接口
 	appGroup := apiGroup.Group("/app")
 	appHandler := api.NewAppHandler(services.AppService)
 	{
-		appGroup.Use(api.AuthMiddleware(services.AuthService))
-		appGroup.POST("", appHandler.CreateApp)
-		appGroup.GET("", appHandler.GetAppList)
-		appGroup.PUT("/:akey", appHandler.UpdateApp)
-		appGroup.DELETE("/:akey", appHandler.DeleteApp)
+		appGroup.Use(authRequired)
+		appGroup.POST("", maintainerOnly, appHandler.CreateApp)
+		appGroup.GET("", viewerOnly, appHandler.GetAppList)
+		appGroup.PUT("/:akey", maintainerOnly, appHandler.UpdateApp)
+		appGroup.DELETE("/:akey", maintainerOnly, appHandler.DeleteApp)
+		appGroup.POST("/:akey/rotate-key", maintainerOnly, appHandler.RotateAKey)
 
 		// 版本管理接口
 		versionGroup := appGroup.Group("/:akey/versions")
 		versionHandler := api.NewVersionHandler(services.VersionService)
 		{
-			versionGroup.POST("", versionHandler.CreateVersion)
-			versionGroup.GET("", versionHandler.GetVersionList)
+			versionGroup.POST("", api.ScopeMiddleware(model.RoleMaintainer, model.ScopeVersionCreate), versionHandler.CreateVersion)
+			versionGroup.GET("", api.ScopeMiddleware(model.RoleViewer, model.ScopeVersionRead), versionHandler.GetVersionList)
 		}
+
+		// 发布渠道概要接口
+		appGroup.GET("/:akey/channels", api.ScopeMiddleware(model.RoleViewer, model.ScopeVersionRead), versionHandler.GetChannelSummaries)
+
+		// 强制更新版本范围接口
+		forcedUpdateHandler := api.NewForcedUpdateHandler(services.ForcedUpdateService)
+		appGroup.GET("/:akey/forced-ranges", api.ScopeMiddleware(model.RoleViewer, model.ScopeVersionRead), forcedUpdateHandler.GetRangeList)
+		appGroup.POST("/:akey/forced-ranges", api.ScopeMiddleware(model.RoleMaintainer, model.ScopeVersionUpdate), forcedUpdateHandler.CreateRange)
+		appGroup.DELETE("/:akey/forced-ranges/:id", api.ScopeMiddleware(model.RoleMaintainer, model.ScopeVersionUpdate), forcedUpdateHandler.DeleteRange)
+
+		// 许可证管理接口
+		licenseHandler := api.NewLicenseHandler(services.LicenseService)
+		appGroup.POST("/:akey/licenses", maintainerOnly, licenseHandler.CreateLicense)
+		appGroup.GET("/:akey/licenses", viewerOnly, licenseHandler.GetLicenseList)
+		appGroup.GET("/:akey/licenses/:id", viewerOnly, licenseHandler.GetLicense)
+		appGroup.POST("/:akey/licenses/:id/revoke", maintainerOnly, licenseHandler.RevokeLicense)
+		appGroup.POST("/:akey/licenses/:id/offline", maintainerOnly, licenseHandler.ExportOfflineLicense)
+
+		// 设备管理接口
+		deviceHandler := api.NewDeviceHandler(services.DeviceService)
+		appGroup.GET("/:akey/devices", viewerOnly, deviceHandler.GetDeviceList)
+		appGroup.POST("/:akey/devices/:id/block", maintainerOnly, deviceHandler.BlockDevice)
+		appGroup.POST("/:akey/devices/:id/unblock", maintainerOnly, deviceHandler.UnblockDevice)
+		appGroup.DELETE("/:akey/devices/:id", maintainerOnly, deviceHandler.DeleteDevice)
+
+		// 校验请求签名设置接口，响应包含签名密钥，仅维护者及以上可访问
+		requestSigningHandler := api.NewRequestSigningHandler(services.RequestSigningService)
+		appGroup.GET("/:akey/request-signing", maintainerOnly, requestSigningHandler.GetSettings)
+		appGroup.PUT("/:akey/request-signing", maintainerOnly, requestSigningHandler.UpdateSettings)
+		appGroup.POST("/:akey/request-signing/rotate-secret", maintainerOnly, requestSigningHandler.RotateSecret)
+		appGroup.DELETE("/:akey/licenses/:id/activations/:activation_id", maintainerOnly, licenseHandler.DeleteActivation)
+
+		// Webhook接口，响应包含签名密钥，仅维护者及以上可访问
+		webhookHandler := api.NewWebhookHandler(services.WebhookService)
+		appGroup.GET("/:akey/webhooks", maintainerOnly, webhookHandler.GetWebhookList)
+		appGroup.POST("/:akey/webhooks", maintainerOnly, webhookHandler.CreateWebhook)
+		appGroup.PUT("/:akey/webhooks/:id", maintainerOnly, webhookHandler.UpdateWebhook)
+		appGroup.DELETE("/:akey/webhooks/:id", maintainerOnly, webhookHandler.DeleteWebhook)
+		appGroup.POST("/:akey/webhooks/:id/rotate-secret", maintainerOnly, webhookHandler.RotateSecret)
+		appGroup.POST("/:akey/webhooks/:id/test", maintainerOnly, webhookHandler.SendTestEvent)
+		appGroup.GET("/:akey/webhooks/:id/deliveries", maintainerOnly, webhookHandler.GetDeliveryList)
 	}
 
 	// 版本详情接口
 	versionDetailGroup := apiGroup.Group("/versions")
 	versionDetailHandler := api.NewVersionHandler(services.VersionService)
+	artifactHandler := api.NewArtifactHandler(services.ArtifactService)
 	{
-		versionDetailGroup.Use(api.AuthMiddleware(services.AuthService))
-		versionDetailGroup.PUT("/:vkey", versionDetailHandler.UpdateVersion)
-		versionDetailGroup.DELETE("/:vkey", versionDetailHandler.DeleteVersion)
+		versionDetailGroup.Use(authRequired)
+		versionDetailGroup.PUT("/:vkey", api.VersionScopeMiddleware(services.VersionService, model.RoleMaintainer, model.ScopeVersionUpdate), versionDetailHandler.UpdateVersion)
+		versionDetailGroup.DELETE("/:vkey", api.VersionScopeMiddleware(services.VersionService, model.RoleMaintainer, model.ScopeVersionDelete), versionDetailHandler.DeleteVersion)
+
+		// 灰度发布接口
+		versionUpdate := api.VersionScopeMiddleware(services.VersionService, model.RoleMaintainer, model.ScopeVersionUpdate)
+		versionDetailGroup.PUT("/:vkey/rollout", versionUpdate, versionDetailHandler.SetRolloutPercent)
+		versionDetailGroup.POST("/:vkey/rollout/pause", versionUpdate, versionDetailHandler.PauseRollout)
+		versionDetailGroup.POST("/:vkey/rollout/resume", versionUpdate, versionDetailHandler.ResumeRollout)
+		versionDetailGroup.POST("/:vkey/rollout/halt", versionUpdate, versionDetailHandler.HaltRollout)
+
+		// 撤回与恢复接口
+		versionDetailGroup.POST("/:vkey/revoke", versionUpdate, versionDetailHandler.RevokeVersion)
+		versionDetailGroup.POST("/:vkey/restore", versionUpdate, versionDetailHandler.RestoreVersion)
+
+		// 密钥轮换接口
+		versionDetailGroup.POST("/:vkey/rotate-key", versionUpdate, versionDetailHandler.RotateVKey)
+
+		// 制品接口
+		versionDetailGroup.GET("/:vkey/artifacts", api.VersionScopeMiddleware(services.VersionService, model.RoleViewer, model.ScopeVersionRead), artifactHandler.GetArtifactList)
+		versionDetailGroup.POST("/:vkey/artifacts", versionUpdate, artifactHandler.UploadArtifact)
+		versionDetailGroup.DELETE("/:vkey/artifacts/:id", versionUpdate, artifactHandler.DeleteArtifact)
 	}
 
+	// 制品下载接口（不需要认证，凭下载地址中的签名访问）
+	apiGroup.GET("/download/:id/:filename", artifactHandler.DownloadArtifact)
+
 	// 校验接口
 	checkGroup := apiGroup.Group("/check")
-	checkHandler := api.NewCheckHandler(services.CheckService)
+	checkHandler := api.NewCheckHandler(services.CheckService, services.LicenseService, services.SignatureService, services.AnnouncementService)
 	{
-		checkGroup.POST("/validate", checkHandler.Validate)
-		checkGroup.POST("/update", checkHandler.CheckUpdate)
+		// 按客户端IP和AKey限流，防止暴力猜测VKey
+		checkLimits := []gin.HandlerFunc{
+			RateLimitMiddleware(rateLimitBackend, "check_ip", appConfig.RateLimit.CheckPerIP, clientIPKey),
+			RateLimitMiddleware(rateLimitBackend, "check_akey", appConfig.RateLimit.CheckPerAKey, api.RequestAKey),
+			// 校验请求签名，防止请求被重放
+			api.RequestSignatureMiddleware(services.RequestSigningService),
+		}
+		checkGroup.POST("/validate", append(checkLimits, checkHandler.Validate)...)
+		checkGroup.POST("/update", append(checkLimits, checkHandler.CheckUpdate)...)
+		checkGroup.POST("/activate", append(checkLimits, checkHandler.Activate)...)
+		checkGroup.POST("/announcements", append(checkLimits, checkHandler.GetAnnouncements)...)
+		checkGroup.GET("/public-key", checkHandler.GetPublicKey)
 		// 健康检查接口（不需要认证）
 		checkGroup.GET("/health", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{
@@ -113,9 +251,15 @@ func SetupRouter(services *service.Services, appConfig *config.Config, version s
 	dashboardGroup := apiGroup.Group("/dashboard")
 	dashboardHandler := api.NewDashboardHandler(services.DashboardService, services.AnnouncementService)
 	{
-		dashboardGroup.Use(api.AuthMiddleware(services.AuthService))
+		dashboardGroup.Use(authRequired, viewerOnly)
 		dashboardGroup.GET("/stats", dashboardHandler.GetDashboardData)
 		dashboardGroup.GET("/announcements", dashboardHandler.GetAnnouncements)
+
+		// 版本普及和校验失败率统计接口
+		dashboardGroup.GET("/apps/:akey/installs", dashboardHandler.GetActiveInstalls)
+		dashboardGroup.GET("/apps/:akey/adoption", dashboardHandler.GetAdoptionCurve)
+		dashboardGroup.GET("/apps/:akey/failures", dashboardHandler.GetCheckFailureStats)
+		dashboardGroup.GET("/apps/:akey/timeseries", dashboardHandler.GetTimeSeries)
 	}
 
 	// 静态文件处理（前端资源）
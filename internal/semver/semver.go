@@ -0,0 +1,138 @@
+// Package semver 提供语义化版本号的解析与比较
+// 支持的格式为 主版本号.次版本号.修订号[-预发布标识][+构建元数据]，例如 1.2.3、2.0.0-beta、1.0.0+20240101
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pattern 语义化版本号格式，预发布标识和构建元数据只能包含字母、数字和连字符，不能包含点号
+var pattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-([a-zA-Z0-9\-]+))?(?:\+([a-zA-Z0-9\-]+))?$`)
+
+// Version 解析后的语义化版本号
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease string // 预发布标识，如 beta、rc1
+	Build      string // 构建元数据，不参与版本比较
+}
+
+// Parse 解析语义化版本号
+func Parse(version string) (*Version, error) {
+	matches := pattern.FindStringSubmatch(strings.TrimSpace(version))
+	if matches == nil {
+		return nil, fmt.Errorf("无效的语义化版本号: %q", version)
+	}
+
+	numbers := make([]uint64, 3)
+	for i := range numbers {
+		n, err := strconv.ParseUint(matches[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的语义化版本号: %q", version)
+		}
+		numbers[i] = n
+	}
+
+	return &Version{
+		Major:      numbers[0],
+		Minor:      numbers[1],
+		Patch:      numbers[2],
+		PreRelease: matches[4],
+		Build:      matches[5],
+	}, nil
+}
+
+// IsValid 判断字符串是否为合法的语义化版本号
+func IsValid(version string) bool {
+	_, err := Parse(version)
+	return err == nil
+}
+
+// Compare 按语义化版本优先级比较两个版本
+// a 低于 b 返回 -1，相等返回 0，高于返回 1。构建元数据不参与比较
+func (v *Version) Compare(other *Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePreRelease(v.PreRelease, other.PreRelease)
+}
+
+// String 返回版本号的字符串形式
+func (v *Version) String() string {
+	result := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		result += "-" + v.PreRelease
+	}
+	if v.Build != "" {
+		result += "+" + v.Build
+	}
+	return result
+}
+
+// Compare 比较两个版本号字符串，任意一个无法解析时返回错误
+func Compare(a, b string) (int, error) {
+	va, err := Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// comparePreRelease 比较预发布标识
+// 没有预发布标识的版本优先级更高。版本号格式不允许预发布标识包含点号，
+// 因此标识只有一段，按 compareIdentifier 的规则比较
+func comparePreRelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	return compareIdentifier(a, b)
+}
+
+// compareIdentifier 比较预发布标识
+// 纯数字标识按数值比较且低于非数字标识，其余按ASCII顺序比较
+func compareIdentifier(a, b string) int {
+	numA, errA := strconv.ParseUint(a, 10, 64)
+	numB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(numA, numB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compareUint 比较两个无符号整数
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
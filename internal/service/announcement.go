@@ -1,28 +1,233 @@
 package service
 
 import (
+	"net/url"
+	"strings"
+	"time"
+
+	"verkeyoss/internal/errors"
 	"verkeyoss/internal/model"
+	"verkeyoss/internal/semver"
 	"verkeyoss/internal/store"
 )
 
+// 预定义错误
+var (
+	ErrAnnouncementNotFound        = errors.ErrAnnouncementNotFound
+	ErrInvalidAnnouncementTitle    = errors.NewValidationError("公告标题不能为空且不能超过100个字符")
+	ErrInvalidAnnouncementContent  = errors.NewValidationError("公告内容不能为空")
+	ErrInvalidAnnouncementURL      = errors.NewValidationError("公告链接必须是以 http:// 或 https:// 开头的完整URL，且不能超过500个字符")
+	ErrInvalidAnnouncementSchedule = errors.NewValidationError("下线时间必须晚于发布日期")
+	ErrInvalidAnnouncementApp      = errors.NewValidationError("目标应用不存在")
+	ErrInvalidAnnouncementRange    = errors.NewValidationError("目标版本范围无效，边界必须是语义化版本号，且下界不能高于上界")
+	ErrInvalidAnnouncementStatus   = errors.NewValidationError("无效的公告状态，可选值为 draft、scheduled、published、expired")
+	ErrAnnouncementKeysInvalid     = errors.NewNotFoundError("AKey或VKey无效")
+)
+
 // AnnouncementService 公告服务
-// 提供获取系统公告的功能
+// 提供公告的管理功能，以及按应用、渠道和版本筛选客户端可见的公告
 
 type AnnouncementService struct {
-	store store.AnnouncementStore
+	store        store.AnnouncementStore
+	appStore     store.AppStore
+	versionStore store.VersionStore
 }
 
 // NewAnnouncementService 创建公告服务实例
 // 参数 store 为公告存储接口的实现
-func NewAnnouncementService(store store.AnnouncementStore) *AnnouncementService {
+func NewAnnouncementService(store store.AnnouncementStore, appStore store.AppStore, versionStore store.VersionStore) *AnnouncementService {
 	return &AnnouncementService{
-		store: store,
+		store:        store,
+		appStore:     appStore,
+		versionStore: versionStore,
+	}
+}
+
+// AnnouncementInput 创建或更新公告的参数
+type AnnouncementInput struct {
+	Title       string
+	Content     string
+	URL         string
+	IsActive    bool
+	PublishDate *time.Time // 发布日期，创建时为空表示立即发布，更新时为空表示不修改
+	UnpublishAt *time.Time // 下线时间，为空表示不自动下线
+	AKeys       []string   // 目标应用，为空时面向全部应用
+	Channels    []string   // 目标发布渠道，为空时面向全部渠道
+	MinVersion  string     // 目标版本下界（含），为空表示不限
+	MaxVersion  string     // 目标版本上界（含），为空表示不限
+}
+
+// CreateAnnouncement 创建公告
+func (s *AnnouncementService) CreateAnnouncement(input *AnnouncementInput) (*model.Announcement, error) {
+	announcement := &model.Announcement{PublishDate: time.Now()}
+	if err := s.applyInput(announcement, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.CreateAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+	return announcement, nil
+}
+
+// GetAnnouncementList 获取公告列表
+func (s *AnnouncementService) GetAnnouncementList(query *model.AnnouncementQuery, page, size int) ([]*model.Announcement, int64, error) {
+	if query.Status != "" && !model.IsValidAnnouncementStatus(query.Status) {
+		return nil, 0, ErrInvalidAnnouncementStatus
+	}
+	return s.store.GetAnnouncementList(query, time.Now(), page, size)
+}
+
+// GetAnnouncement 获取公告详情
+func (s *AnnouncementService) GetAnnouncement(id uint) (*model.Announcement, error) {
+	announcement, err := s.store.GetAnnouncementByID(id)
+	if err != nil {
+		return nil, ErrAnnouncementNotFound
+	}
+	return announcement, nil
+}
+
+// UpdateAnnouncement 更新公告
+// 除发布日期外的全部字段都会被替换为新值
+func (s *AnnouncementService) UpdateAnnouncement(id uint, input *AnnouncementInput) (*model.Announcement, error) {
+	announcement, err := s.GetAnnouncement(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyInput(announcement, input); err != nil {
+		return nil, err
 	}
+
+	if err := s.store.UpdateAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+	return announcement, nil
 }
 
-// GetActiveAnnouncements 获取激活的公告列表
-// 返回所有状态为激活的公告，按发布日期降序排序
+// DeleteAnnouncement 删除公告
+func (s *AnnouncementService) DeleteAnnouncement(id uint) error {
+	if _, err := s.GetAnnouncement(id); err != nil {
+		return err
+	}
+	return s.store.DeleteAnnouncement(id)
+}
+
+// GetActiveAnnouncements 获取当前已发布的公告列表
+// 返回所有已激活、已到发布日期且未下线的公告，按发布日期降序排序
 func (s *AnnouncementService) GetActiveAnnouncements() ([]*model.Announcement, error) {
-	// 调用存储层获取激活的公告列表
-	return s.store.GetActiveAnnouncements()
+	// 调用存储层获取已发布的公告列表
+	return s.store.GetActiveAnnouncements(time.Now())
+}
+
+// GetClientAnnouncements 获取客户端可见的公告列表
+// 客户端使用AKey和VKey标识应用和版本，已撤回或宽限期内的旧密钥仍可获取公告；
+// 参数 channel 为客户端订阅的发布渠道，为空时使用稳定版渠道
+func (s *AnnouncementService) GetClientAnnouncements(akey, vkey, channel string) ([]*model.Announcement, error) {
+	if channel == "" {
+		channel = model.ChannelStable
+	}
+	if !model.IsValidChannel(channel) {
+		return nil, ErrInvalidChannel
+	}
+
+	version, status, err := s.versionStore.Validate(akey, vkey)
+	if err != nil {
+		return nil, err
+	}
+	if status == model.KeyStatusInvalid {
+		return nil, ErrAnnouncementKeysInvalid
+	}
+
+	announcements, err := s.store.GetActiveAnnouncements(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*model.Announcement, 0, len(announcements))
+	for _, announcement := range announcements {
+		if announcement.Targets(version.AKey, channel, version.Version) {
+			visible = append(visible, announcement)
+		}
+	}
+	return visible, nil
+}
+
+// applyInput 校验参数并设置公告字段
+func (s *AnnouncementService) applyInput(announcement *model.Announcement, input *AnnouncementInput) error {
+	title := strings.TrimSpace(input.Title)
+	if title == "" || len([]rune(title)) > 100 {
+		return ErrInvalidAnnouncementTitle
+	}
+	if strings.TrimSpace(input.Content) == "" {
+		return ErrInvalidAnnouncementContent
+	}
+
+	link := strings.TrimSpace(input.URL)
+	if link != "" {
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(link) > 500 {
+			return ErrInvalidAnnouncementURL
+		}
+	}
+
+	publishDate := announcement.PublishDate
+	if input.PublishDate != nil {
+		publishDate = *input.PublishDate
+	}
+	if input.UnpublishAt != nil && !input.UnpublishAt.After(publishDate) {
+		return ErrInvalidAnnouncementSchedule
+	}
+
+	akeys := uniqueItems(input.AKeys)
+	for _, akey := range akeys {
+		if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+			return ErrInvalidAnnouncementApp
+		}
+	}
+	channels := uniqueItems(input.Channels)
+	for _, channel := range channels {
+		if !model.IsValidChannel(channel) {
+			return ErrInvalidChannel
+		}
+	}
+
+	minVersion := strings.TrimSpace(input.MinVersion)
+	maxVersion := strings.TrimSpace(input.MaxVersion)
+	for _, bound := range []string{minVersion, maxVersion} {
+		if bound != "" && !semver.IsValid(bound) {
+			return ErrInvalidAnnouncementRange
+		}
+	}
+	if minVersion != "" && maxVersion != "" {
+		if c, _ := semver.Compare(minVersion, maxVersion); c > 0 {
+			return ErrInvalidAnnouncementRange
+		}
+	}
+
+	announcement.Title = title
+	announcement.Content = input.Content
+	announcement.URL = link
+	announcement.IsActive = input.IsActive
+	announcement.PublishDate = publishDate
+	announcement.UnpublishAt = input.UnpublishAt
+	announcement.AKeys = model.JoinList(akeys)
+	announcement.Channels = model.JoinList(channels)
+	announcement.MinVersion = minVersion
+	announcement.MaxVersion = maxVersion
+	return nil
+}
+
+// uniqueItems 去除列表中的空项和重复项，保持原有顺序
+func uniqueItems(items []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" && !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
 }
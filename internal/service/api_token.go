@@ -0,0 +1,133 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// APITokenPrefix API令牌的固定前缀，用于和JWT令牌区分
+const APITokenPrefix = "vko_"
+
+// 预定义错误，使用统一的错误处理
+var (
+	ErrTokenNotFound = errors.ErrTokenNotFound
+)
+
+// APITokenService API令牌服务
+// 负责创建、查询和吊销用于自动化场景的长期令牌
+
+type APITokenService struct {
+	store    store.APITokenStore
+	appStore store.AppStore
+}
+
+// NewAPITokenService 创建API令牌服务实例
+func NewAPITokenService(store store.APITokenStore, appStore store.AppStore) *APITokenService {
+	return &APITokenService{store: store, appStore: appStore}
+}
+
+// CreateToken 创建API令牌
+// 返回的明文令牌只会出现这一次，数据库中只保存其哈希值
+// 参数 expiresInDays 为0时令牌永不过期
+func (s *APITokenService) CreateToken(principal *Principal, name string, akeys, scopes []string, expiresInDays int) (string, *model.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", nil, errors.NewValidationError("令牌名称不能为空且不能超过100个字符")
+	}
+	if len(akeys) == 0 {
+		return "", nil, errors.NewValidationError("至少需要授权一个应用")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.NewValidationError("至少需要指定一个权限范围")
+	}
+	for _, scope := range scopes {
+		if !model.IsValidScope(scope) {
+			return "", nil, errors.NewValidationError("无效的权限范围: " + scope)
+		}
+	}
+	for _, akey := range akeys {
+		if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+			return "", nil, errors.NewValidationError("应用不存在: " + akey)
+		}
+	}
+	if expiresInDays < 0 {
+		return "", nil, errors.NewValidationError("有效期不能为负数")
+	}
+
+	// 生成随机令牌
+	plainToken, err := generateAPIToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := &model.APIToken{
+		UserID:    principal.UserID,
+		Name:      name,
+		TokenHash: hashAPIToken(plainToken),
+		Prefix:    plainToken[:len(APITokenPrefix)+8],
+		AKeys:     model.JoinList(akeys),
+		Scopes:    model.JoinList(scopes),
+	}
+	if expiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, expiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.store.CreateAPIToken(token); err != nil {
+		return "", nil, err
+	}
+
+	return plainToken, token, nil
+}
+
+// GetTokenList 获取API令牌列表
+// 所有者可以查看全部令牌，其他用户只能查看自己创建的令牌
+func (s *APITokenService) GetTokenList(principal *Principal, all bool) ([]*model.APIToken, error) {
+	if all && principal.HasRole(model.RoleOwner) {
+		return s.store.GetAPITokenList(0)
+	}
+	return s.store.GetAPITokenList(principal.UserID)
+}
+
+// RevokeToken 吊销API令牌
+// 所有者可以吊销任意令牌，其他用户只能吊销自己创建的令牌
+func (s *APITokenService) RevokeToken(principal *Principal, id uint) error {
+	token, err := s.store.GetAPITokenByID(id)
+	if err != nil {
+		return ErrTokenNotFound
+	}
+
+	if token.UserID != principal.UserID && !principal.HasRole(model.RoleOwner) {
+		return errors.ErrForbidden
+	}
+
+	return s.store.RevokeAPIToken(id, time.Now())
+}
+
+// IsAPIToken 判断令牌字符串是否为API令牌
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// generateAPIToken 生成带固定前缀的随机API令牌
+func generateAPIToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + hex.EncodeToString(bytes), nil
+}
+
+// hashAPIToken 计算API令牌的SHA-256哈希
+// 令牌本身为高熵随机值，无需使用慢哈希
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
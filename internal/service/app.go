@@ -14,28 +14,40 @@ var (
 
 // AppService 应用服务
 type AppService struct {
-	store store.AppStore
+	store         store.AppStore
+	keyGraceHours int // 轮换AKey后旧AKey的默认有效时长（小时）
+	audit         *AuditService
+	artifacts     *ArtifactService
 }
 
 // NewAppService 创建应用服务实例
-func NewAppService(store store.AppStore) *AppService {
-	return &AppService{store: store}
+func NewAppService(store store.AppStore, keyGraceHours int, audit *AuditService, artifacts *ArtifactService) *AppService {
+	return &AppService{store: store, keyGraceHours: keyGraceHours, audit: audit, artifacts: artifacts}
 }
 
-// CreateApp 创建新应用
-func (s *AppService) CreateApp(userId uint, name, description string, isPaid bool) (*model.App, error) {
+// CreateApp 创建新应用，创建者为执行操作的用户
+// 参数 minSupportedVersion 为空表示不限制最低支持版本
+func (s *AppService) CreateApp(actor *Actor, name, description string, isPaid bool, minSupportedVersion string) (*model.App, error) {
+	requestSecret, err := generateRequestSecret()
+	if err != nil {
+		return nil, err
+	}
+
 	app := &model.App{
-		UserID:      userId,
-		Name:        name,
-		Description: description,
-		IsPaid:      isPaid,
+		UserID:              actor.UserID,
+		Name:                name,
+		Description:         description,
+		IsPaid:              isPaid,
+		MinSupportedVersion: minSupportedVersion,
+		RequestSecret:       requestSecret,
 	}
 
-	err := s.store.CreateApp(app)
+	err = s.store.CreateApp(app)
 	if err != nil {
 		return nil, err
 	}
 
+	s.audit.Record(actor, model.AuditActionCreate, model.AuditResourceApp, app.AKey, nil, app)
 	return app, nil
 }
 
@@ -49,10 +61,22 @@ func (s *AppService) GetAppList(page, size int) ([]*model.App, int64, error) {
 		size = 10
 	}
 
-	// 在单管理员模式下，直接获取所有应用
 	return s.store.GetAppList(page, size)
 }
 
+// GetAppListByUserID 获取指定用户创建的应用列表
+func (s *AppService) GetAppListByUserID(userID uint, page, size int) ([]*model.App, int64, error) {
+	// 分页参数校验
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+
+	return s.store.GetAppListByUserID(userID, page, size)
+}
+
 // GetAppByAKey 根据AKey获取应用信息
 func (s *AppService) GetAppByAKey(akey string) (*model.App, error) {
 	app, err := s.store.GetAppByAKey(akey)
@@ -64,38 +88,67 @@ func (s *AppService) GetAppByAKey(akey string) (*model.App, error) {
 }
 
 // UpdateApp 更新应用信息
-func (s *AppService) UpdateApp(akey, name, description string, isPaid bool) error {
+// 参数 minSupportedVersion 为空表示取消最低支持版本限制
+func (s *AppService) UpdateApp(actor *Actor, akey, name, description string, isPaid bool, minSupportedVersion string) error {
 	app, err := s.store.GetAppByAKey(akey)
 	if err != nil {
 		return ErrAppNotFound
 	}
+	before := *app
 
 	// 更新应用信息
 	app.Name = name
 	app.Description = description
 	app.IsPaid = isPaid
+	app.MinSupportedVersion = minSupportedVersion
 
 	err = s.store.UpdateApp(app)
 	if err != nil {
 		return err
 	}
 
+	s.audit.Record(actor, model.AuditActionUpdate, model.AuditResourceApp, akey, &before, app)
 	return nil
 }
 
-// DeleteApp 删除应用
-func (s *AppService) DeleteApp(akey string) error {
+// RotateAKey 轮换应用的AKey
+// 旧AKey在宽限期内仍可通过校验，参数 graceHours 为nil时使用默认宽限期
+func (s *AppService) RotateAKey(actor *Actor, akey string, graceHours *int) (*KeyRotation, error) {
+	expiresAt, err := graceExpiresAt(s.keyGraceHours, graceHours)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.GetAppByAKey(akey); err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	newAKey, err := s.store.RotateAKey(akey, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, model.AuditActionRotateKey, model.AuditResourceApp, akey,
+		map[string]interface{}{"akey": akey}, map[string]interface{}{"akey": newAKey, "old_key_expires_at": expiresAt})
+	return &KeyRotation{OldKey: akey, NewKey: newAKey, OldKeyExpiresAt: expiresAt}, nil
+}
+
+// DeleteApp 删除应用及其全部版本的制品
+func (s *AppService) DeleteApp(actor *Actor, akey string) error {
 	// 检查应用是否存在
-	_, err := s.store.GetAppByAKey(akey)
+	app, err := s.store.GetAppByAKey(akey)
 	if err != nil {
 		return ErrAppNotFound
 	}
 
 	// 删除应用
+	artifacts := s.artifacts.appArtifacts(akey)
 	err = s.store.DeleteApp(akey)
 	if err != nil {
 		return err
 	}
+	s.artifacts.deleteBlobs(artifacts)
 
+	s.audit.Record(actor, model.AuditActionDelete, model.AuditResourceApp, akey, app, nil)
 	return nil
 }
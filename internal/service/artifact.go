@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"verkeyoss/internal/blob"
+	apperrors "verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// 预定义错误
+var (
+	ErrArtifactNotFound        = apperrors.ErrArtifactNotFound
+	ErrInvalidPlatform         = apperrors.NewValidationError("操作系统和架构只能包含小写字母、数字和下划线，且不能超过20个字符")
+	ErrInvalidArtifactFileName = apperrors.NewValidationError("制品文件名不能为空且不能超过255个字符")
+	ErrEmptyArtifact           = apperrors.NewValidationError("制品文件不能为空")
+	ErrInvalidArtifactDigest   = apperrors.NewValidationError("SHA-256摘要必须是64位十六进制字符串")
+	ErrArtifactDigestMismatch  = apperrors.NewValidationError("上传的文件与提供的SHA-256摘要不一致")
+	ErrInvalidArtifactDownload = apperrors.NewForbiddenError("下载链接无效或已过期")
+	ErrArtifactFileNotFound    = apperrors.NewNotFoundError("制品文件不存在")
+)
+
+const (
+	// 删除制品文件的超时时间
+	artifactDeleteTimeout = 30 * time.Second
+	// 文件名的最大长度（字节）
+	maxArtifactFileNameLength = 255
+	// 未指定文件类型且无法根据扩展名判断时使用的类型
+	defaultArtifactContentType = "application/octet-stream"
+)
+
+// ArtifactService 制品服务
+// 管理版本在各平台上的安装包或更新包：文件内容保存在制品存储中，数据库只保存元数据；
+// 检查更新时为客户端平台选择最匹配的制品并生成有时效的下载地址
+type ArtifactService struct {
+	store        store.ArtifactStore
+	versionStore store.VersionStore
+	blobs        blob.Store
+	baseURL      string
+	urlSecret    []byte
+	urlTTL       time.Duration
+	maxSize      int64
+	audit        *AuditService
+}
+
+// NewArtifactService 创建制品服务实例
+// 参数 baseURL 为服务的外部访问地址，用于生成本地存储制品的下载地址，为空时生成相对路径；
+// secret 用于派生下载地址的签名密钥，urlTTLSeconds 为下载地址的有效期，maxUploadMB 为单个制品的大小上限
+func NewArtifactService(store store.ArtifactStore, versionStore store.VersionStore, blobs blob.Store, baseURL, secret string, urlTTLSeconds, maxUploadMB int, audit *AuditService) *ArtifactService {
+	// 使用独立的派生密钥签名下载地址，避免与其他用途共用同一密钥
+	urlSecret := sha256.Sum256([]byte("verkeyoss-artifact-download:" + secret))
+	return &ArtifactService{
+		store:        store,
+		versionStore: versionStore,
+		blobs:        blobs,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		urlSecret:    urlSecret[:],
+		urlTTL:       time.Duration(urlTTLSeconds) * time.Second,
+		maxSize:      int64(maxUploadMB) << 20,
+		audit:        audit,
+	}
+}
+
+// MaxSize 返回单个制品的大小上限（字节）
+func (s *ArtifactService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// UploadArtifact 上传版本在指定平台上的制品，替换该平台的现有制品
+// 参数 osName 和 arch 为空时表示适用于全部平台；contentType 为空时根据文件扩展名判断；
+// digest 为客户端计算的SHA-256摘要，不为空时与实际内容比较，不一致时拒绝上传。
+// 先完整读取一遍内容计算摘要和大小，再写入制品存储，因此 content 必须支持重新定位
+func (s *ArtifactService) UploadArtifact(actor *Actor, vkey, osName, arch, fileName, contentType string, content io.ReadSeeker, size int64, digest string) (*model.Artifact, error) {
+	osName, arch = model.NormalizePlatform(osName), model.NormalizePlatform(arch)
+	if !model.IsValidPlatform(osName) || !model.IsValidPlatform(arch) {
+		return nil, ErrInvalidPlatform
+	}
+	fileName = sanitizeArtifactFileName(fileName)
+	if fileName == "" || len(fileName) > maxArtifactFileNameLength {
+		return nil, ErrInvalidArtifactFileName
+	}
+	if size <= 0 {
+		return nil, ErrEmptyArtifact
+	}
+	if size > s.maxSize {
+		return nil, apperrors.NewValidationError(fmt.Sprintf("制品文件不能超过 %d MB", s.maxSize>>20))
+	}
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if digest != "" && !isSHA256Hex(digest) {
+		return nil, ErrInvalidArtifactDigest
+	}
+
+	version, err := s.versionStore.GetVersionByVKey(vkey)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+
+	// 计算摘要并核对大小
+	hash := sha256.New()
+	read, err := io.Copy(hash, content)
+	if err != nil {
+		return nil, err
+	}
+	if read != size {
+		return nil, apperrors.NewValidationError("上传的文件不完整")
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if digest != "" && digest != sum {
+		return nil, ErrArtifactDigestMismatch
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fileName))
+	}
+	if contentType == "" {
+		contentType = defaultArtifactContentType
+	}
+	artifact := &model.Artifact{
+		VersionID:   version.ID,
+		OS:          osName,
+		Arch:        arch,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      sum,
+		// 对象键不包含AKey和VKey，下载地址中不会泄露密钥
+		StorageKey: uuid.New().String() + "/" + fileName,
+	}
+
+	object := blob.Object{Key: artifact.StorageKey, Size: size, SHA256: sum, ContentType: contentType}
+	if err := s.blobs.Put(context.Background(), object, content); err != nil {
+		if errors.Is(err, blob.ErrDigestMismatch) {
+			return nil, ErrArtifactDigestMismatch
+		}
+		return nil, err
+	}
+
+	previous, err := s.store.SaveArtifact(artifact)
+	if err != nil {
+		s.deleteBlobs([]*model.Artifact{artifact})
+		return nil, err
+	}
+
+	action := model.AuditActionCreate
+	if previous != nil {
+		action = model.AuditActionUpdate
+		s.deleteBlobs([]*model.Artifact{previous})
+	}
+	s.audit.Record(actor, action, model.AuditResourceArtifact, strconv.FormatUint(uint64(artifact.ID), 10), previous, artifact)
+	return artifact, nil
+}
+
+// GetArtifacts 获取版本的全部制品
+func (s *ArtifactService) GetArtifacts(vkey string) ([]*model.Artifact, error) {
+	version, err := s.versionStore.GetVersionByVKey(vkey)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	return s.store.GetArtifactsByVersionID(version.ID)
+}
+
+// DeleteArtifact 删除版本的制品及其文件
+func (s *ArtifactService) DeleteArtifact(actor *Actor, vkey string, id uint) error {
+	version, err := s.versionStore.GetVersionByVKey(vkey)
+	if err != nil {
+		return ErrVersionNotFound
+	}
+	artifact, err := s.store.GetArtifactByID(id)
+	if err != nil || artifact.VersionID != version.ID {
+		return ErrArtifactNotFound
+	}
+
+	if err := s.store.DeleteArtifact(id); err != nil {
+		return err
+	}
+	s.deleteBlobs([]*model.Artifact{artifact})
+
+	s.audit.Record(actor, model.AuditActionDelete, model.AuditResourceArtifact, strconv.FormatUint(uint64(id), 10), artifact, nil)
+	return nil
+}
+
+// Download 生成制品的下载信息
+// 制品存储支持直接下载时使用存储生成的预签名地址，否则使用服务端下载接口的签名地址
+func (s *ArtifactService) Download(artifact *model.Artifact) (*model.ArtifactDownload, error) {
+	expiresAt := time.Now().Add(s.urlTTL).Truncate(time.Second)
+	downloadURL, err := s.blobs.DownloadURL(artifact.StorageKey, s.urlTTL)
+	if err != nil {
+		return nil, err
+	}
+	if downloadURL == "" {
+		expires := expiresAt.Unix()
+		downloadURL = fmt.Sprintf("%s/api/download/%d/%s?expires=%d&signature=%s",
+			s.baseURL, artifact.ID, url.PathEscape(artifact.FileName), expires, s.downloadSignature(artifact.ID, expires))
+	}
+
+	return &model.ArtifactDownload{
+		ID:          artifact.ID,
+		OS:          artifact.OS,
+		Arch:        artifact.Arch,
+		FileName:    artifact.FileName,
+		ContentType: artifact.ContentType,
+		Size:        artifact.Size,
+		SHA256:      artifact.SHA256,
+		URL:         downloadURL,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// ResolveDownload 为客户端平台选择版本最匹配的制品并生成下载信息，没有匹配的制品时返回nil
+func (s *ArtifactService) ResolveDownload(version *model.Version, osName, arch string) (*model.ArtifactDownload, error) {
+	artifacts, err := s.store.GetArtifactsByVersionID(version.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	osName, arch = model.NormalizePlatform(osName), model.NormalizePlatform(arch)
+	var best *model.Artifact
+	bestRank := 0
+	for _, artifact := range artifacts {
+		if rank := artifact.MatchRank(osName, arch); rank > bestRank {
+			best, bestRank = artifact, rank
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return s.Download(best)
+}
+
+// OpenDownload 校验下载地址的签名和有效期后打开制品文件，调用方负责关闭返回的文件
+func (s *ArtifactService) OpenDownload(id uint, expires int64, signature string) (*model.Artifact, io.ReadCloser, error) {
+	expected := s.downloadSignature(id, expires)
+	if time.Now().Unix() > expires || !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, nil, ErrInvalidArtifactDownload
+	}
+
+	artifact, err := s.store.GetArtifactByID(id)
+	if err != nil {
+		return nil, nil, ErrArtifactNotFound
+	}
+	file, err := s.blobs.Open(context.Background(), artifact.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, ErrArtifactFileNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return artifact, file, nil
+}
+
+// downloadSignature 计算服务端下载地址的签名
+func (s *ArtifactService) downloadSignature(id uint, expires int64) string {
+	mac := hmac.New(sha256.New, s.urlSecret)
+	fmt.Fprintf(mac, "%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// versionArtifacts 获取版本的全部制品，用于删除版本后清理制品文件，查询失败时记录日志并返回nil
+func (s *ArtifactService) versionArtifacts(versionID uint) []*model.Artifact {
+	artifacts, err := s.store.GetArtifactsByVersionID(versionID)
+	if err != nil {
+		logger.Errorf("获取版本制品失败 (版本ID: %d): %v", versionID, err)
+	}
+	return artifacts
+}
+
+// appArtifacts 获取应用全部版本的制品，用于删除应用后清理制品文件，查询失败时记录日志并返回nil
+func (s *ArtifactService) appArtifacts(akey string) []*model.Artifact {
+	artifacts, err := s.store.GetArtifactsByAKey(akey)
+	if err != nil {
+		logger.Errorf("获取应用制品失败 (AKey: %s): %v", akey, err)
+	}
+	return artifacts
+}
+
+// allArtifacts 获取全部制品，用于替换导入备份后清理不再使用的制品文件，查询失败时返回错误
+func (s *ArtifactService) allArtifacts() ([]*model.Artifact, error) {
+	return s.store.GetAllArtifacts()
+}
+
+// deleteBlobs 删除制品文件，失败时只记录日志，不影响已完成的数据库操作
+func (s *ArtifactService) deleteBlobs(artifacts []*model.Artifact) {
+	for _, artifact := range artifacts {
+		ctx, cancel := context.WithTimeout(context.Background(), artifactDeleteTimeout)
+		if err := s.blobs.Delete(ctx, artifact.StorageKey); err != nil {
+			logger.Errorf("删除制品文件失败 (%s): %v", artifact.StorageKey, err)
+		}
+		cancel()
+	}
+}
+
+// sanitizeArtifactFileName 去除文件名中的路径，并将字母、数字和 .-_+() 以外的字符替换为下划线
+func sanitizeArtifactFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(".-_+()", r) {
+			return r
+		}
+		return '_'
+	}, name)
+	// 不允许以 . 开头，避免生成隐藏文件或 . 和 ..
+	return strings.TrimLeft(name, ".")
+}
+
+// isSHA256Hex 判断字符串是否为小写十六进制的SHA-256摘要
+func isSHA256Hex(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
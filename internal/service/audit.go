@@ -0,0 +1,149 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// 清理过期审计日志的间隔
+const auditPurgeInterval = 24 * time.Hour
+
+// 计算字段差异时忽略的字段，这些字段随每次保存变化或不属于资源本身
+var auditIgnoredFields = map[string]bool{
+	"ID":            true,
+	"CreatedAt":     true,
+	"UpdatedAt":     true,
+	"DeletedAt":     true,
+	"created_at":    true,
+	"last_login_at": true,
+	"version_count": true,
+	"versions":      true,
+}
+
+// Actor 执行管理操作的调用者，用于记录审计日志
+type Actor struct {
+	UserID   uint   // 用户ID，未认证时为0
+	Username string // 用户名
+	TokenID  uint   // 通过API令牌操作时的令牌ID
+	IP       string // 来源IP地址
+}
+
+// AuditChange 单个字段的变更，创建时 Before 为空，删除时 After 为空
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditService 审计日志服务
+// 由各业务服务在管理操作成功后调用，记录执行者和变更前后的字段差异；
+// 后台协程定期删除超过保留期限的审计日志
+type AuditService struct {
+	store         store.AuditStore
+	retentionDays int
+}
+
+// NewAuditService 创建审计日志服务实例并启动过期日志清理协程
+// 参数 retentionDays 为审计日志保留天数，为负数时永久保留
+func NewAuditService(store store.AuditStore, retentionDays int) *AuditService {
+	s := &AuditService{store: store, retentionDays: retentionDays}
+	if retentionDays >= 0 {
+		go s.runPurge()
+	}
+	return s
+}
+
+// Record 记录一次管理操作
+// 参数 before 和 after 为操作前后的资源，可以是模型或map，创建时 before 为nil，删除时 after 为nil；
+// 只记录发生变化的字段，不会序列化为JSON的字段（如密码）不会被记录。
+// 写入失败不影响已完成的操作，只记录错误日志
+func (s *AuditService) Record(actor *Actor, action, resourceType, resourceID string, before, after interface{}) {
+	if actor == nil {
+		actor = &Actor{}
+	}
+
+	entry := &model.AuditEntry{
+		ActorID:      actor.UserID,
+		ActorName:    actor.Username,
+		TokenID:      actor.TokenID,
+		IP:           actor.IP,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+	}
+	if changes := auditDiff(before, after); len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err == nil {
+			entry.Changes = string(data)
+		}
+	}
+
+	if err := s.store.CreateAuditEntry(entry); err != nil {
+		logger.Errorf("写入审计日志失败 (%s %s %s): %v", action, resourceType, resourceID, err)
+	}
+}
+
+// GetAuditEntryList 获取审计日志列表
+func (s *AuditService) GetAuditEntryList(query *model.AuditQuery, page, size int) ([]*model.AuditEntry, int64, error) {
+	query.ActorName = strings.TrimSpace(query.ActorName)
+	query.ResourceID = strings.TrimSpace(query.ResourceID)
+	return s.store.GetAuditEntryList(query, page, size)
+}
+
+// runPurge 定期删除超过保留期限的审计日志
+func (s *AuditService) runPurge() {
+	ticker := time.NewTicker(auditPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().AddDate(0, 0, -s.retentionDays)
+		if _, err := s.store.DeleteAuditEntriesBefore(before); err != nil {
+			logger.Errorf("清理过期的审计日志失败: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// auditDiff 计算操作前后发生变化的字段，字段名使用JSON序列化后的名称
+func auditDiff(before, after interface{}) map[string]*AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := make(map[string]*AuditChange)
+	for name, value := range beforeFields {
+		if auditIgnoredFields[name] {
+			continue
+		}
+		if afterValue, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes[name] = &AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if auditIgnoredFields[name] {
+			continue
+		}
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = &AuditChange{After: value}
+		}
+	}
+	return changes
+}
+
+// auditFields 将资源序列化为JSON后转换为字段表，资源为nil或无法序列化时返回空表
+func auditFields(resource interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if resource == nil {
+		return fields
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
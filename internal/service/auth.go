@@ -4,6 +4,10 @@ import (
 	"errors"
 	"time"
 	"verkeyoss/internal/config"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+	"verkeyoss/internal/validator"
 
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
@@ -13,56 +17,102 @@ var (
 	ErrInvalidCredentials = errors.New("用户名或密码错误")
 )
 
+// Principal 已认证的调用者身份
+// 通过API令牌认证时，UserID、Username 和 Role 为令牌创建者的信息
+type Principal struct {
+	UserID   uint
+	Username string
+	Role     string
+	Token    *model.APIToken // 通过API令牌认证时不为空
+}
+
+// HasRole 判断调用者是否拥有不低于指定角色的权限
+// API令牌只能访问显式声明了权限范围的接口，因此始终返回false
+func (p *Principal) HasRole(role string) bool {
+	if p.Token != nil {
+		return false
+	}
+	return model.RoleLevel(p.Role) >= model.RoleLevel(role)
+}
+
+// CanAccess 判断调用者是否可以对指定应用执行需要 role 角色或 scope 权限范围的操作
+// API令牌需同时拥有该权限范围、被授权访问该应用，且创建者角色不低于 role
+func (p *Principal) CanAccess(role, scope, akey string) bool {
+	if p.Token == nil {
+		return p.HasRole(role)
+	}
+	return p.Token.HasScope(scope) && p.Token.AllowsApp(akey) &&
+		model.RoleLevel(p.Role) >= model.RoleLevel(role)
+}
+
 // AuthService 管理员认证服务
-// 负责处理管理员登录、密码修改和令牌验证等功能
-// 基于本地文件存储管理员账号信息
+// 负责处理用户登录、密码修改和令牌验证等功能
+// 用户账号存储在数据库中，配置文件中的管理员账号用于初始化所有者账号
 
 type AuthService struct {
-	jwtSecret  []byte
-	expireTime time.Duration
+	userStore    store.UserStore
+	tokenStore   store.APITokenStore
+	jwtSecret    []byte
+	expireTime   time.Duration
+	loginLockout *LoginLockout
+	audit        *AuditService
 }
 
 // NewAuthService 创建认证服务实例
+// 参数 userStore 用户存储接口的实现
+// 参数 tokenStore API令牌存储接口的实现
 // 参数 jwtSecret JWT令牌的密钥
 // 参数 expireHours 令牌有效期（小时）
-func NewAuthService(jwtSecret string, expireHours int) *AuthService {
+// 参数 loginLockout 登录失败锁定策略
+// 参数 audit 审计日志服务
+func NewAuthService(userStore store.UserStore, tokenStore store.APITokenStore, jwtSecret string, expireHours int, loginLockout *LoginLockout, audit *AuditService) *AuthService {
 	return &AuthService{
-		jwtSecret:  []byte(jwtSecret),
-		expireTime: time.Duration(expireHours) * time.Hour,
+		userStore:    userStore,
+		tokenStore:   tokenStore,
+		jwtSecret:    []byte(jwtSecret),
+		expireTime:   time.Duration(expireHours) * time.Hour,
+		loginLockout: loginLockout,
+		audit:        audit,
 	}
 }
 
-// Login 管理员登录
+// Login 用户登录
 // 参数 username 用户名
 // 参数 password 密码
-// 返回 token JWT令牌（包含admin:true声明，用于验证管理员身份）
+// 参数 ip 客户端IP地址，用于记录审计日志
+// 返回 token JWT令牌（包含admin:true声明和用户ID）
 // 返回 expiresAt 过期时间
-// 返回 error 错误信息
-func (s *AuthService) Login(username, password string) (string, string, error) {
-	// 获取管理员配置
-	adminConfig, err := config.GetAdminConfig()
-	if err != nil {
-		return "", "", errors.New("获取管理员配置失败")
+// 返回 user 登录的用户信息
+// 返回 error 错误信息，连续失败次数过多被锁定时返回请求过于频繁错误
+func (s *AuthService) Login(username, password, ip string) (string, string, *model.User, error) {
+	// 锁定期间不再校验密码
+	if err := s.loginLockout.Check(username); err != nil {
+		return "", "", nil, err
 	}
 
-	// 验证用户名
-	if username != adminConfig.Username {
-		return "", "", ErrInvalidCredentials
+	// 获取用户信息，用户名不存在时同样计入失败次数，避免泄露用户是否存在
+	user, err := s.userStore.GetUserByUsername(username)
+	if err != nil || !user.IsActive {
+		return "", "", nil, s.loginFailed(username, ip)
 	}
 
 	// 验证密码
-	if pwErr := bcrypt.CompareHashAndPassword([]byte(adminConfig.Password), []byte(password)); pwErr != nil {
-		return "", "", ErrInvalidCredentials
+	if pwErr := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); pwErr != nil {
+		return "", "", nil, s.loginFailed(username, ip)
 	}
+	s.loginLockout.Reset(username)
 
 	// 生成JWT令牌
-	expirationTime := time.Now().Add(s.expireTime)
+	now := time.Now()
+	expirationTime := now.Add(s.expireTime)
 
 	// 创建声明
 	claims := &jwt.MapClaims{
-		"admin": true,
-		"exp":   expirationTime.Unix(),
-		"iat":   time.Now().Unix(),
+		"admin":    true,
+		"uid":      user.ID,
+		"username": user.Username,
+		"exp":      expirationTime.Unix(),
+		"iat":      now.Unix(),
 	}
 
 	// 创建token对象
@@ -71,45 +121,109 @@ func (s *AuthService) Login(username, password string) (string, string, error) {
 	// 签名并获取完整的编码后的字符串token
 	tokenString, err := token.SignedString(s.jwtSecret)
 	if err != nil {
-		return "", "", errors.New("生成令牌失败")
+		return "", "", nil, errors.New("生成令牌失败")
 	}
 
+	// 记录登录时间，失败不影响登录
+	_ = s.userStore.UpdateLastLogin(user.ID, now)
+	user.LastLoginAt = &now
+	s.audit.Record(&Actor{UserID: user.ID, Username: user.Username, IP: ip}, model.AuditActionLogin, model.AuditResourceUser, userResourceID(user.ID), nil, nil)
+
 	// 格式化过期时间
 	expiresAt := expirationTime.Format(time.RFC3339)
 
-	// 返回token和过期时间
-	return tokenString, expiresAt, nil
+	// 返回token、过期时间和用户信息
+	return tokenString, expiresAt, user, nil
 }
 
-// ChangePassword 修改管理员密码
+// loginFailed 记录登录失败，本次失败触发锁定时返回锁定错误，否则返回用户名或密码错误
+func (s *AuthService) loginFailed(username, ip string) error {
+	s.audit.Record(&Actor{Username: username, IP: ip}, model.AuditActionLoginFailed, model.AuditResourceUser, "", nil, nil)
+	if err := s.loginLockout.RecordFailure(username); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// ChangePassword 修改当前用户密码
+// 参数 actor 当前用户
 // 参数 oldPassword 旧密码
 // 参数 newPassword 新密码
 // 返回 error 错误信息
-func (s *AuthService) ChangePassword(oldPassword, newPassword string) error {
-	// 获取管理员配置
-	adminConfig, err := config.GetAdminConfig()
+// 新密码需满足强度要求；若修改的是配置文件中的管理员账号，会同步写回配置文件
+func (s *AuthService) ChangePassword(actor *Actor, oldPassword, newPassword string) error {
+	// 获取用户信息
+	user, err := s.userStore.GetUserByID(actor.UserID)
 	if err != nil {
-		return errors.New("获取管理员配置失败")
+		return ErrInvalidCredentials
 	}
 
 	// 验证旧密码
-	if err := bcrypt.CompareHashAndPassword([]byte(adminConfig.Password), []byte(oldPassword)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
 		return ErrInvalidCredentials
 	}
 
-	// 更新密码
-	return config.UpdateAdminPassword(newPassword)
+	// 校验新密码强度
+	if err := validator.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := model.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("加密新密码失败")
+	}
+
+	// 先同步配置文件，写入失败时不修改数据库
+	restoreConfig, err := syncAdminPassword(user.Username, newPassword)
+	if err != nil {
+		return err
+	}
+
+	// 更新数据库中的密码
+	user.Password = hashedPassword
+	if err := s.userStore.UpdateUser(user); err != nil {
+		restoreConfig()
+		return err
+	}
+	s.audit.Record(actor, model.AuditActionPasswordChange, model.AuditResourceUser, userResourceID(user.ID), nil, nil)
+
+	return nil
 }
 
-// VerifyToken 验证管理员令牌
-// 参数 tokenString JWT令牌字符串
-// 返回 bool 是否有效
+// syncAdminPassword 修改的是配置文件中的管理员账号时，将新密码写回配置文件
+// 配置文件中的管理员账号用于初始化所有者账号，需要与数据库保持一致。
+// 返回的函数用于在数据库更新失败时恢复配置文件中的原密码，恢复失败时只记录日志
+func syncAdminPassword(username, password string) (func(), error) {
+	adminConfig, err := config.GetAdminConfig()
+	if err != nil || adminConfig.Username != username {
+		return func() {}, nil
+	}
+
+	previous := adminConfig.Password
+	if err := config.UpdateAdminPassword(password); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := config.SetAdminPasswordHash(previous); err != nil {
+			logger.Errorf("恢复配置文件中的管理员密码失败: %v", err)
+		}
+	}, nil
+}
+
+// VerifyToken 验证用户令牌
+// 参数 tokenString JWT令牌或API令牌字符串
+// 返回 *Principal 令牌对应的调用者身份
 // 返回 error 错误信息
-// 验证内容包括令牌签名、过期时间和admin:true声明
-func (s *AuthService) VerifyToken(tokenString string) (bool, error) {
+// JWT令牌的验证内容包括签名、过期时间、admin:true声明以及用户是否存在且处于启用状态
+func (s *AuthService) VerifyToken(tokenString string) (*Principal, error) {
 	// 检查token是否为空
 	if tokenString == "" {
-		return false, errors.New("令牌为空")
+		return nil, errors.New("令牌为空")
+	}
+
+	// API令牌使用单独的验证逻辑
+	if IsAPIToken(tokenString) {
+		return s.verifyAPIToken(tokenString)
 	}
 
 	// 解析token
@@ -122,18 +236,66 @@ func (s *AuthService) VerifyToken(tokenString string) (bool, error) {
 	})
 
 	if err != nil {
-		return false, errors.New("令牌无效或已过期")
+		return nil, errors.New("令牌无效或已过期")
 	}
 
 	// 验证token是否有效并检查是否为管理员
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 检查是否包含管理员标识
-		adminClaim, hasAdminClaim := claims["admin"].(bool)
-		if !hasAdminClaim || !adminClaim {
-			return false, errors.New("无效的管理员令牌")
-		}
-		return true, nil
-	} else {
-		return false, errors.New("令牌无效或已过期")
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("令牌无效或已过期")
+	}
+
+	// 检查是否包含管理员标识
+	adminClaim, hasAdminClaim := claims["admin"].(bool)
+	if !hasAdminClaim || !adminClaim {
+		return nil, errors.New("无效的管理员令牌")
+	}
+
+	// 检查用户是否存在且处于启用状态，角色以数据库中的最新值为准
+	uid, hasUID := claims["uid"].(float64)
+	if !hasUID {
+		return nil, errors.New("令牌无效或已过期")
 	}
+	user, err := s.userStore.GetUserByID(uint(uid))
+	if err != nil || !user.IsActive {
+		return nil, errors.New("用户不存在或已被禁用")
+	}
+
+	return &Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}, nil
+}
+
+// verifyAPIToken 验证API令牌
+// 令牌需未吊销、未过期，且创建者仍处于启用状态
+func (s *AuthService) verifyAPIToken(tokenString string) (*Principal, error) {
+	token, err := s.tokenStore.GetAPITokenByHash(hashAPIToken(tokenString))
+	if err != nil {
+		return nil, errors.New("令牌无效或已过期")
+	}
+
+	now := time.Now()
+	if !token.IsUsable(now) {
+		return nil, errors.New("令牌已吊销或已过期")
+	}
+
+	user, err := s.userStore.GetUserByID(token.UserID)
+	if err != nil || !user.IsActive {
+		return nil, errors.New("令牌创建者不存在或已被禁用")
+	}
+
+	// 记录最近使用时间，同一分钟内不重复写入，失败不影响认证
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= time.Minute {
+		_ = s.tokenStore.UpdateLastUsed(token.ID, now)
+		token.LastUsedAt = &now
+	}
+
+	return &Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Token:    token,
+	}, nil
 }
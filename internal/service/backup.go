@@ -0,0 +1,491 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	apperrors "verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// BackupFormatVersion 当前的备份文件格式版本，格式发生不兼容的变化时递增
+const BackupFormatVersion = 1
+
+// 备份文件格式
+const (
+	BackupFormatJSON  = "json"   // JSON文件
+	BackupFormatTarGz = "tar.gz" // 包含 backup.json 的 tar.gz 归档
+)
+
+// backupArchiveEntry tar.gz 归档中备份数据的文件名
+const backupArchiveEntry = "backup.json"
+
+// backupChecksumPrefix 校验和的算法前缀
+const backupChecksumPrefix = "sha256:"
+
+var (
+	ErrInvalidBackupFormat = apperrors.NewValidationError("无效的备份格式，可选值为 json、tar.gz")
+	ErrInvalidBackupMode   = apperrors.NewValidationError("无效的导入模式，可选值为 merge、replace")
+)
+
+// Backup 备份文件内容
+// Checksum 为 Data 紧凑JSON编码的SHA-256摘要，导入时重新计算以检查文件是否在传输或存储中损坏。
+// 校验和不带密钥，任何人都可以修改内容后重新计算，因此不能防止备份被有意篡改，备份文件需要妥善保管
+type Backup struct {
+	FormatVersion int        `json:"format_version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Checksum      string     `json:"checksum"`
+	Data          BackupData `json:"data"`
+}
+
+// BackupData 备份的数据
+type BackupData struct {
+	Apps          []*BackupApp          `json:"apps"`
+	Versions      []*BackupVersion      `json:"versions"`
+	Announcements []*BackupAnnouncement `json:"announcements"`
+}
+
+// BackupApp 备份中的应用，包含校验请求签名密钥
+type BackupApp struct {
+	AKey                  string    `json:"akey"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description"`
+	IsPaid                bool      `json:"is_paid"`
+	MinSupportedVersion   string    `json:"min_supported_version"`
+	RequestSecret         string    `json:"request_secret"`
+	RequireSignedRequests bool      `json:"require_signed_requests"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// BackupVersion 备份中的版本
+type BackupVersion struct {
+	VKey           string     `json:"vkey"`
+	AKey           string     `json:"akey"`
+	Version        string     `json:"version"`
+	Channel        string     `json:"channel"`
+	Description    string     `json:"description"`
+	IsLatest       bool       `json:"is_latest"`
+	IsForcedUpdate bool       `json:"is_forced_update"`
+	RolloutPercent int        `json:"rollout_percent"`
+	RolloutStatus  string     `json:"rollout_status"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	RevokeReason   string     `json:"revoke_reason"`
+	CreatedAt      time.Time  `json:"created_at"`
+	// 版本的制品，没有制品时省略，使不包含制品的旧备份文件校验和保持不变
+	Artifacts []*BackupArtifact `json:"artifacts,omitempty"`
+}
+
+// BackupArtifact 备份中的制品元数据，制品文件保留在制品存储中，不包含在备份内
+type BackupArtifact struct {
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"storage_key"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BackupAnnouncement 备份中的公告
+type BackupAnnouncement struct {
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	IsActive    bool       `json:"is_active"`
+	PublishDate time.Time  `json:"publish_date"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+	URL         string     `json:"url"`
+	AKeys       []string   `json:"akeys"`
+	Channels    []string   `json:"channels"`
+	MinVersion  string     `json:"min_version"`
+	MaxVersion  string     `json:"max_version"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// BackupService 备份服务
+// 导出和导入全部应用（包括AKey和签名密钥）、版本（包括VKey和制品元数据）和公告，用于迁移或恢复实例
+type BackupService struct {
+	store     store.BackupStore
+	audit     *AuditService
+	artifacts *ArtifactService
+}
+
+// NewBackupService 创建备份服务实例
+func NewBackupService(store store.BackupStore, audit *AuditService, artifacts *ArtifactService) *BackupService {
+	return &BackupService{store: store, audit: audit, artifacts: artifacts}
+}
+
+// Export 导出全部应用、版本和公告
+func (s *BackupService) Export(actor *Actor) (*Backup, error) {
+	apps, versions, announcements, err := s.store.ExportBackup()
+	if err != nil {
+		return nil, err
+	}
+
+	data := BackupData{
+		Apps:          make([]*BackupApp, 0, len(apps)),
+		Versions:      make([]*BackupVersion, 0, len(versions)),
+		Announcements: make([]*BackupAnnouncement, 0, len(announcements)),
+	}
+	for _, app := range apps {
+		data.Apps = append(data.Apps, &BackupApp{
+			AKey:                  app.AKey,
+			Name:                  app.Name,
+			Description:           app.Description,
+			IsPaid:                app.IsPaid,
+			MinSupportedVersion:   app.MinSupportedVersion,
+			RequestSecret:         app.RequestSecret,
+			RequireSignedRequests: app.RequireSignedRequests,
+			CreatedAt:             app.CreatedAt,
+		})
+	}
+	for _, version := range versions {
+		item := &BackupVersion{
+			VKey:           version.VKey,
+			AKey:           version.AKey,
+			Version:        version.Version,
+			Channel:        version.Channel,
+			Description:    version.Description,
+			IsLatest:       version.IsLatest,
+			IsForcedUpdate: version.IsForcedUpdate,
+			RolloutPercent: version.RolloutPercent,
+			RolloutStatus:  version.RolloutStatus,
+			RevokedAt:      version.RevokedAt,
+			RevokeReason:   version.RevokeReason,
+			CreatedAt:      version.CreatedAt,
+		}
+		for _, artifact := range version.Artifacts {
+			item.Artifacts = append(item.Artifacts, &BackupArtifact{
+				OS:          artifact.OS,
+				Arch:        artifact.Arch,
+				FileName:    artifact.FileName,
+				ContentType: artifact.ContentType,
+				Size:        artifact.Size,
+				SHA256:      artifact.SHA256,
+				StorageKey:  artifact.StorageKey,
+				CreatedAt:   artifact.CreatedAt,
+			})
+		}
+		data.Versions = append(data.Versions, item)
+	}
+	for _, announcement := range announcements {
+		data.Announcements = append(data.Announcements, &BackupAnnouncement{
+			Title:       announcement.Title,
+			Content:     announcement.Content,
+			IsActive:    announcement.IsActive,
+			PublishDate: announcement.PublishDate,
+			UnpublishAt: announcement.UnpublishAt,
+			URL:         announcement.URL,
+			AKeys:       announcement.AKeyList(),
+			Channels:    announcement.ChannelList(),
+			MinVersion:  announcement.MinVersion,
+			MaxVersion:  announcement.MaxVersion,
+			CreatedAt:   announcement.CreatedAt,
+		})
+	}
+
+	checksum, err := backupChecksum(&data)
+	if err != nil {
+		return nil, err
+	}
+	backup := &Backup{
+		FormatVersion: BackupFormatVersion,
+		CreatedAt:     time.Now(),
+		Checksum:      checksum,
+		Data:          data,
+	}
+
+	s.audit.Record(actor, model.AuditActionExport, model.AuditResourceBackup, "", nil, backupCounts(&data))
+	return backup, nil
+}
+
+// Import 导入备份
+// 参数 mode 为 merge 或 replace，dryRun 为 true 时只返回导入结果而不保存。
+// 导入的应用创建者为执行操作的用户；制品只导入元数据，制品文件需要已存在于当前的制品存储中。
+// 替换导入后删除不再被任何制品引用的制品文件
+func (s *BackupService) Import(actor *Actor, backup *Backup, mode string, dryRun bool) (*model.BackupImportResult, error) {
+	if mode == "" {
+		mode = model.BackupModeMerge
+	}
+	if !model.IsValidBackupMode(mode) {
+		return nil, ErrInvalidBackupMode
+	}
+	if err := validateBackupData(&backup.Data); err != nil {
+		return nil, err
+	}
+
+	apps := make([]*model.App, 0, len(backup.Data.Apps))
+	for _, app := range backup.Data.Apps {
+		apps = append(apps, &model.App{
+			UserID:                actor.UserID,
+			AKey:                  app.AKey,
+			Name:                  app.Name,
+			Description:           app.Description,
+			IsPaid:                app.IsPaid,
+			MinSupportedVersion:   app.MinSupportedVersion,
+			RequestSecret:         app.RequestSecret,
+			RequireSignedRequests: app.RequireSignedRequests,
+			CreatedAt:             app.CreatedAt,
+		})
+	}
+	versions := make([]*model.Version, 0, len(backup.Data.Versions))
+	for _, version := range backup.Data.Versions {
+		item := &model.Version{
+			VKey:           version.VKey,
+			AKey:           version.AKey,
+			Version:        version.Version,
+			Channel:        version.Channel,
+			Description:    version.Description,
+			IsLatest:       version.IsLatest,
+			IsForcedUpdate: version.IsForcedUpdate,
+			RolloutPercent: version.RolloutPercent,
+			RolloutStatus:  version.RolloutStatus,
+			RevokedAt:      version.RevokedAt,
+			RevokeReason:   version.RevokeReason,
+			CreatedAt:      version.CreatedAt,
+		}
+		for _, artifact := range version.Artifacts {
+			item.Artifacts = append(item.Artifacts, &model.Artifact{
+				OS:          artifact.OS,
+				Arch:        artifact.Arch,
+				FileName:    artifact.FileName,
+				ContentType: artifact.ContentType,
+				Size:        artifact.Size,
+				SHA256:      artifact.SHA256,
+				StorageKey:  artifact.StorageKey,
+				CreatedAt:   artifact.CreatedAt,
+			})
+		}
+		versions = append(versions, item)
+	}
+	announcements := make([]*model.Announcement, 0, len(backup.Data.Announcements))
+	for _, announcement := range backup.Data.Announcements {
+		item := &model.Announcement{
+			Title:       announcement.Title,
+			Content:     announcement.Content,
+			IsActive:    announcement.IsActive,
+			PublishDate: announcement.PublishDate,
+			UnpublishAt: announcement.UnpublishAt,
+			URL:         announcement.URL,
+			AKeys:       model.JoinList(announcement.AKeys),
+			Channels:    model.JoinList(announcement.Channels),
+			MinVersion:  announcement.MinVersion,
+			MaxVersion:  announcement.MaxVersion,
+		}
+		item.CreatedAt = announcement.CreatedAt
+		announcements = append(announcements, item)
+	}
+
+	// 替换导入会删除现有制品记录，导入前记录现有制品，导入后清理不再使用的制品文件
+	var previousArtifacts []*model.Artifact
+	if mode == model.BackupModeReplace && !dryRun {
+		artifacts, err := s.artifacts.allArtifacts()
+		if err != nil {
+			return nil, err
+		}
+		previousArtifacts = artifacts
+	}
+
+	result, err := s.store.ImportBackup(apps, versions, announcements, mode, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if len(previousArtifacts) > 0 {
+		s.removeUnusedArtifactFiles(previousArtifacts)
+	}
+
+	if !dryRun {
+		s.audit.Record(actor, model.AuditActionImport, model.AuditResourceBackup, "", nil, map[string]interface{}{
+			"mode":                           result.Mode,
+			model.BackupResourceApp:          result.Apps,
+			model.BackupResourceVersion:      result.Versions,
+			model.BackupResourceAnnouncement: result.Announcements,
+			"conflicts":                      len(result.Conflicts),
+		})
+	}
+	return result, nil
+}
+
+// removeUnusedArtifactFiles 删除导入前存在、导入后不再被任何制品引用的制品文件
+func (s *BackupService) removeUnusedArtifactFiles(previous []*model.Artifact) {
+	current, err := s.artifacts.allArtifacts()
+	if err != nil {
+		logger.Errorf("获取制品列表失败，跳过清理制品文件: %v", err)
+		return
+	}
+	used := make(map[string]bool, len(current))
+	for _, artifact := range current {
+		used[artifact.StorageKey] = true
+	}
+
+	var unused []*model.Artifact
+	for _, artifact := range previous {
+		if !used[artifact.StorageKey] {
+			unused = append(unused, artifact)
+		}
+	}
+	s.artifacts.deleteBlobs(unused)
+}
+
+// WriteBackup 按指定格式写入备份文件
+func WriteBackup(w io.Writer, backup *Backup, format string) error {
+	content, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case BackupFormatJSON:
+		_, err := w.Write(content)
+		return err
+	case BackupFormatTarGz:
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		header := &tar.Header{
+			Name:    backupArchiveEntry,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: backup.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	return ErrInvalidBackupFormat
+}
+
+// ReadBackup 读取备份文件，根据文件头自动识别JSON或tar.gz格式
+// 格式版本不受支持或校验和不一致时返回错误
+func ReadBackup(r io.Reader) (*Backup, error) {
+	reader := bufio.NewReader(r)
+	content, err := readBackupContent(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var backup Backup
+	if err := json.Unmarshal(content, &backup); err != nil {
+		return nil, apperrors.NewValidationError("备份文件格式错误: " + err.Error())
+	}
+	if backup.FormatVersion <= 0 {
+		return nil, apperrors.NewValidationError("不是有效的备份文件：缺少格式版本")
+	}
+	if backup.FormatVersion > BackupFormatVersion {
+		return nil, apperrors.NewValidationError(fmt.Sprintf("备份文件格式版本 %d 高于当前支持的版本 %d，请升级后再导入", backup.FormatVersion, BackupFormatVersion))
+	}
+
+	checksum, err := backupChecksum(&backup.Data)
+	if err != nil {
+		return nil, err
+	}
+	if backup.Checksum != checksum {
+		return nil, apperrors.NewValidationError("备份文件校验和不一致，文件可能已损坏")
+	}
+	return &backup, nil
+}
+
+// readBackupContent 读取备份的JSON内容，gzip压缩的文件从 tar 归档中读取 backup.json
+func readBackupContent(reader *bufio.Reader) ([]byte, error) {
+	header, err := reader.Peek(2)
+	if err != nil && len(header) == 0 {
+		return nil, apperrors.NewValidationError("备份文件为空")
+	}
+	if !bytes.Equal(header, []byte{0x1f, 0x8b}) {
+		return io.ReadAll(reader)
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, apperrors.NewValidationError("备份归档格式错误: " + err.Error())
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		entry, err := tr.Next()
+		if err == io.EOF {
+			return nil, apperrors.NewValidationError("备份归档中缺少 " + backupArchiveEntry)
+		}
+		if err != nil {
+			return nil, apperrors.NewValidationError("备份归档格式错误: " + err.Error())
+		}
+		if entry.Name == backupArchiveEntry {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// backupChecksum 计算备份数据的校验和
+func backupChecksum(data *BackupData) (string, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return backupChecksumPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// backupCounts 返回备份中各类记录的数量，用于审计日志
+// 审计日志计算差异时忽略 versions 字段，因此按记录类型命名
+func backupCounts(data *BackupData) map[string]interface{} {
+	return map[string]interface{}{
+		model.BackupResourceApp:          len(data.Apps),
+		model.BackupResourceVersion:      len(data.Versions),
+		model.BackupResourceAnnouncement: len(data.Announcements),
+	}
+}
+
+// validateBackupData 检查备份记录的必填字段，校验和一致但内容不完整的备份整体拒绝导入
+func validateBackupData(data *BackupData) error {
+	for i, app := range data.Apps {
+		if app == nil || app.AKey == "" || app.Name == "" {
+			return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：第 %d 个应用缺少AKey或名称", i+1))
+		}
+	}
+	for i, version := range data.Versions {
+		if version == nil || version.VKey == "" || version.AKey == "" || version.Version == "" {
+			return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：第 %d 个版本缺少VKey、AKey或版本号", i+1))
+		}
+		if !model.IsValidChannel(version.Channel) {
+			return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：版本 %s 的发布渠道 %q 无效", version.VKey, version.Channel))
+		}
+		if version.RolloutPercent < 1 || version.RolloutPercent > 100 {
+			return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：版本 %s 的灰度发布比例无效", version.VKey))
+		}
+		platforms := make(map[string]bool, len(version.Artifacts))
+		for _, artifact := range version.Artifacts {
+			if artifact == nil || !model.IsValidPlatform(artifact.OS) || !model.IsValidPlatform(artifact.Arch) ||
+				artifact.FileName == "" || artifact.StorageKey == "" || !isSHA256Hex(artifact.SHA256) {
+				return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：版本 %s 的制品缺少平台、文件名、对象键或SHA-256摘要", version.VKey))
+			}
+			platform := artifact.OS + "/" + artifact.Arch
+			if platforms[platform] {
+				return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：版本 %s 有多个 %s 平台的制品", version.VKey, platform))
+			}
+			platforms[platform] = true
+		}
+	}
+	for i, announcement := range data.Announcements {
+		if announcement == nil || announcement.Title == "" || announcement.Content == "" {
+			return apperrors.NewValidationError(fmt.Sprintf("备份数据无效：第 %d 个公告缺少标题或内容", i+1))
+		}
+	}
+	return nil
+}
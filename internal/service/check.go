@@ -1,6 +1,7 @@
 package service
 
 import (
+	"verkeyoss/internal/logger"
 	"verkeyoss/internal/model"
 	"verkeyoss/internal/store"
 )
@@ -8,90 +9,240 @@ import (
 // CheckService 校验服务
 
 type CheckService struct {
-	versionStore store.VersionStore
-	appStore     store.AppStore
+	versionStore     store.VersionStore
+	appStore         store.AppStore
+	forcedRangeStore store.ForcedUpdateRangeStore
+	licenseService   *LicenseService
+	deviceService    *DeviceService
+	telemetry        *TelemetryService
+	artifacts        *ArtifactService
 }
 
 // NewCheckService 创建校验服务实例
-func NewCheckService(versionStore store.VersionStore, appStore store.AppStore) *CheckService {
-	return &CheckService{versionStore: versionStore, appStore: appStore}
+func NewCheckService(versionStore store.VersionStore, appStore store.AppStore, forcedRangeStore store.ForcedUpdateRangeStore, licenseService *LicenseService, deviceService *DeviceService, telemetry *TelemetryService, artifacts *ArtifactService) *CheckService {
+	return &CheckService{versionStore: versionStore, appStore: appStore, forcedRangeStore: forcedRangeStore, licenseService: licenseService, deviceService: deviceService, telemetry: telemetry, artifacts: artifacts}
 }
 
 // Validate 校验AKey和VKey的合法性
-func (s *CheckService) Validate(akey, vkey string) (*model.ValidationResponse, error) {
+// VKey已被撤回时返回 revoked 状态及撤回原因，与不存在的VKey区分开；
+// 付费应用同时返回许可证在当前设备上的校验结果。
+// 请求携带设备标识时登记设备，设备已被禁用时返回 blocked 状态
+func (s *CheckService) Validate(request *model.CheckRequest) (*model.ValidationResponse, error) {
+	response, version, err := s.validate(request)
+	if err == nil {
+		s.recordCheck(model.CheckEndpointValidate, request, version, response.Status)
+	}
+	return response, err
+}
+
+// validate 校验AKey和VKey的合法性，同时返回请求对应的版本，AKey或VKey无效时版本为nil
+func (s *CheckService) validate(request *model.CheckRequest) (*model.ValidationResponse, *model.Version, error) {
+	akey, vkey := request.AKey, request.VKey
+
 	// 校验AKey和VKey是否存在对应关系
-	legal, err := s.versionStore.Validate(akey, vkey)
+	version, status, err := s.versionStore.Validate(akey, vkey)
 	if err != nil {
 		return &model.ValidationResponse{
 			Valid:   false,
+			Status:  model.KeyStatusInvalid,
 			Message: "校验失败",
-		}, err
+		}, nil, err
 	}
 
-	if legal {
-		// 查询版本信息以获取版本号
-		version, err := s.versionStore.GetVersionByVKey(vkey)
+	// 登记设备，只有AKey和VKey合法时才登记，避免伪造的请求写入设备记录
+	if status != model.KeyStatusInvalid {
+		device, err := s.deviceService.RegisterDevice(version, request.DeviceID, request.ClientIP)
 		if err != nil {
 			return &model.ValidationResponse{
-				Valid:   true,
-				Message: "校验成功",
-			}, nil
+				Valid:   false,
+				Status:  model.KeyStatusInvalid,
+				Message: "校验失败",
+			}, nil, err
 		}
-
-		// 查询应用信息以获取应用名
-		app, err := s.appStore.GetAppByAKey(akey)
-		if err != nil {
+		if device != nil && device.IsBlocked() {
 			return &model.ValidationResponse{
-				Valid:   true,
-				Message: "校验成功",
-			}, nil
+				Valid:       false,
+				Status:      model.KeyStatusBlocked,
+				Message:     "设备已被禁用",
+				BlockReason: device.BlockReason,
+			}, version, nil
 		}
+	}
 
+	switch status {
+	case model.KeyStatusRevoked:
 		return &model.ValidationResponse{
-			Valid:   true,
-			Message: "校验成功",
-			AppName: app.Name,
-			Version: version.Version,
-		}, nil
+			Valid:        false,
+			Status:       model.KeyStatusRevoked,
+			Message:      "版本已撤回",
+			Version:      version.Version,
+			RevokeReason: version.RevokeReason,
+			RevokedAt:    version.RevokedAt,
+		}, version, nil
+	case model.KeyStatusValid:
+		response := &model.ValidationResponse{
+			Valid:      true,
+			Status:     model.KeyStatusValid,
+			Message:    "校验成功",
+			Version:    version.Version,
+			KeyRotated: version.AKey != akey || version.VKey != vkey,
+		}
+
+		// 查询应用信息以获取应用名，付费应用校验许可证
+		if app, err := s.appStore.GetAppByAKey(version.AKey); err == nil {
+			response.AppName = app.Name
+			if app.IsPaid {
+				response.License = s.licenseService.Check(app.AKey, request.LicenseKey, request.DeviceID)
+			}
+		}
+		return response, version, nil
 	}
 
 	return &model.ValidationResponse{
 		Valid:   false,
+		Status:  model.KeyStatusInvalid,
 		Message: "校验失败",
-	}, nil
+	}, nil, nil
 }
 
 // CheckUpdate 检查是否有新版本
-func (s *CheckService) CheckUpdate(akey, vkey string) (map[string]interface{}, error) {
-	// 首先检查AKey和VKey的合法性
-	legal, err := s.versionStore.Validate(akey, vkey)
-	if err != nil || !legal {
+// 只有存在按语义化版本优先级严格高于当前版本的最新版本时，has_update 才为 true。
+// 订阅测试版等渠道的客户端也会收到更新的稳定版；处于灰度发布中的版本只推送给被覆盖的客户端。
+// 响应中的 force_update 给出是否必须更新及原因，当前版本已撤回或停止支持但暂无可用新版本时也会返回必须更新。
+// 请求携带设备标识时登记设备，设备已被禁用时 blocked 为 true 且不返回版本信息。
+// 新版本有与客户端平台匹配的制品时，artifact 返回其下载地址、大小和SHA-256摘要
+func (s *CheckService) CheckUpdate(request *model.CheckRequest) (map[string]interface{}, error) {
+	result, currentVersion, err := s.checkUpdate(request)
+	if err == nil {
+		status := model.KeyStatusInvalid
+		if blocked, _ := result["blocked"].(bool); blocked {
+			status = model.KeyStatusBlocked
+		} else if currentVersion != nil && currentVersion.IsRevoked() {
+			status = model.KeyStatusRevoked
+		} else if currentVersion != nil {
+			status = model.KeyStatusValid
+		}
+		s.recordCheck(model.CheckEndpointUpdate, request, currentVersion, status)
+	}
+	return result, err
+}
+
+// checkUpdate 检查是否有新版本，同时返回当前版本，AKey或VKey无效时当前版本为nil
+func (s *CheckService) checkUpdate(request *model.CheckRequest) (map[string]interface{}, *model.Version, error) {
+	akey, vkey := request.AKey, request.VKey
+
+	// 首先检查AKey和VKey的合法性，已撤回的版本仍然可以检查更新，并被要求强制更新
+	currentVersion, status, err := s.versionStore.Validate(akey, vkey)
+	if err != nil || status == model.KeyStatusInvalid {
 		return map[string]interface{}{
 			"has_update": false,
 			"message":    "校验失败",
-		}, nil
+		}, nil, nil
 	}
 
-	// 检查当前版本是否是最新版本
-	isLatest, latestVersion, err := s.versionStore.IsVersionLatest(akey, vkey)
+	// 登记设备
+	device, err := s.deviceService.RegisterDevice(currentVersion, request.DeviceID, request.ClientIP)
 	if err != nil {
 		return map[string]interface{}{
 			"has_update": false,
 			"message":    "校验失败",
-		}, err
+		}, currentVersion, err
+	}
+	if device != nil && device.IsBlocked() {
+		return map[string]interface{}{
+			"has_update":   false,
+			"message":      "设备已被禁用",
+			"blocked":      true,
+			"block_reason": device.BlockReason,
+		}, currentVersion, nil
 	}
 
-	if isLatest {
+	// 使用旧密钥时以轮换后的密钥为准
+	keyRotated := currentVersion.AKey != akey || currentVersion.VKey != vkey
+	akey = currentVersion.AKey
+
+	// 获取该软件的全部版本
+	versions, err := s.versionStore.GetVersionsByAKey(akey)
+	if err != nil {
 		return map[string]interface{}{
 			"has_update": false,
-			"message":    "当前已是最新版本",
-		}, nil
+			"message":    "校验失败",
+		}, currentVersion, err
+	}
+
+	// 计算订阅渠道的最新版本并判断是否严格高于当前版本
+	channel := request.Channel
+	if channel == "" {
+		channel = model.ChannelStable
 	}
+	latestVersion := resolveLatestVersion(versions, channel, request.DeviceID)
+	hasUpdate := latestVersion != nil && compareVersions(latestVersion, currentVersion) > 0
 
-	// 存在新版本
+	// 根据应用的最低支持版本、强制更新版本范围和待更新版本计算强制更新判定
+	app, err := s.appStore.GetAppByAKey(akey)
+	if err != nil {
+		return map[string]interface{}{
+			"has_update": false,
+			"message":    "校验失败",
+		}, currentVersion, err
+	}
+	ranges, err := s.forcedRangeStore.GetForcedUpdateRangesByAKey(akey)
+	if err != nil {
+		return map[string]interface{}{
+			"has_update": false,
+			"message":    "校验失败",
+		}, currentVersion, err
+	}
+	var pending []*model.Version
+	if hasUpdate {
+		pending = pendingVersions(versions, channel, request.DeviceID, currentVersion, latestVersion)
+	}
+	forceUpdate := decideForceUpdate(app, ranges, currentVersion, pending)
+
+	if !hasUpdate {
+		return map[string]interface{}{
+			"has_update":   false,
+			"message":      "当前已是最新版本",
+			"revoked":      currentVersion.IsRevoked(),
+			"key_rotated":  keyRotated,
+			"force_update": forceUpdate,
+		}, currentVersion, nil
+	}
+
+	// 存在新版本，生成客户端平台的制品下载地址失败时只记录日志，不影响更新检查
+	download, err := s.artifacts.ResolveDownload(latestVersion, request.OS, request.Arch)
+	if err != nil {
+		logger.Errorf("生成制品下载地址失败 (版本: %s): %v", latestVersion.Version, err)
+	}
 	return map[string]interface{}{
 		"has_update":     true,
 		"latest_version": latestVersion.Version,
+		"channel":        latestVersion.Channel,
 		"release_time":   latestVersion.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}, nil
+		"revoked":        currentVersion.IsRevoked(),
+		"key_rotated":    keyRotated,
+		"force_update":   forceUpdate,
+		"artifact":       download,
+	}, currentVersion, nil
+}
+
+// recordCheck 记录校验请求遥测
+// 参数 version 为请求对应的版本，AKey或VKey无效时为nil，此时按请求中的AKey归属应用，应用不存在时不记录
+func (s *CheckService) recordCheck(endpoint string, request *model.CheckRequest, version *model.Version, status model.KeyStatus) {
+	event := &model.CheckEvent{
+		Endpoint:  endpoint,
+		Result:    status,
+		DeviceID:  request.DeviceID,
+		ClientIP:  request.ClientIP,
+		UserAgent: request.UserAgent,
+	}
+	if version != nil {
+		event.AKey = version.AKey
+		event.Version = version.Version
+	} else if app, err := s.appStore.ResolveApp(request.AKey); err == nil {
+		event.AKey = app.AKey
+	} else {
+		return
+	}
+	s.telemetry.Record(event)
 }
@@ -1,25 +1,89 @@
 package service
 
 import (
+	"sort"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/semver"
 	"verkeyoss/internal/store"
 )
 
+// 统计查询允许的最大天数
+const maxAnalyticsDays = 90
+
+// 统计日期的格式
+const analyticsDateLayout = "2006-01-02"
+
+// ErrInvalidAnalyticsDays 统计天数超出范围
+var ErrInvalidAnalyticsDays = errors.NewValidationError("统计天数必须在1-90之间")
+
 // DashboardService 仪表盘服务
 // 提供获取系统统计信息的功能
-// 在单管理员模式下，只提供系统总应用数
 
 type DashboardService struct {
-	store store.DashboardStore
+	store    store.DashboardStore
+	appStore store.AppStore
 }
 
 // NewDashboardService 创建仪表盘服务实例
 // 参数 store 为仪表盘存储接口的实现
-func NewDashboardService(store store.DashboardStore) *DashboardService {
+// 参数 appStore 为应用存储接口的实现
+func NewDashboardService(store store.DashboardStore, appStore store.AppStore) *DashboardService {
 	return &DashboardService{
-		store: store,
+		store:    store,
+		appStore: appStore,
 	}
 }
 
+// VersionInstalls 版本的活跃安装数及占比
+type VersionInstalls struct {
+	Version  string  `json:"version"`
+	Installs int64   `json:"installs"`
+	Share    float64 `json:"share"` // 占全部活跃安装数的比例
+}
+
+// ActiveInstalls 应用的活跃安装数统计
+type ActiveInstalls struct {
+	Days     int                `json:"days"`
+	Total    int64              `json:"total"`
+	Versions []*VersionInstalls `json:"versions"`
+}
+
+// VersionAdoption 版本每天的活跃设备数及占比
+type VersionAdoption struct {
+	Version string    `json:"version"`
+	Devices []int64   `json:"devices"`
+	Shares  []float64 `json:"shares"`
+}
+
+// AdoptionCurve 应用各版本的普及曲线
+// Devices 和 Shares 与 Dates 一一对应，Totals 为每天的活跃设备总数（同一设备当天使用多个版本时只计一次）
+type AdoptionCurve struct {
+	Dates    []string           `json:"dates"`
+	Totals   []int64            `json:"totals"`
+	Versions []*VersionAdoption `json:"versions"`
+}
+
+// CheckResultCounts 按校验结果统计的请求数
+type CheckResultCounts struct {
+	Date        string  `json:"date,omitempty"`
+	Total       int64   `json:"total"`
+	Valid       int64   `json:"valid"`
+	Invalid     int64   `json:"invalid"`
+	Revoked     int64   `json:"revoked"`
+	Blocked     int64   `json:"blocked"`
+	FailureRate float64 `json:"failure_rate"` // 校验未通过的请求占比
+}
+
+// CheckFailureStats 应用的校验失败率统计
+type CheckFailureStats struct {
+	Days    int                  `json:"days"`
+	Summary *CheckResultCounts   `json:"summary"`
+	Daily   []*CheckResultCounts `json:"daily"`
+}
+
 // GetDashboardData 获取仪表盘数据
 // 返回系统总应用数、总版本数、最近应用和最近版本
 func (s *DashboardService) GetDashboardData() (map[string]interface{}, error) {
@@ -56,3 +120,211 @@ func (s *DashboardService) GetDashboardData() (map[string]interface{}, error) {
 
 	return dashboardData, nil
 }
+
+// GetActiveInstalls 获取应用各版本的活跃安装数
+// 活跃安装指最近 days 天内携带设备标识调用过校验接口且未被禁用的设备，按设备最近使用的版本统计
+func (s *DashboardService) GetActiveInstalls(akey string, days int) (*ActiveInstalls, error) {
+	if err := s.checkAnalyticsQuery(akey, days); err != nil {
+		return nil, err
+	}
+
+	installs, err := s.store.GetActiveInstalls(akey, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ActiveInstalls{Days: days, Versions: make([]*VersionInstalls, 0, len(installs))}
+	for _, item := range installs {
+		result.Total += item.Installs
+	}
+	for _, item := range installs {
+		result.Versions = append(result.Versions, &VersionInstalls{
+			Version:  item.Version,
+			Installs: item.Installs,
+			Share:    ratio(item.Installs, result.Total),
+		})
+	}
+	sort.Slice(result.Versions, func(i, j int) bool {
+		return compareVersionStrings(result.Versions[i].Version, result.Versions[j].Version) > 0
+	})
+	return result, nil
+}
+
+// GetAdoptionCurve 获取应用最近 days 天各版本每天的活跃设备数，用于观察新版本的普及情况
+// 只统计携带设备标识且校验通过的请求
+func (s *DashboardService) GetAdoptionCurve(akey string, days int) (*AdoptionCurve, error) {
+	if err := s.checkAnalyticsQuery(akey, days); err != nil {
+		return nil, err
+	}
+
+	dates, fromHour, toHour := analyticsWindow(days)
+	devices, err := s.store.GetCheckEventDevices(akey, fromHour, toHour)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按天去重设备
+	dateIndex := indexDates(dates)
+	versionDevices := make(map[string][]map[string]struct{})
+	dailyDevices := make([]map[string]struct{}, len(dates))
+	for i := range dailyDevices {
+		dailyDevices[i] = make(map[string]struct{})
+	}
+	for _, item := range devices {
+		i, ok := dateIndex[hourDate(item.Hour)]
+		if !ok {
+			continue
+		}
+		if versionDevices[item.Version] == nil {
+			versionDevices[item.Version] = make([]map[string]struct{}, len(dates))
+		}
+		if versionDevices[item.Version][i] == nil {
+			versionDevices[item.Version][i] = make(map[string]struct{})
+		}
+		versionDevices[item.Version][i][item.DeviceID] = struct{}{}
+		dailyDevices[i][item.DeviceID] = struct{}{}
+	}
+
+	curve := &AdoptionCurve{
+		Dates:    dates,
+		Totals:   make([]int64, len(dates)),
+		Versions: make([]*VersionAdoption, 0, len(versionDevices)),
+	}
+	for i, set := range dailyDevices {
+		curve.Totals[i] = int64(len(set))
+	}
+	for version, daily := range versionDevices {
+		adoption := &VersionAdoption{
+			Version: version,
+			Devices: make([]int64, len(dates)),
+			Shares:  make([]float64, len(dates)),
+		}
+		for i, set := range daily {
+			adoption.Devices[i] = int64(len(set))
+			adoption.Shares[i] = ratio(adoption.Devices[i], curve.Totals[i])
+		}
+		curve.Versions = append(curve.Versions, adoption)
+	}
+	sort.Slice(curve.Versions, func(i, j int) bool {
+		return compareVersionStrings(curve.Versions[i].Version, curve.Versions[j].Version) > 0
+	})
+	return curve, nil
+}
+
+// GetCheckFailureStats 获取应用最近 days 天每天的校验请求数和校验失败率
+// 校验失败包括AKey或VKey无效、版本已撤回和设备已被禁用
+func (s *DashboardService) GetCheckFailureStats(akey string, days int) (*CheckFailureStats, error) {
+	if err := s.checkAnalyticsQuery(akey, days); err != nil {
+		return nil, err
+	}
+
+	dates, fromHour, toHour := analyticsWindow(days)
+	counts, err := s.store.GetCheckEventCounts(akey, fromHour, toHour)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &CheckFailureStats{
+		Days:    days,
+		Summary: &CheckResultCounts{},
+		Daily:   make([]*CheckResultCounts, len(dates)),
+	}
+	for i, date := range dates {
+		stats.Daily[i] = &CheckResultCounts{Date: date}
+	}
+	dateIndex := indexDates(dates)
+	for _, item := range counts {
+		i, ok := dateIndex[hourDate(item.Hour)]
+		if !ok {
+			continue
+		}
+		stats.Daily[i].add(item.Result, item.Count)
+		stats.Summary.add(item.Result, item.Count)
+	}
+	for _, daily := range stats.Daily {
+		daily.FailureRate = ratio(daily.Total-daily.Valid, daily.Total)
+	}
+	stats.Summary.FailureRate = ratio(stats.Summary.Total-stats.Summary.Valid, stats.Summary.Total)
+	return stats, nil
+}
+
+// add 累加指定校验结果的请求数
+func (c *CheckResultCounts) add(result model.KeyStatus, count int64) {
+	c.Total += count
+	switch result {
+	case model.KeyStatusValid:
+		c.Valid += count
+	case model.KeyStatusRevoked:
+		c.Revoked += count
+	case model.KeyStatusBlocked:
+		c.Blocked += count
+	default:
+		c.Invalid += count
+	}
+}
+
+// checkAnalyticsQuery 检查统计查询的应用和天数
+func (s *DashboardService) checkAnalyticsQuery(akey string, days int) error {
+	if days < 1 || days > maxAnalyticsDays {
+		return ErrInvalidAnalyticsDays
+	}
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return ErrAppNotFound
+	}
+	return nil
+}
+
+// analyticsWindow 计算最近 days 天（含今天）的日期列表及对应的小时范围
+// 日期按服务端本地时区划分，小时范围包含起始小时，不包含结束小时
+func analyticsWindow(days int) ([]string, int64, int64) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := today.AddDate(0, 0, -(days - 1))
+
+	dates := make([]string, 0, days)
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format(analyticsDateLayout))
+	}
+	return dates, start.Truncate(time.Hour).Unix(), now.Truncate(time.Hour).Add(time.Hour).Unix()
+}
+
+// indexDates 返回日期到下标的映射
+func indexDates(dates []string) map[string]int {
+	index := make(map[string]int, len(dates))
+	for i, date := range dates {
+		index[date] = i
+	}
+	return index
+}
+
+// hourDate 返回小时时间戳在服务端本地时区的日期
+func hourDate(hour int64) string {
+	return time.Unix(hour, 0).Format(analyticsDateLayout)
+}
+
+// ratio 计算占比，分母为0时返回0
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+// compareVersionStrings 比较两个版本号，无法解析为语义化版本的版本号排在后面并按字符串比较
+func compareVersionStrings(a, b string) int {
+	if cmp, err := semver.Compare(a, b); err == nil {
+		return cmp
+	}
+	switch {
+	case semver.IsValid(a):
+		return 1
+	case semver.IsValid(b):
+		return -1
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+)
+
+// 时间序列的统计粒度
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+	GranularityWeek = "week"
+)
+
+// 时间序列允许的最大时间段数
+const maxSeriesBuckets = 744
+
+// 时间序列相关错误
+var (
+	ErrInvalidGranularity = errors.NewValidationError("无效的统计粒度，可选值为 hour、day、week")
+	ErrInvalidSeriesRange = errors.NewValidationError("开始时间必须早于结束时间")
+	ErrSeriesRangeTooLong = errors.NewValidationError("时间范围过长，请缩小时间范围或使用更大的统计粒度")
+)
+
+// SeriesPoint 时间序列中一个时间段的统计数据
+type SeriesPoint struct {
+	Time          time.Time // 时间段的起始时间
+	Releases      int64     // 发布的版本数
+	Checks        int64     // 校验和检查更新的请求数
+	UniqueDevices int64     // 调用过校验接口的设备数
+	Failures      int64     // 校验未通过的请求数
+	FailureRate   float64   // 校验未通过的请求占比
+}
+
+// TimeSeries 应用的时间序列统计
+// From 和 To 为按统计粒度对齐后的时间范围，包含起始时间，不包含结束时间
+type TimeSeries struct {
+	Granularity string
+	From        time.Time
+	To          time.Time
+	Points      []*SeriesPoint
+}
+
+// GetTimeSeries 获取应用在时间范围内按小时、天或周统计的发布版本数、校验请求数、设备数和校验失败数
+// 开始时间向前、结束时间向后对齐到统计粒度的边界，时间段按服务端本地时区划分，周从周一开始；
+// 开始时间或结束时间为零值时按统计粒度使用默认的时间范围
+func (s *DashboardService) GetTimeSeries(akey string, from, to time.Time, granularity string) (*TimeSeries, error) {
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	if granularity != GranularityHour && granularity != GranularityDay && granularity != GranularityWeek {
+		return nil, ErrInvalidGranularity
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = defaultSeriesStart(to, granularity)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidSeriesRange
+	}
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	// 划分时间段
+	series := &TimeSeries{Granularity: granularity, From: truncateToBucket(from, granularity)}
+	bucketIndex := make(map[int64]int)
+	for start := series.From; start.Before(to); start = nextBucket(start, granularity) {
+		if len(series.Points) >= maxSeriesBuckets {
+			return nil, ErrSeriesRangeTooLong
+		}
+		bucketIndex[start.Unix()] = len(series.Points)
+		series.Points = append(series.Points, &SeriesPoint{Time: start})
+	}
+	series.To = nextBucket(series.Points[len(series.Points)-1].Time, granularity)
+	fromHour, toHour := series.From.Truncate(time.Hour).Unix(), series.To.Unix()
+
+	// findBucket 返回时间所在时间段的统计数据，不在时间范围内时返回nil
+	findBucket := func(t time.Time) *SeriesPoint {
+		if i, ok := bucketIndex[truncateToBucket(t, granularity).Unix()]; ok {
+			return series.Points[i]
+		}
+		return nil
+	}
+
+	// 发布的版本数
+	releaseTimes, err := s.store.GetVersionReleaseTimes(akey, series.From, series.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, releasedAt := range releaseTimes {
+		if point := findBucket(releasedAt); point != nil {
+			point.Releases++
+		}
+	}
+
+	// 校验请求数和校验失败数
+	counts, err := s.store.GetCheckEventCounts(akey, fromHour, toHour)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range counts {
+		point := findBucket(time.Unix(item.Hour, 0))
+		if point == nil {
+			continue
+		}
+		point.Checks += item.Count
+		if item.Result != model.KeyStatusValid {
+			point.Failures += item.Count
+		}
+	}
+
+	// 设备数，同一设备在一个时间段内只计一次
+	deviceHours, err := s.store.GetCheckEventDeviceHours(akey, fromHour, toHour)
+	if err != nil {
+		return nil, err
+	}
+	bucketDevices := make(map[*SeriesPoint]map[string]struct{})
+	for _, item := range deviceHours {
+		point := findBucket(time.Unix(item.Hour, 0))
+		if point == nil {
+			continue
+		}
+		if bucketDevices[point] == nil {
+			bucketDevices[point] = make(map[string]struct{})
+		}
+		bucketDevices[point][item.DeviceID] = struct{}{}
+	}
+
+	for _, point := range series.Points {
+		point.UniqueDevices = int64(len(bucketDevices[point]))
+		point.FailureRate = ratio(point.Failures, point.Checks)
+	}
+	return series, nil
+}
+
+// defaultSeriesStart 返回统计粒度默认时间范围的开始时间：按小时统计最近24小时，按天统计最近30天，按周统计最近12周
+func defaultSeriesStart(to time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return to.Add(-24 * time.Hour)
+	case GranularityWeek:
+		return to.AddDate(0, 0, -7*12)
+	default:
+		return to.AddDate(0, 0, -30)
+	}
+}
+
+// truncateToBucket 返回时间所在时间段的起始时间
+func truncateToBucket(t time.Time, granularity string) time.Time {
+	t = t.In(time.Local)
+	switch granularity {
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		// 周从周一开始
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextBucket 返回下一个时间段的起始时间
+func nextBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return start.Add(time.Hour)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
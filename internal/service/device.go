@@ -0,0 +1,133 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// 预定义错误
+var (
+	ErrDeviceNotFound       = errors.ErrDeviceNotFound
+	ErrDeviceBlocked        = errors.NewForbiddenError("设备已被禁用")
+	ErrDeviceAlreadyBlocked = errors.NewConflictError("设备已被禁用")
+	ErrDeviceNotBlocked     = errors.NewConflictError("设备未被禁用")
+)
+
+// DeviceService 设备服务
+// 负责登记调用校验接口的设备，以及设备的查询、禁用和注销
+type DeviceService struct {
+	store    store.DeviceStore
+	appStore store.AppStore
+}
+
+// NewDeviceService 创建设备服务实例
+func NewDeviceService(store store.DeviceStore, appStore store.AppStore) *DeviceService {
+	return &DeviceService{store: store, appStore: appStore}
+}
+
+// RegisterDevice 登记设备对指定版本的访问，返回设备记录
+// 调用方需要先校验AKey和VKey，设备标识为空时不登记并返回nil
+func (s *DeviceService) RegisterDevice(version *model.Version, deviceID, ip string) (*model.Device, error) {
+	if deviceID == "" {
+		return nil, nil
+	}
+
+	return s.store.RecordDevice(&model.Device{
+		AKey:       version.AKey,
+		DeviceID:   deviceID,
+		VKey:       version.VKey,
+		Version:    version.Version,
+		IP:         ip,
+		LastSeenAt: time.Now(),
+	})
+}
+
+// IsDeviceBlocked 判断应用下的设备是否已被禁用
+func (s *DeviceService) IsDeviceBlocked(akey, deviceID string) bool {
+	return isDeviceBlocked(s.store, akey, deviceID)
+}
+
+// GetDeviceList 获取应用的设备列表
+func (s *DeviceService) GetDeviceList(akey string, query *model.DeviceQuery, page, size int) ([]*model.Device, int64, error) {
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, 0, ErrAppNotFound
+	}
+
+	query.Keyword = strings.TrimSpace(query.Keyword)
+	return s.store.GetDeviceListByAKey(akey, query, page, size)
+}
+
+// BlockDevice 禁用设备，禁用后该设备的校验、检查更新和许可证激活请求均被拒绝
+func (s *DeviceService) BlockDevice(akey string, id uint, reason string) (*model.Device, error) {
+	device, err := s.getAppDevice(akey, id)
+	if err != nil {
+		return nil, err
+	}
+	if device.IsBlocked() {
+		return nil, ErrDeviceAlreadyBlocked
+	}
+	if len([]rune(reason)) > 500 {
+		return nil, errors.NewValidationError("禁用原因不能超过500个字符")
+	}
+
+	now := time.Now()
+	if err := s.store.UpdateDeviceBlock(device.ID, &now, reason); err != nil {
+		return nil, err
+	}
+
+	device.BlockedAt = &now
+	device.BlockReason = reason
+	return device, nil
+}
+
+// UnblockDevice 解除设备禁用
+func (s *DeviceService) UnblockDevice(akey string, id uint) (*model.Device, error) {
+	device, err := s.getAppDevice(akey, id)
+	if err != nil {
+		return nil, err
+	}
+	if !device.IsBlocked() {
+		return nil, ErrDeviceNotBlocked
+	}
+
+	if err := s.store.UpdateDeviceBlock(device.ID, nil, ""); err != nil {
+		return nil, err
+	}
+
+	device.BlockedAt = nil
+	device.BlockReason = ""
+	return device, nil
+}
+
+// DeleteDevice 注销设备并释放其占用的许可证席位
+// 设备再次调用校验接口时会重新登记，需要阻止设备访问时应使用禁用
+func (s *DeviceService) DeleteDevice(akey string, id uint) error {
+	device, err := s.getAppDevice(akey, id)
+	if err != nil {
+		return err
+	}
+
+	return s.store.DeleteDevice(device)
+}
+
+// getAppDevice 获取属于指定应用的设备
+func (s *DeviceService) getAppDevice(akey string, id uint) (*model.Device, error) {
+	device, err := s.store.GetDeviceByID(id)
+	if err != nil || device.AKey != akey {
+		return nil, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+// isDeviceBlocked 判断应用下的设备是否已被禁用，设备未登记时视为未禁用
+func isDeviceBlocked(deviceStore store.DeviceStore, akey, deviceID string) bool {
+	if deviceID == "" {
+		return false
+	}
+	device, err := deviceStore.GetDevice(akey, deviceID)
+	return err == nil && device != nil && device.IsBlocked()
+}
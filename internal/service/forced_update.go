@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/semver"
+	"verkeyoss/internal/store"
+)
+
+// 预定义错误
+var (
+	ErrForcedRangeNotFound = errors.ErrForcedRangeNotFound
+	ErrInvalidForcedRange  = errors.NewValidationError("版本范围无效，至少需要指定一个语义化版本号边界，且下界不能高于上界")
+)
+
+// ForcedUpdateService 强制更新版本范围服务
+type ForcedUpdateService struct {
+	store    store.ForcedUpdateRangeStore
+	appStore store.AppStore
+}
+
+// NewForcedUpdateService 创建强制更新版本范围服务实例
+func NewForcedUpdateService(store store.ForcedUpdateRangeStore, appStore store.AppStore) *ForcedUpdateService {
+	return &ForcedUpdateService{store: store, appStore: appStore}
+}
+
+// CreateRange 为应用添加强制更新版本范围
+// 参数 minVersion 和 maxVersion 均包含边界，为空表示不限，但不能同时为空
+func (s *ForcedUpdateService) CreateRange(akey, minVersion, maxVersion, reason string) (*model.ForcedUpdateRange, error) {
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	if minVersion == "" && maxVersion == "" {
+		return nil, ErrInvalidForcedRange
+	}
+	for _, bound := range []string{minVersion, maxVersion} {
+		if bound != "" && !semver.IsValid(bound) {
+			return nil, ErrInvalidForcedRange
+		}
+	}
+	if minVersion != "" && maxVersion != "" {
+		if c, _ := semver.Compare(minVersion, maxVersion); c > 0 {
+			return nil, ErrInvalidForcedRange
+		}
+	}
+
+	forcedRange := &model.ForcedUpdateRange{
+		AKey:       akey,
+		MinVersion: minVersion,
+		MaxVersion: maxVersion,
+		Reason:     reason,
+	}
+	if err := s.store.CreateForcedUpdateRange(forcedRange); err != nil {
+		return nil, err
+	}
+
+	return forcedRange, nil
+}
+
+// GetRanges 获取应用的全部强制更新版本范围
+func (s *ForcedUpdateService) GetRanges(akey string) ([]*model.ForcedUpdateRange, error) {
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	return s.store.GetForcedUpdateRangesByAKey(akey)
+}
+
+// DeleteRange 删除应用的强制更新版本范围
+func (s *ForcedUpdateService) DeleteRange(akey string, id uint) error {
+	forcedRange, err := s.store.GetForcedUpdateRangeByID(id)
+	if err != nil || forcedRange.AKey != akey {
+		return ErrForcedRangeNotFound
+	}
+
+	return s.store.DeleteForcedUpdateRange(id)
+}
+
+// decideForceUpdate 根据客户端当前版本计算强制更新判定
+// 判定顺序：当前版本已撤回、低于应用最低支持版本、落在强制更新版本范围内、
+// 当前版本与最新版本之间存在标记为强制更新的版本。
+// 当前版本号无法解析时只检查撤回状态和是否存在强制更新的新版本
+func decideForceUpdate(app *model.App, ranges []*model.ForcedUpdateRange, current *model.Version, pending []*model.Version) *model.ForceUpdateDecision {
+	if current.IsRevoked() {
+		message := current.RevokeReason
+		if message == "" {
+			message = "当前版本已撤回"
+		}
+		return &model.ForceUpdateDecision{
+			Required: true,
+			Reason:   model.ForceReasonRevoked,
+			Message:  message,
+		}
+	}
+
+	if app != nil && app.MinSupportedVersion != "" {
+		if c, err := semver.Compare(current.Version, app.MinSupportedVersion); err == nil && c < 0 {
+			return &model.ForceUpdateDecision{
+				Required: true,
+				Reason:   model.ForceReasonMinVersion,
+				Message:  fmt.Sprintf("当前版本低于最低支持版本 %s", app.MinSupportedVersion),
+			}
+		}
+	}
+
+	for _, forcedRange := range ranges {
+		if forcedRange.Contains(current.Version) {
+			message := forcedRange.Reason
+			if message == "" {
+				message = "当前版本已停止支持"
+			}
+			return &model.ForceUpdateDecision{
+				Required: true,
+				Reason:   model.ForceReasonVersionRange,
+				Message:  message,
+			}
+		}
+	}
+
+	for _, version := range pending {
+		if version.IsForcedUpdate {
+			return &model.ForceUpdateDecision{
+				Required: true,
+				Reason:   model.ForceReasonForcedRelease,
+				Message:  fmt.Sprintf("版本 %s 为强制更新版本", version.Version),
+			}
+		}
+	}
+
+	return &model.ForceUpdateDecision{Required: false}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/semver"
+	"verkeyoss/internal/store"
+	offline "verkeyoss/pkg/license"
+)
+
+// 预定义错误，使用统一的错误处理
+var (
+	ErrLicenseNotFound     = errors.ErrLicenseNotFound
+	ErrActivationNotFound  = errors.ErrActivationNotFound
+	ErrAppNotPaid          = errors.NewValidationError("只能为付费应用签发许可证")
+	ErrLicenseRevoked      = errors.NewConflictError("许可证已吊销")
+	ErrLicenseExpired      = errors.NewConflictError("许可证已过期")
+	ErrLicenseSeatLimit    = errors.NewConflictError("许可证席位已满")
+	ErrInvalidDeviceID     = errors.NewValidationError("设备标识不能为空且不能超过128个字符")
+	ErrInvalidLicenseRange = errors.NewValidationError("版本范围无效，边界必须是语义化版本号，且下界不能高于上界")
+)
+
+// 许可证席位数上限
+const maxLicenseSeats = 10000
+
+// LicenseService 许可证服务
+// 负责付费应用许可证的签发、吊销、设备激活和校验，以及离线许可证文件的导出
+
+type LicenseService struct {
+	store            store.LicenseStore
+	appStore         store.AppStore
+	deviceStore      store.DeviceStore
+	signatureService *SignatureService
+}
+
+// NewLicenseService 创建许可证服务实例
+func NewLicenseService(store store.LicenseStore, appStore store.AppStore, deviceStore store.DeviceStore, signatureService *SignatureService) *LicenseService {
+	return &LicenseService{store: store, appStore: appStore, deviceStore: deviceStore, signatureService: signatureService}
+}
+
+// LicenseDetail 许可证详情，包含全部激活记录
+type LicenseDetail struct {
+	License     *model.License
+	Activations []*model.LicenseActivation
+}
+
+// CreateLicense 为付费应用签发许可证
+// 参数 expiresInDays 为0时许可证永久有效
+func (s *LicenseService) CreateLicense(principal *Principal, akey string, seats int, expiresInDays int, note string) (*model.License, error) {
+	app, err := s.appStore.GetAppByAKey(akey)
+	if err != nil {
+		return nil, ErrAppNotFound
+	}
+	if !app.IsPaid {
+		return nil, ErrAppNotPaid
+	}
+	if seats < 1 || seats > maxLicenseSeats {
+		return nil, errors.NewValidationError("席位数必须在1-10000之间")
+	}
+	if expiresInDays < 0 {
+		return nil, errors.NewValidationError("有效期不能为负数")
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > 200 {
+		return nil, errors.NewValidationError("备注不能超过200个字符")
+	}
+
+	licenseKey, err := generateLicenseKey()
+	if err != nil {
+		return nil, err
+	}
+
+	license := &model.License{
+		AKey:       akey,
+		LicenseKey: licenseKey,
+		Seats:      seats,
+		Note:       note,
+		UserID:     principal.UserID,
+	}
+	if expiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, expiresInDays)
+		license.ExpiresAt = &expiresAt
+	}
+
+	if err := s.store.CreateLicense(license); err != nil {
+		return nil, err
+	}
+
+	return license, nil
+}
+
+// GetLicenseList 获取应用的许可证列表
+func (s *LicenseService) GetLicenseList(akey string, page, size int) ([]*model.License, int64, error) {
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, 0, ErrAppNotFound
+	}
+
+	return s.store.GetLicenseListByAKey(akey, page, size)
+}
+
+// GetLicense 获取许可证详情
+func (s *LicenseService) GetLicense(akey string, id uint) (*LicenseDetail, error) {
+	license, err := s.getAppLicense(akey, id)
+	if err != nil {
+		return nil, err
+	}
+
+	activations, err := s.store.GetActivations(license.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LicenseDetail{License: license, Activations: activations}, nil
+}
+
+// RevokeLicense 吊销许可证，吊销后所有设备的校验结果均为 revoked
+func (s *LicenseService) RevokeLicense(akey string, id uint) error {
+	license, err := s.getAppLicense(akey, id)
+	if err != nil {
+		return err
+	}
+	if license.RevokedAt != nil {
+		return ErrLicenseRevoked
+	}
+
+	return s.store.RevokeLicense(license.ID, time.Now())
+}
+
+// DeleteActivation 删除设备的激活记录，释放席位
+func (s *LicenseService) DeleteActivation(akey string, id, activationID uint) error {
+	license, err := s.getAppLicense(akey, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.store.DeleteActivation(license.ID, activationID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrActivationNotFound
+	}
+	return nil
+}
+
+// Activate 使用设备标识激活许可证
+// 同一设备重复激活不会占用新的席位，已被禁用的设备无法激活。
+// 宽限期内的旧AKey映射到轮换后的应用，许可证和设备记录均以当前AKey保存
+func (s *LicenseService) Activate(akey, licenseKey, deviceID string) *model.LicenseCheck {
+	app, err := s.appStore.ResolveApp(akey)
+	if err != nil {
+		return &model.LicenseCheck{Status: model.LicenseStatusInvalid, Message: "许可证无效"}
+	}
+
+	license, check := s.findUsableLicense(app.AKey, licenseKey)
+	if check != nil {
+		return check
+	}
+	if isDeviceBlocked(s.deviceStore, app.AKey, deviceID) {
+		return &model.LicenseCheck{Status: model.LicenseStatusBlocked, Message: "设备已被禁用"}
+	}
+
+	activated, used, err := s.store.ActivateLicense(license, deviceID, time.Now())
+	if err != nil {
+		return &model.LicenseCheck{Status: model.LicenseStatusInvalid, Message: "激活失败"}
+	}
+	if !activated {
+		return newLicenseCheck(license, model.LicenseStatusSeatLimit, "许可证席位已满", used)
+	}
+	return newLicenseCheck(license, model.LicenseStatusValid, "激活成功", used)
+}
+
+// Check 校验许可证在指定设备上的状态，并更新激活记录的最近校验时间
+func (s *LicenseService) Check(akey, licenseKey, deviceID string) *model.LicenseCheck {
+	if strings.TrimSpace(licenseKey) == "" {
+		return &model.LicenseCheck{Status: model.LicenseStatusMissing, Message: "未提供许可证"}
+	}
+
+	license, check := s.findUsableLicense(akey, licenseKey)
+	if check != nil {
+		return check
+	}
+
+	used, _ := s.store.CountActivations(license.ID)
+	if deviceID == "" {
+		return newLicenseCheck(license, model.LicenseStatusNotActivated, "未提供设备标识", used)
+	}
+	activation, err := s.store.GetActivation(license.ID, deviceID)
+	if err != nil || activation == nil {
+		return newLicenseCheck(license, model.LicenseStatusNotActivated, "当前设备未激活该许可证", used)
+	}
+
+	s.store.TouchActivation(activation.ID, time.Now())
+	return newLicenseCheck(license, model.LicenseStatusValid, "许可证有效", used)
+}
+
+// OfflineLicense 导出的离线许可证文件及其声明
+type OfflineLicense struct {
+	File   string
+	Claims *offline.Claims
+}
+
+// ExportOfflineLicense 导出绑定设备的离线许可证文件
+// 导出时设备会占用许可证的一个席位，与在线激活相同；离线许可证签发后无法吊销，
+// 因此有效期不会超过许可证本身，参数 expiresInDays 为0时与许可证的有效期一致
+func (s *LicenseService) ExportOfflineLicense(akey string, id uint, deviceID, minVersion, maxVersion string, expiresInDays int) (*OfflineLicense, error) {
+	license, err := s.getAppLicense(akey, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceID = strings.TrimSpace(deviceID)
+	if deviceID == "" || len(deviceID) > 128 {
+		return nil, ErrInvalidDeviceID
+	}
+	for _, bound := range []string{minVersion, maxVersion} {
+		if bound != "" && !semver.IsValid(bound) {
+			return nil, ErrInvalidLicenseRange
+		}
+	}
+	if minVersion != "" && maxVersion != "" {
+		if c, _ := semver.Compare(minVersion, maxVersion); c > 0 {
+			return nil, ErrInvalidLicenseRange
+		}
+	}
+	if expiresInDays < 0 {
+		return nil, errors.NewValidationError("有效期不能为负数")
+	}
+
+	now := time.Now()
+	if license.RevokedAt != nil {
+		return nil, ErrLicenseRevoked
+	}
+	if license.ExpiresAt != nil && !now.Before(*license.ExpiresAt) {
+		return nil, ErrLicenseExpired
+	}
+	if isDeviceBlocked(s.deviceStore, akey, deviceID) {
+		return nil, ErrDeviceBlocked
+	}
+
+	// 离线设备同样占用席位
+	activated, _, err := s.store.ActivateLicense(license, deviceID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !activated {
+		return nil, ErrLicenseSeatLimit
+	}
+
+	claims := &offline.Claims{
+		LicenseKey: license.LicenseKey,
+		AKey:       license.AKey,
+		DeviceID:   deviceID,
+		MinVersion: minVersion,
+		MaxVersion: maxVersion,
+		IssuedAt:   now.Unix(),
+	}
+	if license.ExpiresAt != nil {
+		claims.ExpiresAt = license.ExpiresAt.Unix()
+	}
+	if expiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, expiresInDays).Unix()
+		if claims.ExpiresAt == 0 || expiresAt < claims.ExpiresAt {
+			claims.ExpiresAt = expiresAt
+		}
+	}
+
+	file, err := s.signatureService.SignOfflineLicense(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OfflineLicense{File: file, Claims: claims}, nil
+}
+
+// findUsableLicense 查找属于应用且未吊销、未过期的许可证
+// 许可证不可用时返回对应的校验结果
+func (s *LicenseService) findUsableLicense(akey, licenseKey string) (*model.License, *model.LicenseCheck) {
+	license, err := s.store.GetLicenseByKey(normalizeLicenseKey(licenseKey))
+	if err != nil || license.AKey != akey {
+		return nil, &model.LicenseCheck{Status: model.LicenseStatusInvalid, Message: "许可证无效"}
+	}
+	if license.RevokedAt != nil {
+		return nil, newLicenseCheck(license, model.LicenseStatusRevoked, "许可证已吊销", 0)
+	}
+	if license.ExpiresAt != nil && !time.Now().Before(*license.ExpiresAt) {
+		return nil, newLicenseCheck(license, model.LicenseStatusExpired, "许可证已过期", 0)
+	}
+	return license, nil
+}
+
+// getAppLicense 获取属于指定应用的许可证
+func (s *LicenseService) getAppLicense(akey string, id uint) (*model.License, error) {
+	license, err := s.store.GetLicenseByID(id)
+	if err != nil || license.AKey != akey {
+		return nil, ErrLicenseNotFound
+	}
+	return license, nil
+}
+
+// newLicenseCheck 根据许可证生成校验结果
+func newLicenseCheck(license *model.License, status, message string, used int64) *model.LicenseCheck {
+	return &model.LicenseCheck{
+		Status:    status,
+		Message:   message,
+		Seats:     license.Seats,
+		UsedSeats: used,
+		ExpiresAt: license.ExpiresAt,
+	}
+}
+
+// generateLicenseKey 生成许可证密钥
+// 格式为5组5位的Base32字符，以短横线分隔，如 ABCDE-FGHIJ-KLMNO-PQRST-UVWXY
+func generateLicenseKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf)[:25]
+
+	groups := make([]string, 0, 5)
+	for i := 0; i < len(encoded); i += 5 {
+		groups = append(groups, encoded[i:i+5])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeLicenseKey 规范化用户输入的许可证密钥，忽略首尾空白和大小写
+func normalizeLicenseKey(licenseKey string) string {
+	return strings.ToUpper(strings.TrimSpace(licenseKey))
+}
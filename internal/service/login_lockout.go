@@ -0,0 +1,125 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"verkeyoss/internal/errors"
+)
+
+// 清理过期登录失败记录的间隔
+const loginAttemptPruneInterval = time.Minute
+
+// LoginLockout 登录失败锁定
+// 同一用户名连续登录失败达到次数上限后锁定，锁定期间的登录请求直接拒绝而不校验密码；
+// 锁定结束后再次失败会重新锁定，时长按指数增长直到上限，登录成功后清零
+type LoginLockout struct {
+	maxFailures int
+	baseLockout time.Duration
+	maxLockout  time.Duration
+
+	mu        sync.Mutex
+	attempts  map[string]*loginAttempt
+	nextPrune time.Time
+}
+
+// loginAttempt 用户名的登录失败记录
+type loginAttempt struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// NewLoginLockout 创建登录失败锁定实例
+// 参数 maxFailures 为连续失败多少次后锁定，小于等于0时不锁定
+// 参数 lockoutSeconds 为首次锁定的时长，maxLockoutSeconds 为锁定时长上限
+func NewLoginLockout(maxFailures, lockoutSeconds, maxLockoutSeconds int) *LoginLockout {
+	return &LoginLockout{
+		maxFailures: maxFailures,
+		baseLockout: time.Duration(lockoutSeconds) * time.Second,
+		maxLockout:  time.Duration(maxLockoutSeconds) * time.Second,
+		attempts:    make(map[string]*loginAttempt),
+	}
+}
+
+// Check 检查用户名是否处于锁定期，锁定时返回请求过于频繁错误
+func (l *LoginLockout) Check(username string) error {
+	if l.maxFailures <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if attempt, ok := l.attempts[username]; ok {
+		if remaining := time.Until(attempt.lockedUntil); remaining > 0 {
+			return newLoginLockedError(remaining)
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，达到次数上限时锁定并返回请求过于频繁错误
+func (l *LoginLockout) RecordFailure(username string) error {
+	if l.maxFailures <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	// 距上次失败已超过锁定时长上限的记录重新计数
+	attempt, ok := l.attempts[username]
+	if !ok || (now.After(attempt.lockedUntil) && now.Sub(attempt.lastFailure) > l.maxLockout) {
+		attempt = &loginAttempt{}
+		l.attempts[username] = attempt
+	}
+	attempt.failures++
+	attempt.lastFailure = now
+
+	if attempt.failures < l.maxFailures {
+		return nil
+	}
+
+	// 超过次数上限后每多失败一次锁定时长翻倍
+	lockout := l.baseLockout
+	for i := l.maxFailures; i < attempt.failures && lockout < l.maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > l.maxLockout {
+		lockout = l.maxLockout
+	}
+	attempt.lockedUntil = now.Add(lockout)
+	return newLoginLockedError(lockout)
+}
+
+// Reset 登录成功后清除失败记录
+func (l *LoginLockout) Reset(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, username)
+}
+
+// prune 清理已过锁定期且长时间没有再失败的记录，需在持有锁时调用
+// 失败记录在最后一次失败后保留 maxLockout 时长，期间的失败会累计
+func (l *LoginLockout) prune(now time.Time) {
+	if now.Before(l.nextPrune) {
+		return
+	}
+	for username, attempt := range l.attempts {
+		if now.After(attempt.lockedUntil) && now.Sub(attempt.lastFailure) > l.maxLockout {
+			delete(l.attempts, username)
+		}
+	}
+	l.nextPrune = now.Add(loginAttemptPruneInterval)
+}
+
+// newLoginLockedError 创建登录锁定错误
+func newLoginLockedError(retryAfter time.Duration) error {
+	return errors.NewTooManyRequestsError("登录失败次数过多，请稍后再试", retryAfter)
+}
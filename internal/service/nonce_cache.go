@@ -0,0 +1,52 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceCache 请求随机数缓存，用于拒绝重放的请求
+// 多实例部署时需要使用各实例共享的实现
+type NonceCache interface {
+	// Add 记录随机数直到 expiresAt，随机数已存在且未过期时返回false
+	Add(key string, expiresAt time.Time) bool
+}
+
+// 清理过期随机数的间隔
+const noncePruneInterval = time.Minute
+
+// MemoryNonceCache 基于内存的随机数缓存，适用于单实例部署
+type MemoryNonceCache struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	nextPrune time.Time
+}
+
+// NewMemoryNonceCache 创建基于内存的随机数缓存
+func NewMemoryNonceCache() *MemoryNonceCache {
+	return &MemoryNonceCache{entries: make(map[string]time.Time)}
+}
+
+// Add 记录随机数直到 expiresAt，随机数已存在且未过期时返回false
+func (c *MemoryNonceCache) Add(key string, expiresAt time.Time) bool {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 定期清理过期的随机数，避免缓存无限增长
+	if now.After(c.nextPrune) {
+		for k, expiry := range c.entries {
+			if !now.Before(expiry) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextPrune = now.Add(noncePruneInterval)
+	}
+
+	if expiry, ok := c.entries[key]; ok && now.Before(expiry) {
+		return false
+	}
+	c.entries[key] = expiresAt
+	return true
+}
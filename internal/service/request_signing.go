@@ -0,0 +1,164 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// 预定义错误
+var (
+	ErrRequestSignatureRequired = errors.NewUnauthorizedError("该应用要求校验请求必须签名")
+	ErrInvalidRequestSignature  = errors.NewUnauthorizedError("请求签名无效")
+	ErrRequestTimestampSkewed   = errors.NewUnauthorizedError("请求时间戳超出允许范围")
+	ErrRequestReplayed          = errors.NewUnauthorizedError("请求随机数已被使用")
+	ErrInvalidRequestNonce      = errors.NewValidationError("请求随机数长度必须在16-128个字符之间")
+)
+
+// 请求随机数的长度范围
+const (
+	minRequestNonceLength = 16
+	maxRequestNonceLength = 128
+)
+
+// SignedRequest 客户端提交的签名校验请求
+type SignedRequest struct {
+	AKey      string // 请求体中的AKey
+	Timestamp string // X-Request-Timestamp 请求头，Unix时间戳（秒）
+	Nonce     string // X-Request-Nonce 请求头
+	Signature string // X-Request-Signature 请求头，十六进制编码的HMAC-SHA256
+	Body      []byte // 原始请求体
+}
+
+// RequestSigningService 校验请求签名服务
+// 客户端使用应用的请求签名密钥对时间戳、随机数和请求体做HMAC签名，
+// 服务端校验签名、时间戳偏差，并通过随机数缓存拒绝重放的请求
+type RequestSigningService struct {
+	appStore   store.AppStore
+	nonceCache NonceCache
+	maxSkew    time.Duration
+}
+
+// NewRequestSigningService 创建校验请求签名服务实例
+// 参数 maxSkewSeconds 为请求时间戳与服务端时间允许的最大偏差
+func NewRequestSigningService(appStore store.AppStore, nonceCache NonceCache, maxSkewSeconds int) *RequestSigningService {
+	return &RequestSigningService{
+		appStore:   appStore,
+		nonceCache: nonceCache,
+		maxSkew:    time.Duration(maxSkewSeconds) * time.Second,
+	}
+}
+
+// VerifyRequest 校验请求签名
+// 未携带签名的请求只有在应用要求签名时才被拒绝；携带签名时无论应用是否要求都会校验。
+// AKey不存在时不做处理，由后续的校验逻辑返回校验失败
+func (s *RequestSigningService) VerifyRequest(request *SignedRequest) error {
+	app, err := s.appStore.ResolveApp(request.AKey)
+	if err != nil {
+		return nil
+	}
+
+	if request.Signature == "" {
+		if app.RequireSignedRequests {
+			return ErrRequestSignatureRequired
+		}
+		return nil
+	}
+
+	if len(request.Nonce) < minRequestNonceLength || len(request.Nonce) > maxRequestNonceLength {
+		return ErrInvalidRequestNonce
+	}
+
+	timestamp, err := strconv.ParseInt(request.Timestamp, 10, 64)
+	if err != nil {
+		return ErrRequestTimestampSkewed
+	}
+	now := time.Now()
+	requestTime := time.Unix(timestamp, 0)
+	if requestTime.Before(now.Add(-s.maxSkew)) || requestTime.After(now.Add(s.maxSkew)) {
+		return ErrRequestTimestampSkewed
+	}
+
+	signature, err := hex.DecodeString(request.Signature)
+	if err != nil || app.RequestSecret == "" ||
+		!hmac.Equal(signature, SignRequest(app.RequestSecret, request.Timestamp, request.Nonce, request.Body)) {
+		return ErrInvalidRequestSignature
+	}
+
+	// 签名合法后再记录随机数，随机数在时间戳超出允许范围后才会过期，此后的重放会因时间戳被拒绝
+	if !s.nonceCache.Add(app.AKey+":"+request.Nonce, requestTime.Add(s.maxSkew)) {
+		return ErrRequestReplayed
+	}
+	return nil
+}
+
+// GetSettings 获取应用的请求签名设置
+func (s *RequestSigningService) GetSettings(akey string) (*model.App, error) {
+	app, err := s.appStore.GetAppByAKey(akey)
+	if err != nil {
+		return nil, ErrAppNotFound
+	}
+	return app, nil
+}
+
+// SetRequired 设置应用是否要求校验请求必须签名，应用没有签名密钥时自动生成
+func (s *RequestSigningService) SetRequired(akey string, required bool) (*model.App, error) {
+	app, err := s.appStore.GetAppByAKey(akey)
+	if err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	if app.RequestSecret == "" {
+		if app.RequestSecret, err = generateRequestSecret(); err != nil {
+			return nil, err
+		}
+	}
+	app.RequireSignedRequests = required
+
+	if err := s.appStore.UpdateRequestSigning(akey, app.RequireSignedRequests, app.RequestSecret); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// RotateSecret 为应用生成新的请求签名密钥，旧密钥立即失效
+func (s *RequestSigningService) RotateSecret(akey string) (*model.App, error) {
+	app, err := s.appStore.GetAppByAKey(akey)
+	if err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	if app.RequestSecret, err = generateRequestSecret(); err != nil {
+		return nil, err
+	}
+
+	if err := s.appStore.UpdateRequestSigning(akey, app.RequireSignedRequests, app.RequestSecret); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// SignRequest 计算校验请求的签名
+// 签名内容为 时间戳 + "\n" + 随机数 + "\n" + 原始请求体，使用HMAC-SHA256
+func SignRequest(secret, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// generateRequestSecret 生成请求签名密钥
+func generateRequestSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "sec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/semver"
+)
+
+// resolveLatestVersion 计算订阅指定渠道的客户端应获取的最新版本
+// 依次计算该渠道及所有更稳定渠道各自的最新版本，取其中版本号最高者；
+// 版本号相同时优先选择更稳定的渠道。灰度发布未覆盖 deviceID 的版本不参与计算
+func resolveLatestVersion(versions []*model.Version, channel, deviceID string) *model.Version {
+	var latest *model.Version
+	for _, item := range model.Channels {
+		if model.ChannelLevel(item) > model.ChannelLevel(channel) {
+			break
+		}
+		candidate := resolveChannelLatest(versions, item, deviceID)
+		if candidate != nil && (latest == nil || compareVersions(candidate, latest) > 0) {
+			latest = candidate
+		}
+	}
+	return latest
+}
+
+// resolveChannelLatest 计算单个渠道内的最新版本
+// 如果有版本被固定为最新版本（is_latest）且灰度发布覆盖该客户端，直接使用该版本；
+// 否则选择灰度发布覆盖该客户端的版本中语义化版本优先级最高的版本（存在固定版本时不超过固定版本），
+// 优先级相同时选择创建时间较晚的版本
+func resolveChannelLatest(versions []*model.Version, channel, deviceID string) *model.Version {
+	var pinned *model.Version
+	for _, version := range versions {
+		if version.Channel == channel && version.IsLatest {
+			pinned = version
+			break
+		}
+	}
+	if pinned != nil && isRolledOutTo(pinned, deviceID) {
+		return pinned
+	}
+
+	var latest *model.Version
+	for _, version := range versions {
+		if version.Channel != channel || !isRolledOutTo(version, deviceID) {
+			continue
+		}
+		if pinned != nil && compareVersions(version, pinned) > 0 {
+			continue
+		}
+		if latest == nil {
+			latest = version
+			continue
+		}
+		c := compareVersions(version, latest)
+		if c > 0 || (c == 0 && version.CreatedAt.After(latest.CreatedAt)) {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// pendingVersions 返回客户端从当前版本更新到最新版本时跨过的版本
+// 即订阅渠道内已对该客户端发布、高于当前版本且不高于最新版本的全部版本
+func pendingVersions(versions []*model.Version, channel, deviceID string, current, latest *model.Version) []*model.Version {
+	var pending []*model.Version
+	for _, version := range versions {
+		if model.ChannelLevel(version.Channel) > model.ChannelLevel(channel) || !isRolledOutTo(version, deviceID) {
+			continue
+		}
+		if compareVersions(version, current) > 0 && compareVersions(version, latest) <= 0 {
+			pending = append(pending, version)
+		}
+	}
+	return pending
+}
+
+// compareVersions 比较两个版本的先后顺序
+// 均为合法语义化版本号时按版本优先级比较；合法版本号总是高于无法解析的历史版本号，
+// 两者都无法解析时按创建时间比较
+func compareVersions(a, b *model.Version) int {
+	va, errA := semver.Parse(a.Version)
+	vb, errB := semver.Parse(b.Version)
+	switch {
+	case errA == nil && errB == nil:
+		return va.Compare(vb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	case a.CreatedAt.After(b.CreatedAt):
+		return 1
+	case a.CreatedAt.Before(b.CreatedAt):
+		return -1
+	default:
+		return 0
+	}
+}
+
+// isRolledOutTo 判断版本的灰度发布是否覆盖指定客户端
+// 已撤回、暂停或终止的版本不向任何客户端推送；比例未满100%时，
+// 根据版本ID和设备标识的哈希值将客户端稳定地分到0-99号桶中，桶号小于比例的客户端可以收到该版本。
+// 未提供设备标识的客户端只能收到全量发布的版本
+func isRolledOutTo(version *model.Version, deviceID string) bool {
+	if version.IsRevoked() || version.RolloutStatus != model.RolloutActive {
+		return false
+	}
+	if version.RolloutPercent >= 100 {
+		return true
+	}
+	if deviceID == "" || version.RolloutPercent <= 0 {
+		return false
+	}
+	return rolloutBucket(version.ID, deviceID) < version.RolloutPercent
+}
+
+// rolloutBucket 计算客户端在指定版本灰度发布中的桶号（0-99）
+// 使用版本ID作为盐值，使不同版本的首批客户端互不相同
+func rolloutBucket(versionID uint, deviceID string) int {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", versionID, deviceID)))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"time"
+
+	"verkeyoss/internal/config"
+	"verkeyoss/internal/errors"
+)
+
+// maxKeyGraceHours 旧密钥宽限期的上限，与配置项的取值范围一致
+const maxKeyGraceHours = config.MaxKeyGraceHours
+
+// ErrInvalidGracePeriod 宽限期超出范围
+var ErrInvalidGracePeriod = errors.NewValidationError("宽限期必须在0-720小时之间")
+
+// KeyRotation 密钥轮换结果
+type KeyRotation struct {
+	OldKey          string    // 轮换前的密钥
+	NewKey          string    // 轮换后的新密钥
+	OldKeyExpiresAt time.Time // 旧密钥失效时间，宽限期为0时即轮换时间
+}
+
+// graceExpiresAt 计算旧密钥的失效时间
+// 参数 graceHours 为nil时使用配置的默认宽限期，为0时旧密钥立即失效
+func graceExpiresAt(defaultHours int, graceHours *int) (time.Time, error) {
+	hours := defaultHours
+	if graceHours != nil {
+		hours = *graceHours
+	}
+	if hours < 0 || hours > maxKeyGraceHours {
+		return time.Time{}, ErrInvalidGracePeriod
+	}
+	return time.Now().Add(time.Duration(hours) * time.Hour), nil
+}
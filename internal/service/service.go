@@ -1,36 +1,114 @@
 package service
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"log"
+
+	"verkeyoss/internal/blob"
+	"verkeyoss/internal/config"
 	"verkeyoss/internal/store"
 )
 
 // Services 服务层结构体
 
 type Services struct {
-	AuthService         *AuthService
-	AppService          *AppService
-	VersionService      *VersionService
-	CheckService        *CheckService
-	DashboardService    *DashboardService
-	AnnouncementService *AnnouncementService
+	AuthService           *AuthService
+	UserService           *UserService
+	APITokenService       *APITokenService
+	AppService            *AppService
+	VersionService        *VersionService
+	CheckService          *CheckService
+	ForcedUpdateService   *ForcedUpdateService
+	LicenseService        *LicenseService
+	DeviceService         *DeviceService
+	RequestSigningService *RequestSigningService
+	SignatureService      *SignatureService
+	TelemetryService      *TelemetryService
+	DashboardService      *DashboardService
+	AnnouncementService   *AnnouncementService
+	AuditService          *AuditService
+	WebhookService        *WebhookService
+	BackupService         *BackupService
+	ArtifactService       *ArtifactService
+}
+
+// NewServices 创建新的服务层实例，并启动Webhook后台投递协程
+// 响应签名密钥必须已在启动服务时初始化（见 config.EnsureSigningKey）
+func NewServices(store *store.Store, appConfig *config.Config) *Services {
+	signingKey, err := config.GetSigningKey()
+	if err != nil {
+		log.Fatalf("创建响应签名服务失败: %v", err)
+	}
+	services := newServices(store, appConfig, signingKey)
+	services.WebhookService.Start()
+	return services
 }
 
-// NewServices 创建新的服务层实例
-func NewServices(store *store.Store, jwtSecret string, expireHours int) *Services {
-	// 创建认证服务（替代用户服务）
-	authService := NewAuthService(jwtSecret, expireHours)
-	appService := NewAppService(store.NewAppStore())
-	versionService := NewVersionService(store.NewVersionStore())
-	checkService := NewCheckService(store.NewVersionStore(), store.NewAppStore())
-	dashboardService := NewDashboardService(store.NewDashboardStore())
-	announcementService := NewAnnouncementService(store.NewAnnouncementStore())
+// NewCLIServices 创建命令行子命令使用的服务层实例
+// 不启动Webhook投递协程，避免短暂运行的命令行进程领取投递记录而推迟运行中的服务端投递；
+// 产生的事件写入投递队列后由服务端投递。命令行子命令不会签名校验响应，
+// 配置文件中没有响应签名密钥时使用临时密钥，不写回配置文件
+func NewCLIServices(store *store.Store, appConfig *config.Config) *Services {
+	signingKey, err := config.GetSigningKey()
+	if err != nil {
+		if _, signingKey, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			log.Fatalf("创建响应签名服务失败: %v", err)
+		}
+	}
+	return newServices(store, appConfig, signingKey)
+}
+
+// newServices 创建服务层实例，不启动Webhook投递协程
+func newServices(store *store.Store, appConfig *config.Config, signingKey ed25519.PrivateKey) *Services {
+	// 创建认证服务和用户管理服务
+	auditService := NewAuditService(store.NewAuditStore(), appConfig.Audit.RetentionDays)
+	loginLockout := NewLoginLockout(appConfig.Security.LoginMaxFailures, appConfig.Security.LoginLockoutSeconds, appConfig.Security.LoginMaxLockoutSeconds)
+	authService := NewAuthService(store.NewUserStore(), store.NewAPITokenStore(), appConfig.JWT.Secret, appConfig.JWT.ExpireHours, loginLockout, auditService)
+	userService := NewUserService(store.NewUserStore(), auditService)
+	apiTokenService := NewAPITokenService(store.NewAPITokenStore(), store.NewAppStore())
+
+	// 创建制品服务，制品文件保存在配置的制品存储中，本地存储的下载地址使用JWT密钥派生的密钥签名
+	blobStore, err := blob.Open(appConfig)
+	if err != nil {
+		log.Fatalf("创建制品存储失败: %v", err)
+	}
+	artifactService := NewArtifactService(store.NewArtifactStore(), store.NewVersionStore(), blobStore, appConfig.Storage.BaseURL, appConfig.JWT.Secret, appConfig.Storage.DownloadURLTTLSeconds, appConfig.Storage.MaxUploadMB, auditService)
+
+	appService := NewAppService(store.NewAppStore(), *appConfig.Security.KeyGraceHours, auditService, artifactService)
+	webhookService := NewWebhookService(store.NewWebhookStore(), store.NewAppStore(), appConfig.Webhook.MaxAttempts, appConfig.Webhook.TimeoutSeconds, appConfig.Webhook.RetentionDays)
+	versionService := NewVersionService(store.NewVersionStore(), *appConfig.Security.KeyGraceHours, auditService, webhookService, artifactService)
+	forcedUpdateService := NewForcedUpdateService(store.NewForcedUpdateRangeStore(), store.NewAppStore())
+
+	// 创建响应签名服务
+	signatureService := NewSignatureService(signingKey)
+	licenseService := NewLicenseService(store.NewLicenseStore(), store.NewAppStore(), store.NewDeviceStore(), signatureService)
+	deviceService := NewDeviceService(store.NewDeviceStore(), store.NewAppStore())
+	requestSigningService := NewRequestSigningService(store.NewAppStore(), NewMemoryNonceCache(), appConfig.Security.RequestMaxSkewSeconds)
+	telemetryService := NewTelemetryService(store.NewCheckEventStore(), appConfig.Telemetry.BufferSize, appConfig.Telemetry.BatchSize, appConfig.Telemetry.FlushIntervalSeconds, appConfig.Telemetry.RetentionDays)
+	checkService := NewCheckService(store.NewVersionStore(), store.NewAppStore(), store.NewForcedUpdateRangeStore(), licenseService, deviceService, telemetryService, artifactService)
+	dashboardService := NewDashboardService(store.NewDashboardStore(), store.NewAppStore())
+	announcementService := NewAnnouncementService(store.NewAnnouncementStore(), store.NewAppStore(), store.NewVersionStore())
+	backupService := NewBackupService(store.NewBackupStore(), auditService, artifactService)
 
 	return &Services{
-		AuthService:         authService,
-		AppService:          appService,
-		VersionService:      versionService,
-		CheckService:        checkService,
-		DashboardService:    dashboardService,
-		AnnouncementService: announcementService,
+		AuthService:           authService,
+		UserService:           userService,
+		APITokenService:       apiTokenService,
+		AppService:            appService,
+		VersionService:        versionService,
+		CheckService:          checkService,
+		ForcedUpdateService:   forcedUpdateService,
+		LicenseService:        licenseService,
+		DeviceService:         deviceService,
+		RequestSigningService: requestSigningService,
+		SignatureService:      signatureService,
+		TelemetryService:      telemetryService,
+		DashboardService:      dashboardService,
+		AnnouncementService:   announcementService,
+		AuditService:          auditService,
+		WebhookService:        webhookService,
+		BackupService:         backupService,
+		ArtifactService:       artifactService,
 	}
 }
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"verkeyoss/pkg/license"
+)
+
+// SignatureAlgorithm 校验接口响应签名算法
+const SignatureAlgorithm = "Ed25519"
+
+// SignatureService 响应签名服务
+// 使用配置文件中的Ed25519私钥为校验接口的响应签名，客户端使用固定的公钥验证响应未被篡改
+type SignatureService struct {
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+// NewSignatureService 创建响应签名服务实例
+func NewSignatureService(privateKey ed25519.PrivateKey) *SignatureService {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(publicKey)
+	return &SignatureService{
+		privateKey: privateKey,
+		keyID:      hex.EncodeToString(sum[:8]),
+	}
+}
+
+// Sign 对数据签名，返回Base64编码的签名
+func (s *SignatureService) Sign(payload []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, payload))
+}
+
+// PublicKey 返回Base64编码的公钥
+func (s *SignatureService) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.privateKey.Public().(ed25519.PublicKey))
+}
+
+// KeyID 返回密钥标识，即公钥SHA-256哈希的前8字节（十六进制）
+// 客户端可据此判断服务端是否更换了签名密钥
+func (s *SignatureService) KeyID() string {
+	return s.keyID
+}
+
+// SignOfflineLicense 签发离线许可证文件，声明中的密钥标识由签名服务填写
+func (s *SignatureService) SignOfflineLicense(claims *license.Claims) (string, error) {
+	claims.KeyID = s.keyID
+	return license.Sign(claims, s.privateKey)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// 清理过期遥测记录的间隔
+const telemetryPurgeInterval = time.Hour
+
+// User-Agent 的最大保存长度（字符）
+const maxUserAgentLength = 200
+
+// TelemetryService 校验请求遥测服务
+// 校验接口只把遥测记录放入缓冲区，由后台协程按批次写入数据库，避免拖慢校验接口；
+// 缓冲区满时丢弃新记录并计数。后台协程同时定期清理超过保留期限的记录
+type TelemetryService struct {
+	store         store.CheckEventStore
+	events        chan *model.CheckEvent
+	batchSize     int
+	flushInterval time.Duration
+	retentionDays int
+	dropped       atomic.Int64
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewTelemetryService 创建遥测服务实例并启动后台写入协程
+// 参数 bufferSize 为缓冲区大小，batchSize 为每批写入的最大记录数，
+// flushIntervalSeconds 为写入间隔，retentionDays 为记录保留天数（为负数时永久保留）
+func NewTelemetryService(store store.CheckEventStore, bufferSize, batchSize, flushIntervalSeconds, retentionDays int) *TelemetryService {
+	s := &TelemetryService{
+		store:         store,
+		events:        make(chan *model.CheckEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: time.Duration(flushIntervalSeconds) * time.Second,
+		retentionDays: retentionDays,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Record 记录一次校验请求，不会阻塞调用方
+// 客户端IP只保留所在网段，User-Agent 超长时截断
+func (s *TelemetryService) Record(event *model.CheckEvent) {
+	now := time.Now()
+	event.CreatedAt = now
+	event.Hour = now.Truncate(time.Hour).Unix()
+	event.ClientIP = coarseIP(event.ClientIP)
+	if runes := []rune(event.UserAgent); len(runes) > maxUserAgentLength {
+		event.UserAgent = string(runes[:maxUserAgentLength])
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped 返回因缓冲区已满而丢弃的记录数
+func (s *TelemetryService) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 停止后台协程，并写入缓冲区中剩余的记录
+func (s *TelemetryService) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// run 后台写入协程
+func (s *TelemetryService) run() {
+	defer close(s.done)
+
+	flushTicker := time.NewTicker(s.flushInterval)
+	defer flushTicker.Stop()
+	purgeTicker := time.NewTicker(telemetryPurgeInterval)
+	defer purgeTicker.Stop()
+
+	s.purge()
+	batch := make([]*model.CheckEvent, 0, s.batchSize)
+	for {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		case <-flushTicker.C:
+			batch = s.flush(batch)
+		case <-purgeTicker.C:
+			s.purge()
+		case <-s.stop:
+			// 写入缓冲区中剩余的记录后退出
+			for {
+				select {
+				case event := <-s.events:
+					batch = append(batch, event)
+					if len(batch) >= s.batchSize {
+						batch = s.flush(batch)
+					}
+				default:
+					s.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 写入一批记录，返回清空后的批次供复用
+// 写入失败时丢弃该批记录，避免数据库故障时内存持续增长
+func (s *TelemetryService) flush(batch []*model.CheckEvent) []*model.CheckEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := s.store.CreateCheckEvents(batch); err != nil {
+		s.dropped.Add(int64(len(batch)))
+		logger.Errorf("写入校验请求遥测记录失败，丢弃 %d 条记录: %v", len(batch), err)
+	}
+	return batch[:0]
+}
+
+// purge 删除超过保留期限的记录
+func (s *TelemetryService) purge() {
+	if s.retentionDays < 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -s.retentionDays)
+	if _, err := s.store.DeleteCheckEventsBefore(before); err != nil {
+		logger.Errorf("清理过期的校验请求遥测记录失败: %v", err)
+	}
+}
+
+// coarseIP 将IP地址转换为所在网段，IPv4保留前24位，IPv6保留前48位；无法解析时返回空字符串
+func coarseIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
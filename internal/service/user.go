@@ -0,0 +1,195 @@
+package service
+
+import (
+	"strconv"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+	"verkeyoss/internal/validator"
+)
+
+// 预定义错误，使用统一的错误处理
+var (
+	ErrUserNotFound = errors.ErrUserNotFound
+	ErrUserExists   = errors.ErrUserExists
+	ErrLastOwner    = errors.ErrLastOwner
+)
+
+// UserService 用户管理服务
+// 提供管理用户账号及其角色的功能
+
+type UserService struct {
+	store store.UserStore
+	audit *AuditService
+}
+
+// NewUserService 创建用户服务实例
+func NewUserService(store store.UserStore, audit *AuditService) *UserService {
+	return &UserService{store: store, audit: audit}
+}
+
+// CreateUser 创建新用户
+func (s *UserService) CreateUser(actor *Actor, username, password, role string) (*model.User, error) {
+	if err := validator.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	if err := validator.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	if !model.IsValidRole(role) {
+		return nil, errors.NewValidationError("无效的用户角色")
+	}
+
+	// 检查用户名是否已存在
+	if _, err := s.store.GetUserByUsername(username); err == nil {
+		return nil, ErrUserExists
+	}
+
+	hashedPassword, err := model.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Username: username,
+		Password: hashedPassword,
+		Role:     role,
+		IsActive: true,
+	}
+	if err := s.store.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, model.AuditActionCreate, model.AuditResourceUser, userResourceID(user.ID), nil, user)
+	return user, nil
+}
+
+// GetUserList 获取用户列表
+func (s *UserService) GetUserList(page, size int) ([]*model.User, int64, error) {
+	// 分页参数校验
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+
+	return s.store.GetUserList(page, size)
+}
+
+// UpdateUser 更新用户角色、启用状态或重置密码
+// 参数 password 为空时不修改密码
+func (s *UserService) UpdateUser(actor *Actor, id uint, role string, isActive bool, password string) (*model.User, error) {
+	user, err := s.store.GetUserByID(id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	before := *user
+
+	if !model.IsValidRole(role) {
+		return nil, errors.NewValidationError("无效的用户角色")
+	}
+
+	// 降级或禁用所有者时，需确保仍保留至少一个启用状态的所有者
+	if user.Role == model.RoleOwner && user.IsActive && (role != model.RoleOwner || !isActive) {
+		if err := s.ensureAnotherOwner(); err != nil {
+			return nil, err
+		}
+	}
+
+	if password != "" {
+		if err := validator.ValidatePassword(password); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := model.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = hashedPassword
+	}
+	user.Role = role
+	user.IsActive = isActive
+
+	if err := s.store.UpdateUser(user); err != nil {
+		return nil, err
+	}
+
+	// 密码不会出现在字段差异中，重置密码时单独标记
+	after := map[string]interface{}{"role": user.Role, "is_active": user.IsActive}
+	if password != "" {
+		after["password_reset"] = true
+	}
+	s.audit.Record(actor, model.AuditActionUpdate, model.AuditResourceUser, userResourceID(user.ID),
+		map[string]interface{}{"role": before.Role, "is_active": before.IsActive}, after)
+	return user, nil
+}
+
+// ResetPassword 按用户名重置密码并重新启用账号，用于无法登录时恢复管理员账号
+// 角色保持不变；若重置的是配置文件中的管理员账号，会同步写回配置文件
+func (s *UserService) ResetPassword(actor *Actor, username, password string) (*model.User, error) {
+	user, err := s.store.GetUserByUsername(username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	// UpdateUser 在密码为空时不修改密码，这里必须提供新密码
+	if err := validator.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	// 先同步配置文件，写入失败时不修改数据库
+	restoreConfig, err := syncAdminPassword(user.Username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = s.UpdateUser(actor, user.ID, user.Role, true, password)
+	if err != nil {
+		restoreConfig()
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteUser 删除用户
+// 参数 actor 为执行删除操作的用户，不允许删除自己
+func (s *UserService) DeleteUser(actor *Actor, id uint) error {
+	if id == actor.UserID {
+		return errors.NewValidationError("不能删除当前登录的用户")
+	}
+
+	user, err := s.store.GetUserByID(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if user.Role == model.RoleOwner && user.IsActive {
+		if err := s.ensureAnotherOwner(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.store.DeleteUser(id); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, model.AuditActionDelete, model.AuditResourceUser, userResourceID(id), user, nil)
+	return nil
+}
+
+// userResourceID 返回用户在审计日志中的资源标识
+func userResourceID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// ensureAnotherOwner 确认除当前所有者外还存在其他启用状态的所有者
+func (s *UserService) ensureAnotherOwner() error {
+	count, err := s.store.CountActiveUsersByRole(model.RoleOwner)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
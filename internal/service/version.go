@@ -2,6 +2,9 @@ package service
 
 import (
 	"errors"
+	"time"
+
+	apperrors "verkeyoss/internal/errors"
 	"verkeyoss/internal/model"
 	"verkeyoss/internal/store"
 )
@@ -9,27 +12,64 @@ import (
 var (
 	ErrVersionNotFound = errors.New("版本不存在")
 	ErrAKeyNotFound    = errors.New("软件标识不存在")
+	ErrInvalidChannel  = apperrors.NewValidationError("无效的发布渠道，可选值为 stable、beta、nightly")
+
+	ErrInvalidRolloutPercent = apperrors.NewValidationError("灰度发布比例必须在1-100之间")
+	ErrRolloutNotPaused      = apperrors.NewConflictError("只有已暂停的灰度发布可以恢复")
+	ErrRolloutNotActive      = apperrors.NewConflictError("只有进行中的灰度发布可以暂停")
+
+	ErrVersionRevoked    = apperrors.NewConflictError("版本已撤回")
+	ErrVersionNotRevoked = apperrors.NewConflictError("版本未撤回")
 )
 
 // VersionService 版本服务
 
 type VersionService struct {
-	store store.VersionStore
+	store         store.VersionStore
+	keyGraceHours int // 轮换VKey后旧VKey的默认有效时长（小时）
+	audit         *AuditService
+	webhooks      *WebhookService
+	artifacts     *ArtifactService
 }
 
 // NewVersionService 创建版本服务实例
-func NewVersionService(store store.VersionStore) *VersionService {
-	return &VersionService{store: store}
+func NewVersionService(store store.VersionStore, keyGraceHours int, audit *AuditService, webhooks *WebhookService, artifacts *ArtifactService) *VersionService {
+	return &VersionService{store: store, keyGraceHours: keyGraceHours, audit: audit, webhooks: webhooks, artifacts: artifacts}
+}
+
+// ChannelSummary 发布渠道概要
+type ChannelSummary struct {
+	Channel       string         // 发布渠道
+	VersionCount  int            // 该渠道的版本数量
+	LatestVersion *model.Version // 订阅该渠道的全部客户端都能获取的最新版本，可能来自更稳定的渠道
 }
 
 // CreateVersion 创建新版本
-func (s *VersionService) CreateVersion(akey, version, description string, isLatest bool, isForcedUpdate bool) (*model.Version, error) {
+// 参数 channel 为空时发布到稳定版渠道
+// 参数 rolloutPercent 为0时全量发布
+func (s *VersionService) CreateVersion(actor *Actor, akey, version, channel, description string, isLatest bool, isForcedUpdate bool, rolloutPercent int) (*model.Version, error) {
+	if channel == "" {
+		channel = model.ChannelStable
+	}
+	if !model.IsValidChannel(channel) {
+		return nil, ErrInvalidChannel
+	}
+	if rolloutPercent == 0 {
+		rolloutPercent = 100
+	}
+	if rolloutPercent < 1 || rolloutPercent > 100 {
+		return nil, ErrInvalidRolloutPercent
+	}
+
 	newVersion := &model.Version{
 		AKey:           akey,
 		Version:        version,
+		Channel:        channel,
 		Description:    description,
 		IsLatest:       isLatest,
 		IsForcedUpdate: isForcedUpdate,
+		RolloutPercent: rolloutPercent,
+		RolloutStatus:  model.RolloutActive,
 	}
 
 	err := s.store.CreateVersion(newVersion)
@@ -37,11 +77,18 @@ func (s *VersionService) CreateVersion(akey, version, description string, isLate
 		return nil, err
 	}
 
+	s.audit.Record(actor, model.AuditActionCreate, model.AuditResourceVersion, newVersion.VKey, nil, newVersion)
+	s.webhooks.NotifyVersion(model.WebhookEventVersionCreated, newVersion, nil)
 	return newVersion, nil
 }
 
 // GetVersionList 获取版本列表
-func (s *VersionService) GetVersionList(akey string, page, size int) ([]*model.Version, int64, error) {
+// 参数 channel 为空时返回所有渠道的版本
+func (s *VersionService) GetVersionList(akey, channel string, page, size int) ([]*model.Version, int64, error) {
+	if channel != "" && !model.IsValidChannel(channel) {
+		return nil, 0, ErrInvalidChannel
+	}
+
 	// 分页参数校验
 	if page <= 0 {
 		page = 1
@@ -50,7 +97,31 @@ func (s *VersionService) GetVersionList(akey string, page, size int) ([]*model.V
 		size = 10
 	}
 
-	return s.store.GetVersionListByAKey(akey, page, size)
+	return s.store.GetVersionListByAKey(akey, channel, page, size)
+}
+
+// GetChannelSummaries 获取应用各发布渠道的概要信息
+func (s *VersionService) GetChannelSummaries(akey string) ([]*ChannelSummary, error) {
+	versions, err := s.store.GetVersionsByAKey(akey)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*ChannelSummary, 0, len(model.Channels))
+	for _, channel := range model.Channels {
+		summary := &ChannelSummary{
+			Channel:       channel,
+			LatestVersion: resolveLatestVersion(versions, channel, ""),
+		}
+		for _, version := range versions {
+			if version.Channel == channel {
+				summary.VersionCount++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
 }
 
 // GetVersionInfo 获取版本信息
@@ -64,17 +135,26 @@ func (s *VersionService) GetVersionInfo(vkey string) (*model.Version, error) {
 }
 
 // UpdateVersion 更新版本信息
-func (s *VersionService) UpdateVersion(vkey, version, description string, isLatest bool, isForcedUpdate bool) error {
+// 参数 version、channel、description 为空时不修改
+func (s *VersionService) UpdateVersion(actor *Actor, vkey, version, channel, description string, isLatest bool, isForcedUpdate bool) error {
+	if channel != "" && !model.IsValidChannel(channel) {
+		return ErrInvalidChannel
+	}
+
 	// 获取版本信息
 	versionInfo, err := s.store.GetVersionByVKey(vkey)
 	if err != nil {
 		return ErrVersionNotFound
 	}
+	before := *versionInfo
 
 	// 更新字段
 	if version != "" {
 		versionInfo.Version = version
 	}
+	if channel != "" {
+		versionInfo.Channel = channel
+	}
 	if description != "" {
 		versionInfo.Description = description
 	}
@@ -83,16 +163,161 @@ func (s *VersionService) UpdateVersion(vkey, version, description string, isLate
 	// 更新强制更新字段
 	versionInfo.IsForcedUpdate = isForcedUpdate
 
-	return s.store.UpdateVersion(versionInfo)
+	if err := s.store.UpdateVersion(versionInfo); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, model.AuditActionUpdate, model.AuditResourceVersion, vkey, &before, versionInfo)
+	s.webhooks.NotifyVersion(model.WebhookEventVersionUpdated, versionInfo, &before)
+	return nil
 }
 
-// DeleteVersion 删除版本
-func (s *VersionService) DeleteVersion(vkey string) error {
+// DeleteVersion 删除版本及其制品
+func (s *VersionService) DeleteVersion(actor *Actor, vkey string) error {
 	// 检查版本是否存在
-	_, err := s.store.GetVersionByVKey(vkey)
+	version, err := s.store.GetVersionByVKey(vkey)
 	if err != nil {
 		return ErrVersionNotFound
 	}
 
-	return s.store.DeleteVersion(vkey)
+	artifacts := s.artifacts.versionArtifacts(version.ID)
+	if err := s.store.DeleteVersion(vkey); err != nil {
+		return err
+	}
+	s.artifacts.deleteBlobs(artifacts)
+
+	s.audit.Record(actor, model.AuditActionDelete, model.AuditResourceVersion, vkey, version, nil)
+	s.webhooks.NotifyVersion(model.WebhookEventVersionDeleted, version, nil)
+	return nil
+}
+
+// SetRolloutPercent 设置灰度发布比例
+// 设置后灰度发布恢复为进行中状态，可用于扩大、缩小或重新开始已终止的灰度发布
+func (s *VersionService) SetRolloutPercent(actor *Actor, vkey string, percent int) (*model.Version, error) {
+	if percent < 1 || percent > 100 {
+		return nil, ErrInvalidRolloutPercent
+	}
+	return s.updateRollout(actor, vkey, func(version *model.Version) error {
+		version.RolloutPercent = percent
+		version.RolloutStatus = model.RolloutActive
+		return nil
+	})
+}
+
+// PauseRollout 暂停灰度发布
+// 暂停期间不再向尚未更新的客户端推送该版本，恢复后按原比例继续
+func (s *VersionService) PauseRollout(actor *Actor, vkey string) (*model.Version, error) {
+	return s.updateRollout(actor, vkey, func(version *model.Version) error {
+		if version.RolloutStatus != model.RolloutActive {
+			return ErrRolloutNotActive
+		}
+		version.RolloutStatus = model.RolloutPaused
+		return nil
+	})
+}
+
+// ResumeRollout 恢复已暂停的灰度发布
+func (s *VersionService) ResumeRollout(actor *Actor, vkey string) (*model.Version, error) {
+	return s.updateRollout(actor, vkey, func(version *model.Version) error {
+		if version.RolloutStatus != model.RolloutPaused {
+			return ErrRolloutNotPaused
+		}
+		version.RolloutStatus = model.RolloutActive
+		return nil
+	})
+}
+
+// HaltRollout 终止灰度发布
+// 终止后不再向任何尚未更新的客户端推送该版本，需重新设置比例才会继续
+func (s *VersionService) HaltRollout(actor *Actor, vkey string) (*model.Version, error) {
+	return s.updateRollout(actor, vkey, func(version *model.Version) error {
+		version.RolloutStatus = model.RolloutHalted
+		return nil
+	})
+}
+
+// updateRollout 读取版本、应用灰度发布状态变更并保存
+func (s *VersionService) updateRollout(actor *Actor, vkey string, apply func(version *model.Version) error) (*model.Version, error) {
+	version, err := s.store.GetVersionByVKey(vkey)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	before := *version
+
+	if err := apply(version); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpdateRollout(vkey, version.RolloutPercent, version.RolloutStatus); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, model.AuditActionRollout, model.AuditResourceVersion, vkey, &before, version)
+	return version, nil
+}
+
+// RevokeVersion 撤回版本
+// 撤回后该VKey无法通过校验，版本也不再作为更新推送给客户端，但版本记录和历史保持不变
+func (s *VersionService) RevokeVersion(actor *Actor, vkey, reason string) (*model.Version, error) {
+	version, err := s.store.GetVersionByVKey(vkey)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	if version.IsRevoked() {
+		return nil, ErrVersionRevoked
+	}
+	before := *version
+
+	now := time.Now()
+	if err := s.store.UpdateRevocation(vkey, &now, reason); err != nil {
+		return nil, err
+	}
+
+	version.RevokedAt = &now
+	version.RevokeReason = reason
+	s.audit.Record(actor, model.AuditActionRevoke, model.AuditResourceVersion, vkey, &before, version)
+	return version, nil
+}
+
+// RestoreVersion 恢复已撤回的版本
+func (s *VersionService) RestoreVersion(actor *Actor, vkey string) (*model.Version, error) {
+	version, err := s.store.GetVersionByVKey(vkey)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	if !version.IsRevoked() {
+		return nil, ErrVersionNotRevoked
+	}
+	before := *version
+
+	if err := s.store.UpdateRevocation(vkey, nil, ""); err != nil {
+		return nil, err
+	}
+
+	version.RevokedAt = nil
+	version.RevokeReason = ""
+	s.audit.Record(actor, model.AuditActionRestore, model.AuditResourceVersion, vkey, &before, version)
+	return version, nil
+}
+
+// RotateVKey 轮换版本的VKey
+// 旧VKey在宽限期内仍可通过校验，参数 graceHours 为nil时使用默认宽限期
+func (s *VersionService) RotateVKey(actor *Actor, vkey string, graceHours *int) (*KeyRotation, error) {
+	expiresAt, err := graceExpiresAt(s.keyGraceHours, graceHours)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.GetVersionByVKey(vkey); err != nil {
+		return nil, ErrVersionNotFound
+	}
+
+	newVKey, err := s.store.RotateVKey(vkey, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, model.AuditActionRotateKey, model.AuditResourceVersion, vkey,
+		map[string]interface{}{"vkey": vkey}, map[string]interface{}{"vkey": newVKey, "old_key_expires_at": expiresAt})
+	return &KeyRotation{OldKey: vkey, NewKey: newVKey, OldKeyExpiresAt: expiresAt}, nil
 }
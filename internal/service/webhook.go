@@ -0,0 +1,581 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"verkeyoss/internal/errors"
+	"verkeyoss/internal/logger"
+	"verkeyoss/internal/model"
+	"verkeyoss/internal/store"
+)
+
+// 预定义错误
+var (
+	ErrWebhookNotFound       = errors.ErrWebhookNotFound
+	ErrInvalidWebhookName    = errors.NewValidationError("Webhook名称不能为空且不能超过100个字符")
+	ErrInvalidWebhookURL     = errors.NewValidationError("Webhook地址必须是以 http:// 或 https:// 开头的完整URL，且不能超过500个字符")
+	ErrInvalidWebhookEvents  = errors.NewValidationError("至少需要订阅一个事件，可选值为 version.created、version.updated、version.deleted")
+	ErrInvalidDeliveryStatus = errors.NewValidationError("无效的投递状态，可选值为 pending、succeeded、failed")
+)
+
+const (
+	// 检查到期投递的间隔，新事件写入后会立即唤醒投递协程
+	webhookPollInterval = 5 * time.Second
+	// 每次领取的最大投递数
+	webhookBatchSize = 50
+	// 首次重试的等待时间，此后每次翻倍
+	webhookRetryBaseDelay = 30 * time.Second
+	// 重试等待时间上限
+	webhookRetryMaxDelay = 6 * time.Hour
+	// 清理过期投递记录的间隔
+	webhookPurgeInterval = time.Hour
+	// 保存的响应内容和错误信息的最大长度
+	maxDeliveryResponseLength = 1000
+	maxDeliveryErrorLength    = 500
+)
+
+// WebhookPayload Webhook请求体
+type WebhookPayload struct {
+	ID        string      `json:"id"`         // 事件唯一标识，重试时不变
+	Event     string      `json:"event"`      // 事件类型
+	CreatedAt string      `json:"created_at"` // 事件发生时间（RFC3339）
+	App       *WebhookApp `json:"app"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookApp Webhook事件所属的应用，不包含AKey
+type WebhookApp struct {
+	Name string `json:"name"`
+}
+
+// WebhookVersion Webhook事件中的版本信息，不包含AKey和VKey
+type WebhookVersion struct {
+	ID             uint   `json:"id"`
+	Version        string `json:"version"`
+	Channel        string `json:"channel"`
+	Description    string `json:"description"`
+	IsLatest       bool   `json:"is_latest"`
+	IsForcedUpdate bool   `json:"is_forced_update"`
+	RolloutPercent int    `json:"rollout_percent"`
+	RolloutStatus  string `json:"rollout_status"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// WebhookVersionData 版本事件的数据
+type WebhookVersionData struct {
+	Version  *WebhookVersion `json:"version"`
+	Previous *WebhookVersion `json:"previous,omitempty"` // 更新前的版本信息，仅 version.updated 事件返回
+}
+
+// WebhookService Webhook服务
+// 事件发生时为每个订阅了该事件的Webhook写入一条投递记录，由后台协程从数据库领取到期的记录并投递，
+// 失败时按指数退避重试，超过最大尝试次数后标记为失败。投递队列保存在数据库中，服务重启后继续投递
+type WebhookService struct {
+	store         store.WebhookStore
+	appStore      store.AppStore
+	client        *http.Client
+	maxAttempts   int
+	retentionDays int
+	wake          chan struct{}
+	ctx           context.Context
+	cancel        context.CancelFunc
+	done          chan struct{}
+	startOnce     sync.Once
+	started       bool
+	closeOnce     sync.Once
+}
+
+// NewWebhookService 创建Webhook服务实例，调用 Start 后才启动后台投递协程
+// 参数 maxAttempts 为每次投递的最大尝试次数，timeoutSeconds 为单次请求的超时时间，
+// retentionDays 为已完成的投递记录保留天数（为负数时永久保留）
+func NewWebhookService(store store.WebhookStore, appStore store.AppStore, maxAttempts, timeoutSeconds, retentionDays int) *WebhookService {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &WebhookService{
+		store:    store,
+		appStore: appStore,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutSeconds) * time.Second,
+			// 不跟随重定向，重定向响应视为投递失败
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts:   maxAttempts,
+		retentionDays: retentionDays,
+		wake:          make(chan struct{}, 1),
+		ctx:           ctx,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+	}
+	return s
+}
+
+// Start 启动后台投递协程，重复调用不会启动多个协程
+// 未启动时事件只写入投递队列，由其他启动了投递协程的服务进程投递
+func (s *WebhookService) Start() {
+	s.startOnce.Do(func() {
+		s.started = true
+		go s.run()
+	})
+}
+
+// CreateWebhook 为应用创建Webhook订阅，自动生成签名密钥
+func (s *WebhookService) CreateWebhook(akey, name, webhookURL string, events []string) (*model.Webhook, error) {
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	webhook := &model.Webhook{AKey: akey, IsActive: true}
+	if err := applyWebhookFields(webhook, name, webhookURL, events); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+
+	if err := s.store.CreateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// GetWebhooks 获取应用的全部Webhook订阅
+func (s *WebhookService) GetWebhooks(akey string) ([]*model.Webhook, error) {
+	if _, err := s.appStore.GetAppByAKey(akey); err != nil {
+		return nil, ErrAppNotFound
+	}
+	return s.store.GetWebhooksByAKey(akey)
+}
+
+// GetWebhook 获取应用的Webhook订阅
+func (s *WebhookService) GetWebhook(akey string, id uint) (*model.Webhook, error) {
+	webhook, err := s.store.GetWebhookByID(id)
+	if err != nil || webhook.AKey != akey {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook 更新Webhook订阅
+// 参数 name、webhookURL 为空时不修改，events 为nil时不修改，isActive 为nil时不修改
+func (s *WebhookService) UpdateWebhook(akey string, id uint, name, webhookURL string, events []string, isActive *bool) (*model.Webhook, error) {
+	webhook, err := s.GetWebhook(akey, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = webhook.Name
+	}
+	if webhookURL == "" {
+		webhookURL = webhook.URL
+	}
+	if events == nil {
+		events = webhook.EventList()
+	}
+	if err := applyWebhookFields(webhook, name, webhookURL, events); err != nil {
+		return nil, err
+	}
+	if isActive != nil {
+		webhook.IsActive = *isActive
+	}
+
+	if err := s.store.UpdateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// RotateSecret 为Webhook生成新的签名密钥，旧密钥立即失效，尚未投递的事件也使用新密钥签名
+func (s *WebhookService) RotateSecret(akey string, id uint) (*model.Webhook, error) {
+	webhook, err := s.GetWebhook(akey, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret, err = generateWebhookSecret(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpdateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook 删除Webhook订阅及其投递记录
+func (s *WebhookService) DeleteWebhook(akey string, id uint) error {
+	if _, err := s.GetWebhook(akey, id); err != nil {
+		return err
+	}
+	return s.store.DeleteWebhook(id)
+}
+
+// GetDeliveries 获取Webhook的投递记录
+// 参数 status 为空时返回全部状态的记录
+func (s *WebhookService) GetDeliveries(akey string, id uint, status string, page, size int) ([]*model.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhook(akey, id); err != nil {
+		return nil, 0, err
+	}
+	switch status {
+	case "", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryFailed:
+	default:
+		return nil, 0, ErrInvalidDeliveryStatus
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+	return s.store.GetDeliveryList(id, status, page, size)
+}
+
+// SendTestEvent 向Webhook发送一个测试事件，停用的Webhook也可以发送
+func (s *WebhookService) SendTestEvent(akey string, id uint) (*model.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(akey, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.enqueue(akey, model.WebhookEventPing, map[string]string{"message": "这是一条测试事件"}, []*model.Webhook{webhook})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// NotifyVersion 通知订阅了版本事件的Webhook
+// 参数 previous 为更新前的版本，仅 version.updated 事件需要。
+// 写入失败不影响已完成的操作，只记录错误日志
+func (s *WebhookService) NotifyVersion(event string, version, previous *model.Version) {
+	data := &WebhookVersionData{Version: newWebhookVersion(version)}
+	if previous != nil {
+		data.Previous = newWebhookVersion(previous)
+	}
+
+	webhooks, err := s.store.GetWebhooksByAKey(version.AKey)
+	if err != nil {
+		logger.Errorf("获取应用的Webhook订阅失败 (%s): %v", event, err)
+		return
+	}
+	subscribed := make([]*model.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.IsActive && webhook.Subscribes(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	if _, err := s.enqueue(version.AKey, event, data, subscribed); err != nil {
+		logger.Errorf("写入Webhook投递记录失败 (%s): %v", event, err)
+	}
+}
+
+// Close 停止后台投递协程，正在进行的投递会被取消，并在领取期限过后重新投递
+// 投递协程未启动时只阻止之后再启动
+func (s *WebhookService) Close() {
+	s.closeOnce.Do(func() {
+		s.startOnce.Do(func() {})
+		s.cancel()
+		if s.started {
+			<-s.done
+		}
+	})
+}
+
+// enqueue 为每个Webhook写入一条投递记录，并唤醒投递协程
+func (s *WebhookService) enqueue(akey, event string, data interface{}, webhooks []*model.Webhook) ([]*model.WebhookDelivery, error) {
+	eventID, err := generateWebhookEventID()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &WebhookPayload{
+		ID:        eventID,
+		Event:     event,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		App:       &WebhookApp{},
+		Data:      data,
+	}
+	if app, err := s.appStore.GetAppByAKey(akey); err == nil {
+		payload.App.Name = app.Name
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	deliveries := make([]*model.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			AKey:          akey,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := s.store.CreateDeliveries(deliveries); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return deliveries, nil
+}
+
+// run 后台投递协程
+func (s *WebhookService) run() {
+	defer close(s.done)
+
+	pollTicker := time.NewTicker(webhookPollInterval)
+	defer pollTicker.Stop()
+	purgeTicker := time.NewTicker(webhookPurgeInterval)
+	defer purgeTicker.Stop()
+
+	s.purge()
+	for {
+		s.deliverDue()
+		select {
+		case <-pollTicker.C:
+		case <-s.wake:
+		case <-purgeTicker.C:
+			s.purge()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// deliverDue 领取并投递全部到期的记录
+func (s *WebhookService) deliverDue() {
+	for s.ctx.Err() == nil {
+		now := time.Now()
+		deliveries, err := s.store.GetDueDeliveries(now, webhookBatchSize)
+		if err != nil {
+			logger.Errorf("获取待投递的Webhook记录失败: %v", err)
+			return
+		}
+
+		for _, delivery := range deliveries {
+			if s.ctx.Err() != nil {
+				return
+			}
+			// 领取期限覆盖一次请求的超时时间，期限内其他实例不会重复投递
+			claimed, err := s.store.ClaimDelivery(delivery.ID, now, time.Now().Add(s.client.Timeout+time.Minute))
+			if err != nil {
+				logger.Errorf("领取Webhook投递记录失败 (%d): %v", delivery.ID, err)
+				continue
+			}
+			if claimed {
+				s.attempt(delivery)
+			}
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attempt 投递一次并保存结果，失败时安排重试或标记为失败
+func (s *WebhookService) attempt(delivery *model.WebhookDelivery) {
+	webhook, err := s.store.GetWebhookByID(delivery.WebhookID)
+	if err != nil {
+		// Webhook已被删除时投递记录随之删除；查询失败时领取期限过后重新投递
+		return
+	}
+
+	status, body, err := s.send(webhook, delivery)
+	if s.ctx.Err() != nil {
+		// 服务正在退出，保留领取状态，领取期限过后重新投递
+		return
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = truncateUTF8(body, maxDeliveryResponseLength)
+	delivery.Error = ""
+
+	if err == nil && status >= 200 && status < 300 {
+		delivery.Status = model.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	} else {
+		if err != nil {
+			delivery.Error = truncateUTF8(err.Error(), maxDeliveryErrorLength)
+		} else {
+			delivery.Error = "响应状态码 " + strconv.Itoa(status)
+		}
+		if delivery.Attempts >= s.maxAttempts {
+			delivery.Status = model.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = now.Add(webhookRetryDelay(delivery.Attempts))
+		}
+	}
+
+	if err := s.store.UpdateDeliveryAttempt(delivery); err != nil {
+		logger.Errorf("保存Webhook投递结果失败 (%d): %v", delivery.ID, err)
+	}
+}
+
+// send 发送签名的请求，返回响应状态码和响应内容
+func (s *WebhookService) send(webhook *model.Webhook, delivery *model.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "VerKeyOSS-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Id", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(SignWebhookPayload(webhook.Secret, timestamp, body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxDeliveryResponseLength))
+	return resp.StatusCode, string(respBody), nil
+}
+
+// purge 删除超过保留期限的已完成投递记录
+func (s *WebhookService) purge() {
+	if s.retentionDays < 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -s.retentionDays)
+	if _, err := s.store.DeleteDeliveriesBefore(before); err != nil {
+		logger.Errorf("清理过期的Webhook投递记录失败: %v", err)
+	}
+}
+
+// SignWebhookPayload 计算Webhook请求的签名
+// 签名内容为 时间戳 + "\n" + 原始请求体，使用HMAC-SHA256
+func SignWebhookPayload(secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// webhookRetryDelay 返回第 attempts 次尝试失败后的重试等待时间
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMaxDelay {
+			return webhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// applyWebhookFields 校验并设置Webhook的名称、地址和订阅事件
+func applyWebhookFields(webhook *model.Webhook, name, webhookURL string, events []string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return ErrInvalidWebhookName
+	}
+
+	webhookURL = strings.TrimSpace(webhookURL)
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(webhookURL) > 500 {
+		return ErrInvalidWebhookURL
+	}
+
+	var eventList []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !model.IsValidWebhookEvent(event) {
+			return ErrInvalidWebhookEvents
+		}
+		if !seen[event] {
+			seen[event] = true
+			eventList = append(eventList, event)
+		}
+	}
+	if len(eventList) == 0 {
+		return ErrInvalidWebhookEvents
+	}
+
+	webhook.Name = name
+	webhook.URL = webhookURL
+	webhook.Events = strings.Join(eventList, ",")
+	return nil
+}
+
+// newWebhookVersion 将版本转换为Webhook事件中的版本信息
+func newWebhookVersion(version *model.Version) *WebhookVersion {
+	return &WebhookVersion{
+		ID:             version.ID,
+		Version:        version.Version,
+		Channel:        version.Channel,
+		Description:    version.Description,
+		IsLatest:       version.IsLatest,
+		IsForcedUpdate: version.IsForcedUpdate,
+		RolloutPercent: version.RolloutPercent,
+		RolloutStatus:  version.RolloutStatus,
+		CreatedAt:      version.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// generateWebhookSecret 生成Webhook签名密钥
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// generateWebhookEventID 生成Webhook事件唯一标识
+func generateWebhookEventID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "evt_" + hex.EncodeToString(buf), nil
+}
+
+// truncateUTF8 将字符串截断到不超过 maxBytes 字节，并去除无效的UTF-8字符
+func truncateUTF8(value string, maxBytes int) string {
+	if len(value) > maxBytes {
+		value = value[:maxBytes]
+	}
+	return strings.ToValidUTF8(value, "")
+}
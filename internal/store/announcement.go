@@ -1,7 +1,11 @@
 package store
 
 import (
+	"time"
+
 	"verkeyoss/internal/model"
+
+	"gorm.io/gorm"
 )
 
 // AnnouncementStoreImpl 公告存储实现
@@ -14,14 +18,86 @@ func (s *Store) NewAnnouncementStore() *AnnouncementStoreImpl {
 	return &AnnouncementStoreImpl{Store: s}
 }
 
-// GetActiveAnnouncements 获取激活的公告列表
-// 返回所有状态为激活的公告，按发布日期降序排序
-func (s *AnnouncementStoreImpl) GetActiveAnnouncements() ([]*model.Announcement, error) {
+// CreateAnnouncement 创建公告
+// is_active 字段有默认值，GORM创建时会把值为false的该字段替换为默认值，因此未激活的公告在创建后单独更新
+func (s *AnnouncementStoreImpl) CreateAnnouncement(announcement *model.Announcement) error {
+	isActive := announcement.IsActive
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(announcement).Error; err != nil {
+			return err
+		}
+		if !isActive {
+			return tx.Model(announcement).Update("is_active", false).Error
+		}
+		return nil
+	})
+}
+
+// GetAnnouncementByID 根据ID获取公告
+func (s *AnnouncementStoreImpl) GetAnnouncementByID(id uint) (*model.Announcement, error) {
+	var announcement model.Announcement
+	err := s.DB.First(&announcement, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// GetAnnouncementList 获取公告列表（分页），按发布日期降序排序
+// 参数 now 用于按状态筛选
+func (s *AnnouncementStoreImpl) GetAnnouncementList(query *model.AnnouncementQuery, now time.Time, page, size int) ([]*model.Announcement, int64, error) {
+	var announcements []*model.Announcement
+	var total int64
+
+	db := s.DB.Model(&model.Announcement{})
+	switch query.Status {
+	case model.AnnouncementDraft:
+		db = db.Where("is_active = ?", false)
+	case model.AnnouncementScheduled:
+		db = db.Where("is_active = ? AND publish_date > ? AND (unpublish_at IS NULL OR unpublish_at > ?)", true, now, now)
+	case model.AnnouncementPublished:
+		db = publishedAnnouncements(db, now)
+	case model.AnnouncementExpired:
+		db = db.Where("is_active = ? AND unpublish_at <= ?", true, now)
+	}
+	if query.AKey != "" {
+		db = db.Where("a_keys = '' OR a_keys IS NULL OR a_keys LIKE ?", "%"+query.AKey+"%")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	if err := db.Order("publish_date DESC, id DESC").Offset(offset).Limit(size).Find(&announcements).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return announcements, total, nil
+}
+
+// UpdateAnnouncement 更新公告
+func (s *AnnouncementStoreImpl) UpdateAnnouncement(announcement *model.Announcement) error {
+	return s.DB.Save(announcement).Error
+}
+
+// DeleteAnnouncement 删除公告
+func (s *AnnouncementStoreImpl) DeleteAnnouncement(id uint) error {
+	return s.DB.Delete(&model.Announcement{}, id).Error
+}
+
+// GetActiveAnnouncements 获取当前已发布的公告列表
+// 返回已激活、已到发布日期且未到下线时间的公告，按发布日期降序排序
+func (s *AnnouncementStoreImpl) GetActiveAnnouncements(now time.Time) ([]*model.Announcement, error) {
 	var announcements []*model.Announcement
-	// 查询所有激活状态的公告，并按发布日期降序排序
-	err := s.DB.Where("is_active = ?", true).Order("publish_date DESC").Find(&announcements).Error
+	err := publishedAnnouncements(s.DB, now).Order("publish_date DESC, id DESC").Find(&announcements).Error
 	if err != nil {
 		return nil, err
 	}
 	return announcements, nil
 }
+
+// publishedAnnouncements 添加筛选当前已发布公告的查询条件
+func publishedAnnouncements(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("is_active = ? AND publish_date <= ? AND (unpublish_at IS NULL OR unpublish_at > ?)", true, now, now)
+}
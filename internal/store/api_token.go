@@ -0,0 +1,67 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+)
+
+// APITokenStoreImpl API令牌存储实现
+type APITokenStoreImpl struct {
+	*Store
+}
+
+// NewAPITokenStore 创建API令牌存储实例
+func (s *Store) NewAPITokenStore() *APITokenStoreImpl {
+	return &APITokenStoreImpl{Store: s}
+}
+
+// CreateAPIToken 创建新的API令牌
+func (s *APITokenStoreImpl) CreateAPIToken(token *model.APIToken) error {
+	return s.DB.Create(token).Error
+}
+
+// GetAPITokenByID 根据ID获取API令牌
+func (s *APITokenStoreImpl) GetAPITokenByID(id uint) (*model.APIToken, error) {
+	var token model.APIToken
+	err := s.DB.First(&token, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetAPITokenByHash 根据令牌哈希获取API令牌
+func (s *APITokenStoreImpl) GetAPITokenByHash(tokenHash string) (*model.APIToken, error) {
+	var token model.APIToken
+	err := s.DB.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetAPITokenList 获取API令牌列表
+// 参数 userID 为0时返回所有用户的令牌
+func (s *APITokenStoreImpl) GetAPITokenList(userID uint) ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+	query := s.DB.Order("created_at DESC")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken 吊销API令牌
+func (s *APITokenStoreImpl) RevokeAPIToken(id uint, revokedAt time.Time) error {
+	return s.DB.Model(&model.APIToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", revokedAt).Error
+}
+
+// UpdateLastUsed 更新API令牌最近使用时间
+func (s *APITokenStoreImpl) UpdateLastUsed(id uint, usedAt time.Time) error {
+	return s.DB.Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -1,9 +1,12 @@
 package store
 
 import (
+	"time"
+
 	"verkeyoss/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // AppStoreImpl 应用存储实现
@@ -93,35 +96,161 @@ func (s *AppStoreImpl) GetAppListByUserID(userID uint, page, size int) ([]*model
 	return apps, total, nil
 }
 
+// ResolveApp 根据AKey获取应用信息，宽限期内的旧AKey映射到轮换后的应用
+func (s *AppStoreImpl) ResolveApp(akey string) (*model.App, error) {
+	var apps []*model.App
+	if err := s.DB.Where("a_key = ?", akey).Limit(1).Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	if len(apps) > 0 {
+		return apps[0], nil
+	}
+
+	currentAKey, err := s.resolveRetiredKey(model.KeyTypeAKey, akey)
+	if err != nil {
+		return nil, err
+	}
+	if currentAKey == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var app model.App
+	if err := s.DB.Where("a_key = ?", currentAKey).First(&app).Error; err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// UpdateRequestSigning 更新应用的请求签名设置
+func (s *AppStoreImpl) UpdateRequestSigning(akey string, required bool, secret string) error {
+	return s.DB.Model(&model.App{}).Where("a_key = ?", akey).
+		Updates(map[string]interface{}{"require_signed_requests": required, "request_secret": secret}).Error
+}
+
 // UpdateApp 更新应用信息
 func (s *AppStoreImpl) UpdateApp(app *model.App) error {
 	// 使用 Select 明确指定要更新的字段，包括零值字段
 	return s.DB.Model(&model.App{}).Where("a_key = ?", app.AKey).
-		Select("name", "description", "is_paid").
+		Select("name", "description", "is_paid", "min_supported_version").
 		Updates(app).Error
 }
 
+// RotateAKey 为应用生成新的AKey
+// 同步更新版本等关联记录以及API令牌的授权应用列表，
+// 旧AKey在 graceExpiresAt 之前仍可通过校验，返回新的AKey
+func (s *AppStoreImpl) RotateAKey(akey string, graceExpiresAt time.Time) (string, error) {
+	newAKey := "app_" + uuid.New().String()
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.App{}).Where("a_key = ?", akey).Update("a_key", newAKey)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// 更新关联记录，数据库已通过外键级联更新时不会产生影响
+		for _, item := range akeyModels {
+			if err := tx.Model(item).Where("a_key = ?", akey).Update("a_key", newAKey).Error; err != nil {
+				return err
+			}
+		}
+
+		// 更新API令牌的授权应用列表
+		var tokens []*model.APIToken
+		if err := tx.Where("a_keys LIKE ?", "%"+akey+"%").Find(&tokens).Error; err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			akeys := token.AKeyList()
+			for i := range akeys {
+				if akeys[i] == akey {
+					akeys[i] = newAKey
+				}
+			}
+			if err := tx.Model(token).Update("a_keys", model.JoinList(akeys)).Error; err != nil {
+				return err
+			}
+		}
+
+		// 更新公告的目标应用列表
+		var announcements []*model.Announcement
+		if err := tx.Where("a_keys LIKE ?", "%"+akey+"%").Find(&announcements).Error; err != nil {
+			return err
+		}
+		for _, announcement := range announcements {
+			akeys := announcement.AKeyList()
+			for i := range akeys {
+				if akeys[i] == akey {
+					akeys[i] = newAKey
+				}
+			}
+			if err := tx.Model(announcement).Update("a_keys", model.JoinList(akeys)).Error; err != nil {
+				return err
+			}
+		}
+
+		return retireKey(tx, model.KeyTypeAKey, akey, newAKey, graceExpiresAt)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return newAKey, nil
+}
+
 // DeleteApp 删除应用（同时删除关联的版本）
 func (s *AppStoreImpl) DeleteApp(akey string) error {
-	// 开始事务
-	tx := s.DB.Begin()
-	if tx.Error != nil {
-		return tx.Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteAppRecords(tx, akey)
+	})
+}
+
+// deleteAppRecords 删除应用及其版本、许可证、设备等关联记录，需在事务中调用
+func deleteAppRecords(tx *gorm.DB, akey string) error {
+	// 删除关联版本的制品记录
+	versionIDs := tx.Unscoped().Model(&model.Version{}).Select("id").Where("a_key = ?", akey)
+	if err := tx.Where("version_id IN (?)", versionIDs).Delete(&model.Artifact{}).Error; err != nil {
+		return err
 	}
 
 	// 删除关联的版本
 	if err := tx.Where("a_key = ?", akey).Delete(&model.Version{}).Error; err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	// 删除应用
-	if err := tx.Where("a_key = ?", akey).Delete(&model.App{}).Error; err != nil {
-		tx.Rollback()
+	// 删除关联的强制更新版本范围
+	if err := tx.Unscoped().Where("a_key = ?", akey).Delete(&model.ForcedUpdateRange{}).Error; err != nil {
+		return err
+	}
+
+	// 删除关联的许可证及其激活记录
+	licenseIDs := tx.Model(&model.License{}).Select("id").Where("a_key = ?", akey)
+	if err := tx.Unscoped().Where("license_id IN (?)", licenseIDs).Delete(&model.LicenseActivation{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("a_key = ?", akey).Delete(&model.License{}).Error; err != nil {
 		return err
 	}
 
-	// 提交事务
-	tx.Commit()
-	return nil
+	// 删除关联的设备
+	if err := tx.Unscoped().Where("a_key = ?", akey).Delete(&model.Device{}).Error; err != nil {
+		return err
+	}
+
+	// 删除关联的遥测记录
+	if err := tx.Where("a_key = ?", akey).Delete(&model.CheckEvent{}).Error; err != nil {
+		return err
+	}
+
+	// 删除关联的Webhook订阅及其投递记录
+	if err := tx.Where("a_key = ?", akey).Delete(&model.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("a_key = ?", akey).Delete(&model.Webhook{}).Error; err != nil {
+		return err
+	}
+
+	// 删除应用
+	return tx.Where("a_key = ?", akey).Delete(&model.App{}).Error
 }
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"verkeyoss/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ArtifactStoreImpl 制品存储实现
+type ArtifactStoreImpl struct {
+	*Store
+}
+
+// NewArtifactStore 创建制品存储实例
+func (s *Store) NewArtifactStore() *ArtifactStoreImpl {
+	return &ArtifactStoreImpl{Store: s}
+}
+
+// SaveArtifact 保存制品，替换同一版本同一平台的现有制品
+// 返回被替换的制品，不存在时返回nil
+func (s *ArtifactStoreImpl) SaveArtifact(artifact *model.Artifact) (*model.Artifact, error) {
+	var previous *model.Artifact
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []*model.Artifact
+		err := tx.Where("version_id = ? AND os = ? AND arch = ?", artifact.VersionID, artifact.OS, artifact.Arch).
+			Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			previous = existing[0]
+			if err := tx.Delete(previous).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(artifact).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// GetArtifactByID 根据ID获取制品
+func (s *ArtifactStoreImpl) GetArtifactByID(id uint) (*model.Artifact, error) {
+	var artifact model.Artifact
+	if err := s.DB.First(&artifact, id).Error; err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// GetArtifactsByVersionID 获取版本的全部制品，按操作系统和架构排序
+func (s *ArtifactStoreImpl) GetArtifactsByVersionID(versionID uint) ([]*model.Artifact, error) {
+	var artifacts []*model.Artifact
+	err := s.DB.Where("version_id = ?", versionID).Order("os, arch").Find(&artifacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// GetArtifactsByAKey 获取应用全部版本的制品
+func (s *ArtifactStoreImpl) GetArtifactsByAKey(akey string) ([]*model.Artifact, error) {
+	var artifacts []*model.Artifact
+	versionIDs := s.DB.Unscoped().Model(&model.Version{}).Select("id").Where("a_key = ?", akey)
+	if err := s.DB.Where("version_id IN (?)", versionIDs).Find(&artifacts).Error; err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// GetAllArtifacts 获取全部制品
+func (s *ArtifactStoreImpl) GetAllArtifacts() ([]*model.Artifact, error) {
+	var artifacts []*model.Artifact
+	if err := s.DB.Find(&artifacts).Error; err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// DeleteArtifact 删除制品
+func (s *ArtifactStoreImpl) DeleteArtifact(id uint) error {
+	return s.DB.Delete(&model.Artifact{}, id).Error
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+)
+
+// AuditStoreImpl 审计日志存储实现
+type AuditStoreImpl struct {
+	*Store
+}
+
+// NewAuditStore 创建审计日志存储实例
+func (s *Store) NewAuditStore() *AuditStoreImpl {
+	return &AuditStoreImpl{Store: s}
+}
+
+// CreateAuditEntry 写入审计日志
+func (s *AuditStoreImpl) CreateAuditEntry(entry *model.AuditEntry) error {
+	return s.DB.Create(entry).Error
+}
+
+// GetAuditEntryList 获取审计日志列表（分页），按时间倒序
+func (s *AuditStoreImpl) GetAuditEntryList(query *model.AuditQuery, page, size int) ([]*model.AuditEntry, int64, error) {
+	var entries []*model.AuditEntry
+	var total int64
+
+	db := s.DB.Model(&model.AuditEntry{})
+	if query.ActorName != "" {
+		db = db.Where("actor_name = ?", query.ActorName)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.ResourceType != "" {
+		db = db.Where("resource_type = ?", query.ResourceType)
+	}
+	if query.ResourceID != "" {
+		db = db.Where("resource_id = ?", query.ResourceID)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	if err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(size).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// DeleteAuditEntriesBefore 删除指定时间之前的审计日志，返回删除的记录数
+func (s *AuditStoreImpl) DeleteAuditEntriesBefore(before time.Time) (int64, error) {
+	result := s.DB.Where("created_at < ?", before).Delete(&model.AuditEntry{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"errors"
+
+	"verkeyoss/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// errBackupDryRun 预演导入时用于回滚事务
+var errBackupDryRun = errors.New("backup dry run")
+
+// BackupStoreImpl 备份存储实现
+type BackupStoreImpl struct {
+	*Store
+}
+
+// NewBackupStore 创建备份存储实例
+func (s *Store) NewBackupStore() *BackupStoreImpl {
+	return &BackupStoreImpl{Store: s}
+}
+
+// ExportBackup 获取全部应用、版本（包括制品）和公告，按创建顺序排列
+// 在同一事务中查询，保证导出的数据一致
+func (s *BackupStoreImpl) ExportBackup() ([]*model.App, []*model.Version, []*model.Announcement, error) {
+	var apps []*model.App
+	var versions []*model.Version
+	var artifacts []*model.Artifact
+	var announcements []*model.Announcement
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Order("id").Find(&apps).Error; err != nil {
+			return err
+		}
+		if err := tx.Order("id").Find(&versions).Error; err != nil {
+			return err
+		}
+		if err := tx.Order("id").Find(&artifacts).Error; err != nil {
+			return err
+		}
+		return tx.Order("id").Find(&announcements).Error
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	versionsByID := make(map[uint]*model.Version, len(versions))
+	for _, version := range versions {
+		versionsByID[version.ID] = version
+	}
+	for _, artifact := range artifacts {
+		if version := versionsByID[artifact.VersionID]; version != nil {
+			version.Artifacts = append(version.Artifacts, artifact)
+		}
+	}
+	return apps, versions, announcements, nil
+}
+
+// ImportBackup 导入应用、版本（包括制品）和公告
+// 合并模式下与现有数据重复的记录会被跳过并记为冲突；替换模式下先删除备份中不存在的应用及其关联数据，
+// 再清空版本、制品和公告，备份中存在的应用保留许可证、设备等关联数据。全部操作在同一事务中执行
+func (s *BackupStoreImpl) ImportBackup(apps []*model.App, versions []*model.Version, announcements []*model.Announcement, mode string, dryRun bool) (*model.BackupImportResult, error) {
+	result := &model.BackupImportResult{Mode: mode, DryRun: dryRun, Conflicts: []model.BackupConflict{}}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if mode == model.BackupModeReplace {
+			if err := clearBackupRecords(tx, apps, result); err != nil {
+				return err
+			}
+		}
+
+		for _, app := range apps {
+			if err := importApp(tx, app, result); err != nil {
+				return err
+			}
+		}
+		for _, version := range versions {
+			if err := importVersion(tx, version, result); err != nil {
+				return err
+			}
+		}
+		for _, announcement := range announcements {
+			if err := importAnnouncement(tx, announcement, result); err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errBackupDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBackupDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// clearBackupRecords 替换模式下删除现有的应用、版本和公告
+// 备份中不存在的应用连同许可证、设备等关联记录一起删除
+func clearBackupRecords(tx *gorm.DB, apps []*model.App, result *model.BackupImportResult) error {
+	keep := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		keep[app.AKey] = true
+	}
+
+	var existing []string
+	if err := tx.Model(&model.App{}).Pluck("a_key", &existing).Error; err != nil {
+		return err
+	}
+	for _, akey := range existing {
+		if !keep[akey] {
+			if err := deleteAppRecords(tx, akey); err != nil {
+				return err
+			}
+		}
+	}
+	result.Apps.Removed = len(existing)
+
+	var count int64
+	if err := tx.Model(&model.Version{}).Count(&count).Error; err != nil {
+		return err
+	}
+	result.Versions.Removed = int(count)
+	if err := tx.Model(&model.Announcement{}).Count(&count).Error; err != nil {
+		return err
+	}
+	result.Announcements.Removed = int(count)
+
+	// 已删除的记录仍占用唯一索引，一并彻底删除
+	for _, item := range []interface{}{&model.Artifact{}, &model.Version{}, &model.App{}, &model.Announcement{}} {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(item).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importApp 导入应用，AKey已存在时记为冲突
+func importApp(tx *gorm.DB, app *model.App, result *model.BackupImportResult) error {
+	var count int64
+	if err := tx.Model(&model.App{}).Where("a_key = ?", app.AKey).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		result.AddConflict(model.BackupResourceApp, app.AKey, "AKey已存在")
+		return nil
+	}
+
+	// 已删除的应用仍占用唯一索引，导入前彻底删除
+	if err := tx.Unscoped().Where("a_key = ? AND deleted_at IS NOT NULL", app.AKey).Delete(&model.App{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(app).Error; err != nil {
+		return err
+	}
+	result.Apps.Imported++
+	return nil
+}
+
+// importVersion 导入版本及其制品，VKey已存在或所属应用不存在时记为冲突
+// 导入固定为最新版本的版本时，取消同一渠道其他版本的固定
+func importVersion(tx *gorm.DB, version *model.Version, result *model.BackupImportResult) error {
+	var count int64
+	if err := tx.Model(&model.Version{}).Where("v_key = ?", version.VKey).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		result.AddConflict(model.BackupResourceVersion, version.VKey, "VKey已存在")
+		return nil
+	}
+	if err := tx.Model(&model.App{}).Where("a_key = ?", version.AKey).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		result.AddConflict(model.BackupResourceVersion, version.VKey, "所属应用不存在")
+		return nil
+	}
+
+	// 已删除的版本仍占用唯一索引，导入前彻底删除
+	if err := tx.Unscoped().Where("v_key = ? AND deleted_at IS NOT NULL", version.VKey).Delete(&model.Version{}).Error; err != nil {
+		return err
+	}
+	if version.IsLatest {
+		if err := tx.Model(&model.Version{}).Where("a_key = ? AND channel = ?", version.AKey, version.Channel).Update("is_latest", false).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return err
+	}
+	for _, artifact := range version.Artifacts {
+		artifact.VersionID = version.ID
+		if err := tx.Create(artifact).Error; err != nil {
+			return err
+		}
+	}
+	result.Versions.Imported++
+	return nil
+}
+
+// importAnnouncement 导入公告，标题和发布日期相同的公告已存在时记为冲突
+// is_active 字段有默认值，未激活的公告在创建后单独更新
+func importAnnouncement(tx *gorm.DB, announcement *model.Announcement, result *model.BackupImportResult) error {
+	var count int64
+	err := tx.Model(&model.Announcement{}).
+		Where("title = ? AND publish_date = ?", announcement.Title, announcement.PublishDate).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		result.AddConflict(model.BackupResourceAnnouncement, announcement.Title, "相同标题和发布日期的公告已存在")
+		return nil
+	}
+
+	isActive := announcement.IsActive
+	if err := tx.Create(announcement).Error; err != nil {
+		return err
+	}
+	if !isActive {
+		if err := tx.Model(announcement).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+	result.Announcements.Imported++
+	return nil
+}
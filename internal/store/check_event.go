@@ -0,0 +1,34 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+)
+
+// CheckEventStoreImpl 校验请求遥测记录存储实现
+type CheckEventStoreImpl struct {
+	*Store
+}
+
+// NewCheckEventStore 创建校验请求遥测记录存储实例
+func (s *Store) NewCheckEventStore() *CheckEventStoreImpl {
+	return &CheckEventStoreImpl{Store: s}
+}
+
+// 批量写入时每条语句包含的最大记录数
+const checkEventInsertBatchSize = 100
+
+// CreateCheckEvents 批量写入遥测记录
+func (s *CheckEventStoreImpl) CreateCheckEvents(events []*model.CheckEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return s.DB.CreateInBatches(events, checkEventInsertBatchSize).Error
+}
+
+// DeleteCheckEventsBefore 删除指定时间之前的遥测记录，返回删除的记录数
+func (s *CheckEventStoreImpl) DeleteCheckEventsBefore(before time.Time) (int64, error) {
+	result := s.DB.Where("created_at < ?", before).Delete(&model.CheckEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -1,6 +1,8 @@
 package store
 
 import (
+	"time"
+
 	"verkeyoss/internal/model"
 )
 
@@ -74,3 +76,73 @@ func (s *DashboardStoreImpl) GetRecentVersions(limit int) ([]*model.Version, err
 	}
 	return versions, nil
 }
+
+// GetActiveInstalls 统计应用各版本的活跃安装数
+// 以设备最近一次访问使用的版本为准，只统计指定时间之后访问过且未被禁用的设备
+func (s *DashboardStoreImpl) GetActiveInstalls(akey string, since time.Time) ([]*model.VersionInstalls, error) {
+	var installs []*model.VersionInstalls
+	err := s.DB.Model(&model.Device{}).
+		Select("version, COUNT(*) AS installs").
+		Where("a_key = ? AND last_seen_at >= ? AND blocked_at IS NULL", akey, since).
+		Group("version").
+		Scan(&installs).Error
+	if err != nil {
+		return nil, err
+	}
+	return installs, nil
+}
+
+// GetCheckEventCounts 统计应用在指定小时范围内的校验请求数，按小时、版本和校验结果分组
+// 参数 fromHour 和 toHour 为小时的Unix时间戳，包含起始小时，不包含结束小时
+func (s *DashboardStoreImpl) GetCheckEventCounts(akey string, fromHour, toHour int64) ([]*model.CheckEventCount, error) {
+	var counts []*model.CheckEventCount
+	err := s.DB.Model(&model.CheckEvent{}).
+		Select("hour, version, result, COUNT(*) AS count").
+		Where("a_key = ? AND hour >= ? AND hour < ?", akey, fromHour, toHour).
+		Group("hour, version, result").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetCheckEventDevices 获取应用在指定小时范围内每小时访问过各版本的设备，只包含校验通过的请求
+// 参数 fromHour 和 toHour 为小时的Unix时间戳，包含起始小时，不包含结束小时
+func (s *DashboardStoreImpl) GetCheckEventDevices(akey string, fromHour, toHour int64) ([]*model.CheckEventDevice, error) {
+	var devices []*model.CheckEventDevice
+	err := s.DB.Model(&model.CheckEvent{}).
+		Distinct("hour", "version", "device_id").
+		Where("a_key = ? AND hour >= ? AND hour < ? AND result = ? AND device_id <> ''", akey, fromHour, toHour, model.KeyStatusValid).
+		Scan(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// GetVersionReleaseTimes 获取应用在指定时间范围内发布的版本的发布时间，包含起始时间，不包含结束时间
+func (s *DashboardStoreImpl) GetVersionReleaseTimes(akey string, from, to time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := s.DB.Model(&model.Version{}).
+		Where("a_key = ? AND created_at >= ? AND created_at < ?", akey, from, to).
+		Pluck("created_at", &times).Error
+	if err != nil {
+		return nil, err
+	}
+	return times, nil
+}
+
+// GetCheckEventDeviceHours 获取应用在指定小时范围内每小时调用过校验接口的设备，包含所有校验结果
+// 参数 fromHour 和 toHour 为小时的Unix时间戳，包含起始小时，不包含结束小时
+func (s *DashboardStoreImpl) GetCheckEventDeviceHours(akey string, fromHour, toHour int64) ([]*model.CheckEventDevice, error) {
+	var devices []*model.CheckEventDevice
+	err := s.DB.Model(&model.CheckEvent{}).
+		Distinct("hour", "device_id").
+		Where("a_key = ? AND hour >= ? AND hour < ? AND device_id <> ''", akey, fromHour, toHour).
+		Scan(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"verkeyoss/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// DeviceStoreImpl 设备存储实现
+type DeviceStoreImpl struct {
+	*Store
+}
+
+// NewDeviceStore 创建设备存储实例
+func (s *Store) NewDeviceStore() *DeviceStoreImpl {
+	return &DeviceStoreImpl{Store: s}
+}
+
+// RecordDevice 登记设备访问
+// 设备首次访问时创建记录，否则更新最近使用的VKey、版本号、IP地址和访问时间，返回更新后的设备记录
+func (s *DeviceStoreImpl) RecordDevice(device *model.Device) (*model.Device, error) {
+	existing, err := s.GetDevice(device.AKey, device.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		device.FirstSeenAt = device.LastSeenAt
+		if err := s.DB.Create(device).Error; err == nil {
+			return device, nil
+		}
+		// 同一设备并发登记时唯一索引冲突，重新查询后按更新处理
+		if existing, err = s.GetDevice(device.AKey, device.DeviceID); err != nil || existing == nil {
+			return nil, errors.New("登记设备失败")
+		}
+	}
+
+	if err := s.DB.Model(&model.Device{}).Where("id = ?", existing.ID).Updates(map[string]interface{}{
+		"v_key":        device.VKey,
+		"version":      device.Version,
+		"ip":           device.IP,
+		"last_seen_at": device.LastSeenAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	existing.VKey = device.VKey
+	existing.Version = device.Version
+	existing.IP = device.IP
+	existing.LastSeenAt = device.LastSeenAt
+	return existing, nil
+}
+
+// GetDevice 根据AKey和设备标识获取设备，不存在时返回nil
+func (s *DeviceStoreImpl) GetDevice(akey, deviceID string) (*model.Device, error) {
+	var devices []*model.Device
+	err := s.DB.Where("a_key = ? AND device_id = ?", akey, deviceID).Limit(1).Find(&devices).Error
+	if err != nil || len(devices) == 0 {
+		return nil, err
+	}
+	return devices[0], nil
+}
+
+// GetDeviceByID 根据ID获取设备
+func (s *DeviceStoreImpl) GetDeviceByID(id uint) (*model.Device, error) {
+	var device model.Device
+	err := s.DB.First(&device, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetDeviceListByAKey 获取应用的设备列表（分页），按最近访问时间倒序
+func (s *DeviceStoreImpl) GetDeviceListByAKey(akey string, query *model.DeviceQuery, page, size int) ([]*model.Device, int64, error) {
+	var devices []*model.Device
+	var total int64
+
+	// 计算偏移量
+	offset := (page - 1) * size
+
+	db := s.DB.Model(&model.Device{}).Where("a_key = ?", akey)
+	if query.VKey != "" {
+		db = db.Where("v_key = ?", query.VKey)
+	}
+	if query.Keyword != "" {
+		keyword := "%" + query.Keyword + "%"
+		db = db.Where("device_id LIKE ? OR ip LIKE ?", keyword, keyword)
+	}
+	if query.Blocked != nil {
+		if *query.Blocked {
+			db = db.Where("blocked_at IS NOT NULL")
+		} else {
+			db = db.Where("blocked_at IS NULL")
+		}
+	}
+
+	// 查询总数
+	db.Count(&total)
+
+	// 查询列表
+	err := db.Order("last_seen_at DESC").Limit(size).Offset(offset).Find(&devices).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return devices, total, nil
+}
+
+// UpdateDeviceBlock 更新设备的禁用状态
+// 参数 blockedAt 为nil时解除禁用
+func (s *DeviceStoreImpl) UpdateDeviceBlock(id uint, blockedAt *time.Time, reason string) error {
+	return s.DB.Model(&model.Device{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"blocked_at": blockedAt, "block_reason": reason}).Error
+}
+
+// DeleteDevice 注销设备，同时删除该设备在应用许可证上的激活记录以释放席位
+func (s *DeviceStoreImpl) DeleteDevice(device *model.Device) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		licenseIDs := tx.Model(&model.License{}).Select("id").Where("a_key = ?", device.AKey)
+		if err := tx.Unscoped().Where("device_id = ? AND license_id IN (?)", device.DeviceID, licenseIDs).
+			Delete(&model.LicenseActivation{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.Device{}, device.ID).Error
+	})
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"verkeyoss/internal/model"
+)
+
+// ForcedUpdateRangeStoreImpl 强制更新版本范围存储实现
+type ForcedUpdateRangeStoreImpl struct {
+	*Store
+}
+
+// NewForcedUpdateRangeStore 创建强制更新版本范围存储实例
+func (s *Store) NewForcedUpdateRangeStore() *ForcedUpdateRangeStoreImpl {
+	return &ForcedUpdateRangeStoreImpl{Store: s}
+}
+
+// CreateForcedUpdateRange 创建强制更新版本范围
+func (s *ForcedUpdateRangeStoreImpl) CreateForcedUpdateRange(forcedRange *model.ForcedUpdateRange) error {
+	return s.DB.Create(forcedRange).Error
+}
+
+// GetForcedUpdateRangeByID 根据ID获取强制更新版本范围
+func (s *ForcedUpdateRangeStoreImpl) GetForcedUpdateRangeByID(id uint) (*model.ForcedUpdateRange, error) {
+	var forcedRange model.ForcedUpdateRange
+	err := s.DB.First(&forcedRange, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &forcedRange, nil
+}
+
+// GetForcedUpdateRangesByAKey 获取应用的全部强制更新版本范围
+func (s *ForcedUpdateRangeStoreImpl) GetForcedUpdateRangesByAKey(akey string) ([]*model.ForcedUpdateRange, error) {
+	var ranges []*model.ForcedUpdateRange
+	err := s.DB.Where("a_key = ?", akey).Order("created_at DESC").Find(&ranges).Error
+	if err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// DeleteForcedUpdateRange 删除强制更新版本范围
+func (s *ForcedUpdateRangeStoreImpl) DeleteForcedUpdateRange(id uint) error {
+	return s.DB.Unscoped().Delete(&model.ForcedUpdateRange{}, id).Error
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// LicenseStoreImpl 许可证存储实现
+type LicenseStoreImpl struct {
+	*Store
+}
+
+// NewLicenseStore 创建许可证存储实例
+func (s *Store) NewLicenseStore() *LicenseStoreImpl {
+	return &LicenseStoreImpl{Store: s}
+}
+
+// CreateLicense 创建许可证
+func (s *LicenseStoreImpl) CreateLicense(license *model.License) error {
+	return s.DB.Create(license).Error
+}
+
+// GetLicenseByID 根据ID获取许可证
+func (s *LicenseStoreImpl) GetLicenseByID(id uint) (*model.License, error) {
+	var license model.License
+	err := s.DB.First(&license, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	license.ActivationCount, err = s.CountActivations(license.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &license, nil
+}
+
+// GetLicenseByKey 根据许可证密钥获取许可证
+func (s *LicenseStoreImpl) GetLicenseByKey(licenseKey string) (*model.License, error) {
+	var license model.License
+	err := s.DB.Where("license_key = ?", licenseKey).First(&license).Error
+	if err != nil {
+		return nil, err
+	}
+	return &license, nil
+}
+
+// GetLicenseListByAKey 获取应用的许可证列表（分页）
+func (s *LicenseStoreImpl) GetLicenseListByAKey(akey string, page, size int) ([]*model.License, int64, error) {
+	var licenses []*model.License
+	var total int64
+
+	// 计算偏移量
+	offset := (page - 1) * size
+
+	// 查询总数
+	s.DB.Model(&model.License{}).Where("a_key = ?", akey).Count(&total)
+
+	// 查询列表
+	err := s.DB.Where("a_key = ?", akey).Order("created_at DESC").Limit(size).Offset(offset).Find(&licenses).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 为每个许可证获取已激活的设备数
+	for i := range licenses {
+		licenses[i].ActivationCount, _ = s.CountActivations(licenses[i].ID)
+	}
+
+	return licenses, total, nil
+}
+
+// RevokeLicense 吊销许可证
+func (s *LicenseStoreImpl) RevokeLicense(id uint, revokedAt time.Time) error {
+	return s.DB.Model(&model.License{}).Where("id = ?", id).Update("revoked_at", revokedAt).Error
+}
+
+// CountActivations 获取许可证已激活的设备数
+func (s *LicenseStoreImpl) CountActivations(licenseID uint) (int64, error) {
+	var count int64
+	err := s.DB.Model(&model.LicenseActivation{}).Where("license_id = ?", licenseID).Count(&count).Error
+	return count, err
+}
+
+// GetActivations 获取许可证的全部激活记录
+func (s *LicenseStoreImpl) GetActivations(licenseID uint) ([]*model.LicenseActivation, error) {
+	var activations []*model.LicenseActivation
+	err := s.DB.Where("license_id = ?", licenseID).Order("created_at ASC").Find(&activations).Error
+	if err != nil {
+		return nil, err
+	}
+	return activations, nil
+}
+
+// GetActivation 获取设备对许可证的激活记录，不存在时返回nil
+func (s *LicenseStoreImpl) GetActivation(licenseID uint, deviceID string) (*model.LicenseActivation, error) {
+	var activations []*model.LicenseActivation
+	err := s.DB.Where("license_id = ? AND device_id = ?", licenseID, deviceID).Limit(1).Find(&activations).Error
+	if err != nil || len(activations) == 0 {
+		return nil, err
+	}
+	return activations[0], nil
+}
+
+// ActivateLicense 为设备激活许可证
+// 设备已激活时只更新最近校验时间；否则在席位未满时新增激活记录。
+// 返回是否激活成功以及激活后已使用的席位数，在事务中完成以避免并发激活超出席位数
+func (s *LicenseStoreImpl) ActivateLicense(license *model.License, deviceID string, now time.Time) (bool, int64, error) {
+	var activated bool
+	var used int64
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定许可证记录，串行处理同一许可证的激活请求
+		if err := tx.Model(&model.License{}).Where("id = ?", license.ID).Update("updated_at", now).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.LicenseActivation{}).
+			Where("license_id = ? AND device_id = ?", license.ID, deviceID).
+			Update("last_seen_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := tx.Model(&model.LicenseActivation{}).Where("license_id = ?", license.ID).Count(&used).Error; err != nil {
+			return err
+		}
+		if result.RowsAffected > 0 {
+			activated = true
+			return nil
+		}
+		if used >= int64(license.Seats) {
+			return nil
+		}
+
+		if err := tx.Create(&model.LicenseActivation{
+			LicenseID:  license.ID,
+			DeviceID:   deviceID,
+			LastSeenAt: now,
+		}).Error; err != nil {
+			return err
+		}
+		activated = true
+		used++
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	return activated, used, nil
+}
+
+// TouchActivation 更新激活记录的最近校验时间
+func (s *LicenseStoreImpl) TouchActivation(id uint, seenAt time.Time) error {
+	return s.DB.Model(&model.LicenseActivation{}).Where("id = ?", id).Update("last_seen_at", seenAt).Error
+}
+
+// DeleteActivation 删除许可证的激活记录，释放席位
+func (s *LicenseStoreImpl) DeleteActivation(licenseID, activationID uint) (bool, error) {
+	result := s.DB.Unscoped().Where("id = ? AND license_id = ?", activationID, licenseID).Delete(&model.LicenseActivation{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// akeyModels 包含 a_key 字段的关联模型，轮换AKey时需要同步更新
+var akeyModels = []interface{}{
+	&model.Version{},
+	&model.ForcedUpdateRange{},
+	&model.License{},
+	&model.Device{},
+	&model.CheckEvent{},
+	&model.Webhook{},
+	&model.WebhookDelivery{},
+}
+
+// retireKey 记录被轮换的旧密钥，需在轮换事务中调用
+// 之前轮换到旧密钥的记录改为指向新密钥，使多次轮换后仍在宽限期内的旧密钥都能映射到最新密钥；
+// 同时清理已过期的记录。参数 expiresAt 不晚于当前时间时旧密钥立即失效，不再记录
+func retireKey(tx *gorm.DB, keyType, oldKey, newKey string, expiresAt time.Time) error {
+	now := time.Now()
+	if err := tx.Unscoped().Where("expires_at <= ?", now).Delete(&model.RetiredKey{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&model.RetiredKey{}).Where("key_type = ? AND new_key = ?", keyType, oldKey).
+		Update("new_key", newKey).Error; err != nil {
+		return err
+	}
+
+	if !expiresAt.After(now) {
+		return nil
+	}
+	return tx.Create(&model.RetiredKey{
+		KeyType:   keyType,
+		OldKey:    oldKey,
+		NewKey:    newKey,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// resolveRetiredKey 将宽限期内的旧密钥映射为当前密钥
+// 密钥未被轮换或已过期时返回空字符串
+func (s *Store) resolveRetiredKey(keyType, key string) (string, error) {
+	var retired model.RetiredKey
+	err := s.DB.Where("key_type = ? AND old_key = ? AND expires_at > ?", keyType, key, time.Now()).
+		Limit(1).Find(&retired).Error
+	if err != nil {
+		return "", err
+	}
+	return retired.NewKey, nil
+}
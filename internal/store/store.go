@@ -1,6 +1,8 @@
 package store
 
 import (
+	"time"
+
 	"verkeyoss/internal/model"
 
 	"gorm.io/gorm"
@@ -16,26 +18,97 @@ func NewStore(db *gorm.DB) *Store {
 	return &Store{DB: db}
 }
 
+// UserStore 用户存储接口
+type UserStore interface {
+	CreateUser(user *model.User) error
+	GetUserByID(id uint) (*model.User, error)
+	GetUserByUsername(username string) (*model.User, error)
+	GetUserList(page, size int) ([]*model.User, int64, error)
+	UpdateUser(user *model.User) error
+	UpdateLastLogin(id uint, loginAt time.Time) error
+	DeleteUser(id uint) error
+	CountActiveUsersByRole(role string) (int64, error)
+}
+
+// APITokenStore API令牌存储接口
+type APITokenStore interface {
+	CreateAPIToken(token *model.APIToken) error
+	GetAPITokenByID(id uint) (*model.APIToken, error)
+	GetAPITokenByHash(tokenHash string) (*model.APIToken, error)
+	GetAPITokenList(userID uint) ([]*model.APIToken, error)
+	RevokeAPIToken(id uint, revokedAt time.Time) error
+	UpdateLastUsed(id uint, usedAt time.Time) error
+}
+
 // AppStore 应用存储接口
 type AppStore interface {
 	CreateApp(app *model.App) error
 	GetAppList(page, size int) ([]*model.App, int64, error)
 	GetAppListByUserID(userID uint, page, size int) ([]*model.App, int64, error)
 	GetAppByAKey(akey string) (*model.App, error)
+	ResolveApp(akey string) (*model.App, error)
 	UpdateApp(app *model.App) error
+	UpdateRequestSigning(akey string, required bool, secret string) error
+	RotateAKey(akey string, graceExpiresAt time.Time) (string, error)
 	DeleteApp(akey string) error
 }
 
 // VersionStore 版本存储接口
 type VersionStore interface {
 	CreateVersion(version *model.Version) error
-	GetVersionListByAKey(akey string, page, size int) ([]*model.Version, int64, error)
+	GetVersionListByAKey(akey, channel string, page, size int) ([]*model.Version, int64, error)
 	GetVersionByVKey(vkey string) (*model.Version, error)
 	UpdateVersion(version *model.Version) error
+	UpdateRollout(vkey string, percent int, status string) error
+	UpdateRevocation(vkey string, revokedAt *time.Time, reason string) error
 	DeleteVersion(vkey string) error
-	GetLatestVersionByAKey(akey string) (*model.Version, error)
-	Validate(akey, vkey string) (bool, error)
-	IsVersionLatest(akey, vkey string) (bool, *model.Version, error)
+	GetVersionsByAKey(akey string) ([]*model.Version, error)
+	Validate(akey, vkey string) (*model.Version, model.KeyStatus, error)
+	RotateVKey(vkey string, graceExpiresAt time.Time) (string, error)
+}
+
+// ArtifactStore 制品存储接口
+type ArtifactStore interface {
+	// 保存制品，替换同一版本同一平台的现有制品并返回被替换的制品
+	SaveArtifact(artifact *model.Artifact) (*model.Artifact, error)
+	GetArtifactByID(id uint) (*model.Artifact, error)
+	GetArtifactsByVersionID(versionID uint) ([]*model.Artifact, error)
+	GetArtifactsByAKey(akey string) ([]*model.Artifact, error)
+	GetAllArtifacts() ([]*model.Artifact, error)
+	DeleteArtifact(id uint) error
+}
+
+// ForcedUpdateRangeStore 强制更新版本范围存储接口
+type ForcedUpdateRangeStore interface {
+	CreateForcedUpdateRange(forcedRange *model.ForcedUpdateRange) error
+	GetForcedUpdateRangeByID(id uint) (*model.ForcedUpdateRange, error)
+	GetForcedUpdateRangesByAKey(akey string) ([]*model.ForcedUpdateRange, error)
+	DeleteForcedUpdateRange(id uint) error
+}
+
+// LicenseStore 许可证存储接口
+type LicenseStore interface {
+	CreateLicense(license *model.License) error
+	GetLicenseByID(id uint) (*model.License, error)
+	GetLicenseByKey(licenseKey string) (*model.License, error)
+	GetLicenseListByAKey(akey string, page, size int) ([]*model.License, int64, error)
+	RevokeLicense(id uint, revokedAt time.Time) error
+	CountActivations(licenseID uint) (int64, error)
+	GetActivations(licenseID uint) ([]*model.LicenseActivation, error)
+	GetActivation(licenseID uint, deviceID string) (*model.LicenseActivation, error)
+	ActivateLicense(license *model.License, deviceID string, now time.Time) (bool, int64, error)
+	TouchActivation(id uint, seenAt time.Time) error
+	DeleteActivation(licenseID, activationID uint) (bool, error)
+}
+
+// DeviceStore 设备存储接口
+type DeviceStore interface {
+	RecordDevice(device *model.Device) (*model.Device, error)
+	GetDevice(akey, deviceID string) (*model.Device, error)
+	GetDeviceByID(id uint) (*model.Device, error)
+	GetDeviceListByAKey(akey string, query *model.DeviceQuery, page, size int) ([]*model.Device, int64, error)
+	UpdateDeviceBlock(id uint, blockedAt *time.Time, reason string) error
+	DeleteDevice(device *model.Device) error
 }
 
 // DashboardStore 仪表盘存储接口
@@ -48,10 +121,61 @@ type DashboardStore interface {
 	GetRecentApps(limit int) ([]*model.App, error)
 	// 获取最近版本
 	GetRecentVersions(limit int) ([]*model.Version, error)
+	// 统计各版本的活跃安装数
+	GetActiveInstalls(akey string, since time.Time) ([]*model.VersionInstalls, error)
+	// 按小时、版本和校验结果统计校验请求数
+	GetCheckEventCounts(akey string, fromHour, toHour int64) ([]*model.CheckEventCount, error)
+	// 获取每小时访问过各版本的设备
+	GetCheckEventDevices(akey string, fromHour, toHour int64) ([]*model.CheckEventDevice, error)
+	// 获取时间范围内发布的版本的发布时间
+	GetVersionReleaseTimes(akey string, from, to time.Time) ([]time.Time, error)
+	// 获取每小时调用过校验接口的设备
+	GetCheckEventDeviceHours(akey string, fromHour, toHour int64) ([]*model.CheckEventDevice, error)
+}
+
+// CheckEventStore 校验请求遥测记录存储接口
+type CheckEventStore interface {
+	CreateCheckEvents(events []*model.CheckEvent) error
+	DeleteCheckEventsBefore(before time.Time) (int64, error)
+}
+
+// AuditStore 审计日志存储接口
+type AuditStore interface {
+	CreateAuditEntry(entry *model.AuditEntry) error
+	GetAuditEntryList(query *model.AuditQuery, page, size int) ([]*model.AuditEntry, int64, error)
+	DeleteAuditEntriesBefore(before time.Time) (int64, error)
+}
+
+// WebhookStore Webhook存储接口
+type WebhookStore interface {
+	CreateWebhook(webhook *model.Webhook) error
+	GetWebhookByID(id uint) (*model.Webhook, error)
+	GetWebhooksByAKey(akey string) ([]*model.Webhook, error)
+	UpdateWebhook(webhook *model.Webhook) error
+	DeleteWebhook(id uint) error
+	CreateDeliveries(deliveries []*model.WebhookDelivery) error
+	GetDeliveryList(webhookID uint, status string, page, size int) ([]*model.WebhookDelivery, int64, error)
+	GetDueDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error)
+	ClaimDelivery(id uint, now, leaseUntil time.Time) (bool, error)
+	UpdateDeliveryAttempt(delivery *model.WebhookDelivery) error
+	DeleteDeliveriesBefore(before time.Time) (int64, error)
 }
 
 // AnnouncementStore 公告存储接口
 type AnnouncementStore interface {
-	// 获取激活的公告列表
-	GetActiveAnnouncements() ([]*model.Announcement, error)
+	CreateAnnouncement(announcement *model.Announcement) error
+	GetAnnouncementByID(id uint) (*model.Announcement, error)
+	GetAnnouncementList(query *model.AnnouncementQuery, now time.Time, page, size int) ([]*model.Announcement, int64, error)
+	UpdateAnnouncement(announcement *model.Announcement) error
+	DeleteAnnouncement(id uint) error
+	// 获取当前已发布的公告列表
+	GetActiveAnnouncements(now time.Time) ([]*model.Announcement, error)
+}
+
+// BackupStore 备份存储接口
+type BackupStore interface {
+	// 获取全部应用、版本和公告，用于导出备份
+	ExportBackup() ([]*model.App, []*model.Version, []*model.Announcement, error)
+	// 在同一事务中导入应用、版本和公告，dryRun 为 true 时只返回导入结果，不保存任何修改
+	ImportBackup(apps []*model.App, versions []*model.Version, announcements []*model.Announcement, mode string, dryRun bool) (*model.BackupImportResult, error)
 }
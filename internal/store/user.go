@@ -0,0 +1,91 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+)
+
+// UserStoreImpl 用户存储实现
+type UserStoreImpl struct {
+	*Store
+}
+
+// NewUserStore 创建用户存储实例
+func (s *Store) NewUserStore() *UserStoreImpl {
+	return &UserStoreImpl{Store: s}
+}
+
+// CreateUser 创建新用户
+func (s *UserStoreImpl) CreateUser(user *model.User) error {
+	return s.DB.Create(user).Error
+}
+
+// GetUserByID 根据ID获取用户信息
+func (s *UserStoreImpl) GetUserByID(id uint) (*model.User, error) {
+	var user model.User
+	err := s.DB.First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByUsername 根据用户名获取用户信息
+func (s *UserStoreImpl) GetUserByUsername(username string) (*model.User, error) {
+	var user model.User
+	err := s.DB.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserList 获取用户列表（分页）
+func (s *UserStoreImpl) GetUserList(page, size int) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+
+	// 计算偏移量
+	offset := (page - 1) * size
+
+	// 查询总数
+	s.DB.Model(&model.User{}).Count(&total)
+
+	// 查询列表
+	err := s.DB.Order("id ASC").Limit(size).Offset(offset).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// UpdateUser 更新用户信息
+func (s *UserStoreImpl) UpdateUser(user *model.User) error {
+	// 使用 Select 明确指定要更新的字段，包括零值字段
+	return s.DB.Model(&model.User{}).Where("id = ?", user.ID).
+		Select("password", "role", "is_active").
+		Updates(user).Error
+}
+
+// UpdateLastLogin 更新用户最近登录时间
+func (s *UserStoreImpl) UpdateLastLogin(id uint, loginAt time.Time) error {
+	return s.DB.Model(&model.User{}).Where("id = ?", id).Update("last_login_at", loginAt).Error
+}
+
+// DeleteUser 删除用户
+// 使用物理删除，以便用户名可以被重新使用
+func (s *UserStoreImpl) DeleteUser(id uint) error {
+	return s.DB.Unscoped().Where("id = ?", id).Delete(&model.User{}).Error
+}
+
+// CountActiveUsersByRole 统计指定角色的启用用户数量
+func (s *UserStoreImpl) CountActiveUsersByRole(role string) (int64, error) {
+	var count int64
+	err := s.DB.Model(&model.User{}).Where("role = ? AND is_active = ?", role, true).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
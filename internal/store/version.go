@@ -1,9 +1,13 @@
 package store
 
 import (
+	"errors"
+	"time"
+
 	"verkeyoss/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // VersionStoreImpl 版本存储实现
@@ -28,19 +32,12 @@ func (s *VersionStoreImpl) CreateVersion(version *model.Version) error {
 		return tx.Error
 	}
 
-	// 如果标记为最新版本，先将其他版本设为非最新
+	// 如果固定为所在渠道的最新版本，先取消同一渠道其他版本的固定
 	if version.IsLatest {
-		if err := tx.Model(&model.Version{}).Where("a_key = ?", version.AKey).Update("is_latest", false).Error; err != nil {
+		if err := tx.Model(&model.Version{}).Where("a_key = ? AND channel = ?", version.AKey, version.Channel).Update("is_latest", false).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
-	} else {
-		// 检查是否应该自动设置为最新版本（如果是该软件的第一个版本）
-		var existingVersionCount int64
-		tx.Model(&model.Version{}).Where("a_key = ?", version.AKey).Count(&existingVersionCount)
-		if existingVersionCount == 0 {
-			version.IsLatest = true
-		}
 	}
 
 	// 创建新版本
@@ -55,18 +52,24 @@ func (s *VersionStoreImpl) CreateVersion(version *model.Version) error {
 }
 
 // GetVersionListByAKey 根据AKey获取版本列表
-func (s *VersionStoreImpl) GetVersionListByAKey(akey string, page, size int) ([]*model.Version, int64, error) {
+// 参数 channel 为空时返回所有渠道的版本
+func (s *VersionStoreImpl) GetVersionListByAKey(akey, channel string, page, size int) ([]*model.Version, int64, error) {
 	var versions []*model.Version
 	var total int64
 
 	// 计算偏移量
 	offset := (page - 1) * size
 
+	query := s.DB.Model(&model.Version{}).Where("a_key = ?", akey)
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+
 	// 查询总数
-	s.DB.Model(&model.Version{}).Where("a_key = ?", akey).Count(&total)
+	query.Count(&total)
 
 	// 查询列表（按创建时间倒序）
-	err := s.DB.Where("a_key = ?", akey).Order("created_at DESC").Limit(size).Offset(offset).Find(&versions).Error
+	err := query.Order("created_at DESC").Limit(size).Offset(offset).Find(&versions).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -92,16 +95,18 @@ func (s *VersionStoreImpl) UpdateVersion(version *model.Version) error {
 		return tx.Error
 	}
 
-	// 如果要标记为最新版本，先将其他版本设为非最新
+	// 如果要固定为所在渠道的最新版本，先取消同一渠道其他版本的固定
 	if version.IsLatest {
-		if err := tx.Model(&model.Version{}).Where("a_key = ? AND v_key != ?", version.AKey, version.VKey).Update("is_latest", false).Error; err != nil {
+		if err := tx.Model(&model.Version{}).Where("a_key = ? AND channel = ? AND v_key != ?", version.AKey, version.Channel, version.VKey).Update("is_latest", false).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
-	// 更新版本信息
-	if err := tx.Model(&model.Version{}).Where("v_key = ?", version.VKey).Updates(version).Error; err != nil {
+	// 更新版本信息，使用 Select 明确指定要更新的字段，包括零值字段
+	if err := tx.Model(&model.Version{}).Where("v_key = ?", version.VKey).
+		Select("version", "channel", "description", "is_latest", "is_forced_update").
+		Updates(version).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -111,86 +116,115 @@ func (s *VersionStoreImpl) UpdateVersion(version *model.Version) error {
 	return nil
 }
 
-// DeleteVersion 删除版本
-func (s *VersionStoreImpl) DeleteVersion(vkey string) error {
-	// 获取版本信息，用于后续检查
-	version, err := s.GetVersionByVKey(vkey)
-	if err != nil {
-		return err
-	}
-
-	// 开始事务
-	tx := s.DB.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
+// UpdateRollout 更新版本的灰度发布比例和状态
+func (s *VersionStoreImpl) UpdateRollout(vkey string, percent int, status string) error {
+	return s.DB.Model(&model.Version{}).Where("v_key = ?", vkey).
+		Updates(map[string]interface{}{"rollout_percent": percent, "rollout_status": status}).Error
+}
 
-	// 删除版本
-	if err := tx.Where("v_key = ?", vkey).Delete(&model.Version{}).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+// UpdateRevocation 更新版本的撤回状态
+// 参数 revokedAt 为nil时恢复版本
+func (s *VersionStoreImpl) UpdateRevocation(vkey string, revokedAt *time.Time, reason string) error {
+	return s.DB.Model(&model.Version{}).Where("v_key = ?", vkey).
+		Updates(map[string]interface{}{"revoked_at": revokedAt, "revoke_reason": reason}).Error
+}
 
-	// 如果删除的是最新版本，从剩余版本中选择最新的一个作为最新版本
-	if version.IsLatest {
-		var latestVersion model.Version
-		if err := tx.Where("a_key = ?", version.AKey).Order("created_at DESC").First(&latestVersion).Error; err == nil {
-			latestVersion.IsLatest = true
-			if err := tx.Save(&latestVersion).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
+// DeleteVersion 删除版本及其制品记录
+func (s *VersionStoreImpl) DeleteVersion(vkey string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		versionIDs := tx.Model(&model.Version{}).Select("id").Where("v_key = ?", vkey)
+		if err := tx.Where("version_id IN (?)", versionIDs).Delete(&model.Artifact{}).Error; err != nil {
+			return err
 		}
-	}
-
-	// 提交事务
-	tx.Commit()
-	return nil
+		return tx.Where("v_key = ?", vkey).Delete(&model.Version{}).Error
+	})
 }
 
-// GetLatestVersionByAKey 获取指定软件的最新版本
-func (s *VersionStoreImpl) GetLatestVersionByAKey(akey string) (*model.Version, error) {
-	var version model.Version
-	err := s.DB.Where("a_key = ? AND is_latest = ?", akey, true).First(&version).Error
+// GetVersionsByAKey 获取指定软件的全部版本
+// 最新版本按语义化版本号在服务层计算，因此这里返回完整列表
+func (s *VersionStoreImpl) GetVersionsByAKey(akey string) ([]*model.Version, error) {
+	var versions []*model.Version
+	err := s.DB.Where("a_key = ?", akey).Order("created_at DESC").Find(&versions).Error
 	if err != nil {
 		return nil, err
 	}
-	return &version, nil
+	return versions, nil
 }
 
 // Validate 校验AKey和VKey的合法性
-func (s *VersionStoreImpl) Validate(akey, vkey string) (bool, error) {
-	var count int64
-	err := s.DB.Model(&model.Version{}).Where("a_key = ? AND v_key = ?", akey, vkey).Count(&count).Error
+// AKey和VKey存在对应关系时返回对应的版本，版本已撤回时状态为 revoked。
+// 宽限期内的旧密钥会映射到轮换后的新密钥，此时返回版本的密钥与传入的不同
+func (s *VersionStoreImpl) Validate(akey, vkey string) (*model.Version, model.KeyStatus, error) {
+	version, err := s.findVersionByKeys(akey, vkey)
 	if err != nil {
-		return false, err
+		return nil, model.KeyStatusInvalid, err
 	}
-	return count > 0, nil
-}
 
-// IsVersionLatest 检查指定版本是否是最新版本
-func (s *VersionStoreImpl) IsVersionLatest(akey, vkey string) (bool, *model.Version, error) {
-	// 获取当前版本信息
-	currentVersion, err := s.GetVersionByVKey(vkey)
-	if err != nil {
-		return false, nil, err
+	// 未找到时尝试按已轮换的旧密钥查找
+	if version == nil {
+		currentAKey, err := s.resolveRetiredKey(model.KeyTypeAKey, akey)
+		if err != nil {
+			return nil, model.KeyStatusInvalid, err
+		}
+		currentVKey, err := s.resolveRetiredKey(model.KeyTypeVKey, vkey)
+		if err != nil {
+			return nil, model.KeyStatusInvalid, err
+		}
+		if currentAKey == "" && currentVKey == "" {
+			return nil, model.KeyStatusInvalid, nil
+		}
+		if currentAKey == "" {
+			currentAKey = akey
+		}
+		if currentVKey == "" {
+			currentVKey = vkey
+		}
+		if version, err = s.findVersionByKeys(currentAKey, currentVKey); err != nil || version == nil {
+			return nil, model.KeyStatusInvalid, err
+		}
 	}
 
-	// 检查AKey是否匹配
-	if currentVersion.AKey != akey {
-		return false, nil, nil
+	if version.IsRevoked() {
+		return version, model.KeyStatusRevoked, nil
 	}
+	return version, model.KeyStatusValid, nil
+}
 
-	// 检查是否是最新版本
-	if currentVersion.IsLatest {
-		return true, nil, nil
+// findVersionByKeys 根据AKey和VKey查找版本，不存在时返回nil
+func (s *VersionStoreImpl) findVersionByKeys(akey, vkey string) (*model.Version, error) {
+	var version model.Version
+	err := s.DB.Where("a_key = ? AND v_key = ?", akey, vkey).First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
+	return &version, nil
+}
+
+// RotateVKey 为版本生成新的VKey
+// 旧VKey在 graceExpiresAt 之前仍可通过校验，返回新的VKey
+func (s *VersionStoreImpl) RotateVKey(vkey string, graceExpiresAt time.Time) (string, error) {
+	newVKey := "ver_" + uuid.New().String()
 
-	// 获取最新版本
-	latestVersion, err := s.GetLatestVersionByAKey(akey)
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Version{}).Where("v_key = ?", vkey).Update("v_key", newVKey)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 设备记录的VKey同步更新，便于按VKey筛选设备
+		if err := tx.Model(&model.Device{}).Where("v_key = ?", vkey).Update("v_key", newVKey).Error; err != nil {
+			return err
+		}
+		return retireKey(tx, model.KeyTypeVKey, vkey, newVKey, graceExpiresAt)
+	})
 	if err != nil {
-		return false, nil, err
+		return "", err
 	}
 
-	return false, latestVersion, nil
+	return newVKey, nil
 }
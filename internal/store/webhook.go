@@ -0,0 +1,130 @@
+package store
+
+import (
+	"time"
+
+	"verkeyoss/internal/model"
+)
+
+// WebhookStoreImpl Webhook存储实现
+type WebhookStoreImpl struct {
+	*Store
+}
+
+// NewWebhookStore 创建Webhook存储实例
+func (s *Store) NewWebhookStore() *WebhookStoreImpl {
+	return &WebhookStoreImpl{Store: s}
+}
+
+// CreateWebhook 创建Webhook订阅
+func (s *WebhookStoreImpl) CreateWebhook(webhook *model.Webhook) error {
+	return s.DB.Create(webhook).Error
+}
+
+// GetWebhookByID 根据ID获取Webhook订阅
+func (s *WebhookStoreImpl) GetWebhookByID(id uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := s.DB.First(&webhook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetWebhooksByAKey 获取应用的全部Webhook订阅
+func (s *WebhookStoreImpl) GetWebhooksByAKey(akey string) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	err := s.DB.Where("a_key = ?", akey).Order("created_at ASC").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook 更新Webhook订阅
+func (s *WebhookStoreImpl) UpdateWebhook(webhook *model.Webhook) error {
+	return s.DB.Save(webhook).Error
+}
+
+// DeleteWebhook 删除Webhook订阅及其投递记录
+func (s *WebhookStoreImpl) DeleteWebhook(id uint) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Unscoped().Delete(&model.Webhook{}, id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CreateDeliveries 批量写入投递记录
+func (s *WebhookStoreImpl) CreateDeliveries(deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.DB.Create(deliveries).Error
+}
+
+// GetDeliveryList 获取Webhook的投递记录（分页），按时间倒序
+// 参数 status 为空时返回全部状态的记录
+func (s *WebhookStoreImpl) GetDeliveryList(webhookID uint, status string, page, size int) ([]*model.WebhookDelivery, int64, error) {
+	var deliveries []*model.WebhookDelivery
+	var total int64
+
+	db := s.DB.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	if err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(size).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// GetDueDeliveries 获取已到下次尝试时间的待投递记录，按到期时间排序
+func (s *WebhookStoreImpl) GetDueDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := s.DB.Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at ASC, id ASC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery 领取一条到期的待投递记录，将下次尝试时间推迟到 leaseUntil
+// 多个实例同时投递时只有一个能领取成功；投递过程中服务退出的记录在 leaseUntil 之后会被重新领取
+func (s *WebhookStoreImpl) ClaimDelivery(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := s.DB.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, model.DeliveryPending, now).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateDeliveryAttempt 保存一次投递尝试的结果
+func (s *WebhookStoreImpl) UpdateDeliveryAttempt(delivery *model.WebhookDelivery) error {
+	return s.DB.Model(delivery).Select(
+		"Status", "Attempts", "NextAttemptAt", "LastAttemptAt", "ResponseStatus", "ResponseBody", "Error", "DeliveredAt",
+	).Updates(delivery).Error
+}
+
+// DeleteDeliveriesBefore 删除指定时间之前创建且已完成的投递记录，返回删除的记录数
+func (s *WebhookStoreImpl) DeleteDeliveriesBefore(before time.Time) (int64, error) {
+	result := s.DB.Where("created_at < ? AND status <> ?", before, model.DeliveryPending).Delete(&model.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
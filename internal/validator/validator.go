@@ -6,14 +6,13 @@ import (
 	"unicode/utf8"
 
 	"verkeyoss/internal/errors"
+	"verkeyoss/internal/semver"
 )
 
 // 正则表达式模式
 var (
 	// 应用名称：支持中英文、数字、下划线、短横线、空格、句点、圆括号
 	appNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-\p{Han}\s\.\(\)]{2,50}$`)
-	// 版本号：语义化版本号格式
-	versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[a-zA-Z0-9\-]+)?(\+[a-zA-Z0-9\-]+)?$`)
 	// 用户名：字母、数字、下划线
 	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{3,20}$`)
 )
@@ -55,7 +54,8 @@ func ValidateVersion(version string) error {
 		return errors.NewValidationError("版本号不能为空")
 	}
 
-	if !versionPattern.MatchString(version) {
+	// 版本号：语义化版本号格式，与版本比较使用相同的语法
+	if !semver.IsValid(version) {
 		return errors.NewValidationError("版本号格式无效，请使用语义化版本号格式（如：1.0.0）")
 	}
 
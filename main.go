@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
+	"flag"
 	"fmt"
 	"io/fs"
 	"log"
@@ -14,7 +16,9 @@ import (
 	"syscall"
 	"time"
 
+	"verkeyoss/internal/blob"
 	"verkeyoss/internal/config"
+	"verkeyoss/internal/database"
 	"verkeyoss/internal/initializer"
 	"verkeyoss/internal/logger"
 	"verkeyoss/internal/router"
@@ -22,8 +26,6 @@ import (
 	"verkeyoss/internal/store"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
 )
 
 // 版本信息，在构建时通过 -ldflags 注入
@@ -35,11 +37,25 @@ var version = "dev"
 var frontendFS embed.FS
 
 func main() {
+	if err := runCommand(os.Args[1:]); err != nil {
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return
+		case errors.Is(err, errUsage):
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runServe 启动服务
+func runServe() {
 	// 显示版本信息
 	log.Printf("VerKeyOSS 版本: %s", version)
 
 	// 加载配置文件
-	appConfig, err := config.LoadConfig("config.yaml")
+	appConfig, err := config.LoadConfig(configFile)
 	if err != nil {
 		log.Printf("配置文件加载失败: %v", err)
 		log.Println("请检查 config.yaml 文件是否存在并且格式正确")
@@ -49,26 +65,30 @@ func main() {
 		return
 	}
 
+	// 配置文件中没有响应签名密钥时生成并写回配置文件
+	if err := config.EnsureSigningKey(); err != nil {
+		log.Fatalf("初始化响应签名密钥失败: %v", err)
+	}
+
 	// 初始化日志系统
 	logger.Init(appConfig.Server.Debug)
 	logger.Info("应用启动中...")
 
 	// 数据库连接
 	logger.Info("正在连接数据库...")
-	log.Printf("正在连接数据库: %s:%d/%s", appConfig.DB.Host, appConfig.DB.Port, appConfig.DB.Name)
-	db, err := initDB(appConfig)
+	log.Printf("正在连接数据库: %s", database.Describe(appConfig))
+	db, err := database.Open(appConfig)
 	if err != nil {
 		logger.Error("数据库连接失败:", err)
 		log.Printf("数据库连接失败: %v", err)
 		log.Println("")
 		log.Println("可能的解决方案:")
-		log.Println("1. 检查MySQL服务是否已启动")
+		log.Println("1. 检查数据库服务是否已启动（SQLite 请检查数据库文件路径是否可写）")
 		log.Println("2. 验证数据库连接配置 (config.yaml)")
 		log.Println("3. 确认数据库用户名密码正确")
 		log.Println("4. 确认目标数据库已创建")
 		log.Println("")
-		log.Printf("当前配置: %s:%d, 用户: %s, 数据库: %s",
-			appConfig.DB.Host, appConfig.DB.Port, appConfig.DB.User, appConfig.DB.Name)
+		log.Printf("当前配置: %s, 用户: %s", database.Describe(appConfig), appConfig.DB.User)
 		log.Println("")
 		log.Println("按任意键退出...")
 		fmt.Scanln()
@@ -85,10 +105,11 @@ func main() {
 	store := store.NewStore(db)
 
 	// 初始化服务层
-	services := service.NewServices(store, appConfig.JWT.Secret, appConfig.JWT.ExpireHours)
+	log.Printf("制品存储: %s", blob.Describe(appConfig))
+	services := service.NewServices(store, appConfig)
 
 	// 初始化路由
-	r := router.SetupRouter(services, appConfig, version, StaticFileHandler(), FrontendHandler())
+	r := router.SetupRouter(services, appConfig, version, StaticFileHandler(), FrontendHandler(), router.NewMemoryRateLimitBackend())
 
 	// 启动服务器
 	port := appConfig.Server.Port
@@ -125,38 +146,14 @@ func main() {
 		log.Fatalf("服务器强制关闭: %v", err)
 	}
 
-	logger.Info("服务器已安全关闭")
-	log.Println("服务器已关闭")
-}
-
-// initDB 初始化数据库连接
-func initDB(appConfig *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		appConfig.DB.User, appConfig.DB.Password, appConfig.DB.Host, appConfig.DB.Port, appConfig.DB.Name)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		// 配置GORM参数
-		NowFunc: time.Now,
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("数据库连接失败: %w", err)
-	}
-
-	// 配置连接池
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, fmt.Errorf("获取底层数据库连接失败: %w", err)
-	}
+	// 写入缓冲区中剩余的遥测记录
+	services.TelemetryService.Close()
 
-	// 设置最大开放连接数
-	sqlDB.SetMaxOpenConns(100)
-	// 设置最大空闲连接数
-	sqlDB.SetMaxIdleConns(10)
-	// 设置连接的最大可复用时间
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	// 停止Webhook投递，未完成的投递在下次启动后继续
+	services.WebhookService.Close()
 
-	return db, nil
+	logger.Info("服务器已安全关闭")
+	log.Println("服务器已关闭")
 }
 
 // GetFrontendFS 获取前端文件系统
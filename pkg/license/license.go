@@ -0,0 +1,171 @@
+// Package license 提供 VerKeyOSS 离线许可证文件的签发与校验
+//
+// 无法访问校验接口的客户端（例如隔离网络中的部署）可以内置本包和服务端的签名公钥，
+// 在本地校验管理端导出的离线许可证文件，无需连接服务端。
+//
+// 离线许可证文件为单行文本，格式为
+//
+//	vkl1.<载荷>.<签名>
+//
+// 其中载荷为许可证声明的 JSON（见 Claims）经 Base64URL 编码（无填充）后的结果，
+// 签名为服务端 Ed25519 私钥对 "vkl1.<载荷>" 的签名，同样经 Base64URL 编码（无填充）。
+// 签名密钥与校验接口的响应签名密钥相同，公钥可通过 /api/check/public-key 获取。
+//
+// 客户端校验示例：
+//
+//	publicKey, err := license.ParsePublicKey("Base64编码的公钥")
+//	claims, err := license.Verify(file, publicKey, license.Options{
+//		AKey:     "应用唯一标识",
+//		DeviceID: "设备唯一标识",
+//		Version:  "1.2.0",
+//	})
+//
+// 本包只依赖标准库，可以单独复制到客户端项目中使用。
+package license
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Prefix 离线许可证文件格式标识，格式变更时递增
+const Prefix = "vkl1"
+
+// 校验失败的原因
+var (
+	ErrMalformed         = errors.New("许可证文件格式错误")
+	ErrInvalidSignature  = errors.New("许可证签名无效")
+	ErrAppMismatch       = errors.New("许可证不属于当前应用")
+	ErrDeviceMismatch    = errors.New("许可证未绑定当前设备")
+	ErrVersionNotAllowed = errors.New("当前版本不在许可证允许的版本范围内")
+	ErrExpired           = errors.New("许可证已过期")
+)
+
+// encoding 载荷和签名使用的Base64编码
+var encoding = base64.RawURLEncoding
+
+// Claims 离线许可证声明
+type Claims struct {
+	KeyID      string `json:"kid"`           // 签名密钥标识
+	LicenseKey string `json:"lic"`           // 许可证密钥
+	AKey       string `json:"akey"`          // 应用唯一标识
+	DeviceID   string `json:"dev"`           // 绑定的设备标识
+	MinVersion string `json:"min,omitempty"` // 允许的最低版本（含），为空表示不限
+	MaxVersion string `json:"max,omitempty"` // 允许的最高版本（含），为空表示不限
+	IssuedAt   int64  `json:"iat"`           // 签发时间（Unix时间戳，秒）
+	ExpiresAt  int64  `json:"exp,omitempty"` // 过期时间（Unix时间戳，秒），为0表示永久有效
+}
+
+// Options 校验离线许可证时的客户端环境
+type Options struct {
+	AKey     string    // 客户端内置的应用唯一标识
+	DeviceID string    // 当前设备标识，须与导出许可证时提供的一致
+	Version  string    // 客户端当前版本号，许可证限制了版本范围时必须提供
+	Now      time.Time // 当前时间，为零值时使用 time.Now()
+}
+
+// Sign 使用Ed25519私钥签发离线许可证文件
+func Sign(claims *Claims, privateKey ed25519.PrivateKey) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := Prefix + "." + encoding.EncodeToString(payload)
+	signature := ed25519.Sign(privateKey, []byte(signingInput))
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
+
+// Parse 验证离线许可证文件的签名并解析声明，不检查应用、设备、版本和有效期
+// 文件首尾的空白字符会被忽略
+func Parse(file string, publicKey ed25519.PublicKey) (*Claims, error) {
+	parts := strings.Split(strings.TrimSpace(file), ".")
+	if len(parts) != 3 || parts[0] != Prefix {
+		return nil, ErrMalformed
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	return &claims, nil
+}
+
+// Verify 验证离线许可证文件的签名，并检查其是否适用于当前的应用、设备和版本且未过期
+// 校验通过时返回许可证声明，否则返回本包定义的错误之一
+func Verify(file string, publicKey ed25519.PublicKey, opts Options) (*Claims, error) {
+	claims, err := Parse(file, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := claims.Check(opts); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Check 检查许可证声明是否适用于当前的应用、设备和版本且未过期
+func (c *Claims) Check(opts Options) error {
+	if c.AKey != opts.AKey {
+		return ErrAppMismatch
+	}
+	if c.DeviceID != opts.DeviceID {
+		return ErrDeviceMismatch
+	}
+	if !c.AllowsVersion(opts.Version) {
+		return ErrVersionNotAllowed
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if c.ExpiresAt != 0 && now.Unix() >= c.ExpiresAt {
+		return ErrExpired
+	}
+	return nil
+}
+
+// AllowsVersion 判断版本号是否在许可证允许的范围内，上下界均包含在内
+// 许可证限制了版本范围而版本号无法解析时返回false
+func (c *Claims) AllowsVersion(version string) bool {
+	if c.MinVersion == "" && c.MaxVersion == "" {
+		return true
+	}
+	if c.MinVersion != "" {
+		if cmp, err := compareVersions(version, c.MinVersion); err != nil || cmp < 0 {
+			return false
+		}
+	}
+	if c.MaxVersion != "" {
+		if cmp, err := compareVersions(version, c.MaxVersion); err != nil || cmp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePublicKey 解析Base64编码的Ed25519公钥，即 /api/check/public-key 返回的 public_key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("无效的Ed25519公钥")
+	}
+	return ed25519.PublicKey(key), nil
+}
@@ -0,0 +1,165 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestKey 生成测试用的Ed25519密钥对
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	return publicKey, privateKey
+}
+
+// newTestClaims 生成测试用的许可证声明
+func newTestClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		KeyID:      "0123456789abcdef",
+		LicenseKey: "ABCDE-FGHIJ-KLMNO-PQRST-UVWXY",
+		AKey:       "akey",
+		DeviceID:   "device-1",
+		MinVersion: "1.0.0",
+		MaxVersion: "2.0.0",
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  now.Add(24 * time.Hour).Unix(),
+	}
+}
+
+// testOptions 与 newTestClaims 匹配的校验环境
+var testOptions = Options{AKey: "akey", DeviceID: "device-1", Version: "1.5.0"}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	claims := newTestClaims()
+
+	file, err := Sign(claims, privateKey)
+	if err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+	parts := strings.Split(file, ".")
+	if len(parts) != 3 || parts[0] != Prefix {
+		t.Fatalf("文件格式应为 %s.<载荷>.<签名>，实际为 %q", Prefix, file)
+	}
+
+	got, err := Verify(" "+file+"\n", publicKey, testOptions)
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if *got != *claims {
+		t.Fatalf("声明不一致: got %+v, want %+v", got, claims)
+	}
+}
+
+func TestVerifyRejectsTamperedSignature(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	file, err := Sign(newTestClaims(), privateKey)
+	if err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+	parts := strings.Split(file, ".")
+
+	// 修改载荷中的设备标识，签名保持不变
+	tamperedClaims := newTestClaims()
+	tamperedClaims.DeviceID = "device-2"
+	forged, err := Sign(tamperedClaims, privateKey)
+	if err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+	forgedParts := strings.Split(forged, ".")
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("解码签名失败: %v", err)
+	}
+	signature[0] ^= 0xff
+
+	otherPublicKey, _ := newTestKey(t)
+
+	tests := []struct {
+		name      string
+		file      string
+		publicKey ed25519.PublicKey
+	}{
+		{"修改签名", parts[0] + "." + parts[1] + "." + encoding.EncodeToString(signature), publicKey},
+		{"替换载荷", forgedParts[0] + "." + forgedParts[1] + "." + parts[2], publicKey},
+		{"其他公钥", file, otherPublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.file, tt.publicKey, testOptions); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("应返回 ErrInvalidSignature，实际为 %v", err)
+			}
+		})
+	}
+
+	if _, err := Verify("vkl0."+parts[1]+"."+parts[2], publicKey, testOptions); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("格式标识错误时应返回 ErrMalformed，实际为 %v", err)
+	}
+}
+
+func TestVerifyRejectsExpired(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	claims := newTestClaims()
+	file, err := Sign(claims, privateKey)
+	if err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+
+	opts := testOptions
+	opts.Now = time.Unix(claims.ExpiresAt, 0)
+	if _, err := Verify(file, publicKey, opts); !errors.Is(err, ErrExpired) {
+		t.Fatalf("到达过期时间时应返回 ErrExpired，实际为 %v", err)
+	}
+
+	opts.Now = time.Unix(claims.ExpiresAt-1, 0)
+	if _, err := Verify(file, publicKey, opts); err != nil {
+		t.Fatalf("过期前应校验通过，实际为 %v", err)
+	}
+
+	// 永久有效的许可证不会过期
+	claims.ExpiresAt = 0
+	file, err = Sign(claims, privateKey)
+	if err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+	opts.Now = time.Now().AddDate(100, 0, 0)
+	if _, err := Verify(file, publicKey, opts); err != nil {
+		t.Fatalf("永久有效的许可证应校验通过，实际为 %v", err)
+	}
+}
+
+func TestAllowsVersion(t *testing.T) {
+	claims := &Claims{MinVersion: "1.0.0", MaxVersion: "2.0.0"}
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.0.0", true},
+		{"2.0.0", true},
+		{"2.0.0+build1", true},
+		{"1.0.0-beta", false},
+		{"2.0.0-rc1", true},
+		{"2.0.1", false},
+		{"0.9.9", false},
+		{"", false},
+		{"1.x", false},
+	}
+	for _, tt := range tests {
+		if got := claims.AllowsVersion(tt.version); got != tt.want {
+			t.Errorf("AllowsVersion(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+
+	if !(&Claims{}).AllowsVersion("") {
+		t.Error("未限制版本范围时应允许任意版本")
+	}
+}
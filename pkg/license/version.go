@@ -0,0 +1,100 @@
+package license
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 本文件是服务端语义化版本比较规则的精简副本，使本包只依赖标准库，
+// 可以被客户端单独引用。比较规则须与服务端保持一致
+
+// versionPattern 语义化版本号格式，预发布标识和构建元数据只能包含字母、数字和连字符，不能包含点号
+var versionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-([a-zA-Z0-9\-]+))?(?:\+([a-zA-Z0-9\-]+))?$`)
+
+// errInvalidVersion 版本号无法解析
+var errInvalidVersion = errors.New("无效的语义化版本号")
+
+// version 解析后的版本号，构建元数据不参与比较因此不保存
+type version struct {
+	numbers    [3]uint64
+	preRelease string
+}
+
+// parseVersion 解析语义化版本号
+func parseVersion(s string) (*version, error) {
+	matches := versionPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return nil, errInvalidVersion
+	}
+
+	v := &version{preRelease: matches[4]}
+	for i := range v.numbers {
+		n, err := strconv.ParseUint(matches[i+1], 10, 64)
+		if err != nil {
+			return nil, errInvalidVersion
+		}
+		v.numbers[i] = n
+	}
+	return v, nil
+}
+
+// compareVersions 比较两个版本号字符串，a 低于 b 返回 -1，相等返回 0，高于返回 1
+// 任意一个无法解析时返回错误
+func compareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range va.numbers {
+		if c := compareUint(va.numbers[i], vb.numbers[i]); c != 0 {
+			return c, nil
+		}
+	}
+	return comparePreRelease(va.preRelease, vb.preRelease), nil
+}
+
+// comparePreRelease 比较预发布标识，没有预发布标识的版本优先级更高
+// 纯数字标识按数值比较且低于非数字标识，其余按ASCII顺序比较
+func comparePreRelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	numA, errA := strconv.ParseUint(a, 10, 64)
+	numB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(numA, numB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compareUint 比较两个无符号整数
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}